@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/huh"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/narvanalabs/flkr/pkg/flkr/engine"
+)
+
+// parseLanguages turns --language values ("java" for the root app or
+// "services/api=go" for a workspace member) into DetectOptions.Languages.
+func parseLanguages(values []string) (map[string]flkr.Language, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	languages := map[string]flkr.Language{}
+	for _, v := range values {
+		path, lang, ok := strings.Cut(v, "=")
+		if !ok {
+			path, lang = ".", v
+		}
+		if lang == "" {
+			return nil, fmt.Errorf("--language %q: missing language", v)
+		}
+		languages[path] = flkr.Language(lang)
+	}
+	return languages, nil
+}
+
+// isInteractive reports whether flkr can prompt the user.
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stdout} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// chooseLanguages asks the user to pick the stack of each ambiguous
+// profile. It returns opts.Languages extended with the answers, and
+// whether anything was chosen.
+func chooseLanguages(profiles []*flkr.AppProfile, opts flkr.DetectOptions) (map[string]flkr.Language, bool, error) {
+	languages := map[string]flkr.Language{}
+	for path, lang := range opts.Languages {
+		languages[path] = lang
+	}
+
+	chose := false
+	for _, p := range profiles {
+		if !p.Ambiguous() {
+			continue
+		}
+		path := p.Path
+		if path == "" {
+			path = "."
+		}
+
+		title := "Several stacks match almost equally"
+		if path != "." {
+			title += " in " + path
+		}
+		var options []huh.Option[string]
+		for _, c := range p.Candidates() {
+			options = append(options, huh.NewOption(c.String(), string(c.Language)))
+		}
+		var choice string
+		err := huh.NewSelect[string]().
+			Title(title).
+			Description("Which one is the app?").
+			Options(options...).
+			Value(&choice).
+			Run()
+		if err != nil {
+			return nil, false, err
+		}
+		languages[path] = flkr.Language(choice)
+		chose = true
+		fmt.Fprintf(os.Stderr, "using %s for %s; set language = %q in flkr.toml to skip this question\n", choice, path, choice)
+	}
+	return languages, chose, nil
+}
+
+// detectWorkspace detects every app and, when running interactively,
+// resolves ambiguous detections by asking the user.
+func detectWorkspace(ctx context.Context, opts flkr.DetectOptions) ([]*flkr.AppProfile, error) {
+	profiles, err := engine.DetectWorkspace(ctx, opts)
+	if err != nil || jsonOutput || !isInteractive() {
+		return profiles, err
+	}
+	languages, chose, err := chooseLanguages(profiles, opts)
+	if err != nil || !chose {
+		return profiles, err
+	}
+	opts.Languages = languages
+	return engine.DetectWorkspace(ctx, opts)
+}
+
+// detect detects the root app and, when running interactively, resolves
+// an ambiguous detection by asking the user.
+func detect(ctx context.Context, opts flkr.DetectOptions) (*flkr.AppProfile, error) {
+	profile, err := engine.Detect(ctx, opts)
+	if err != nil || profile == nil || jsonOutput || !isInteractive() {
+		return profile, err
+	}
+	languages, chose, err := chooseLanguages([]*flkr.AppProfile{profile}, opts)
+	if err != nil || !chose {
+		return profile, err
+	}
+	opts.Languages = languages
+	return engine.Detect(ctx, opts)
+}
@@ -5,65 +5,240 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"strings"
 
-	"github.com/narvanalabs/flkr/internal/detector"
+	"github.com/narvanalabs/flkr/pkg/flkr"
 	"github.com/spf13/cobra"
 )
 
+var explain bool
+
 var detectCmd = &cobra.Command{
 	Use:   "detect [path]",
 	Short: "Detect the application stack in a repository",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Detect the application stack in a repository. Workspaces (npm, yarn, pnpm,
+Cargo, go.work and Maven multi-module builds) yield one profile per deployable
+member, each tagged with its path relative to the repository root.
+
+With --explain, every detected value is listed with the detector, file and
+rule it came from, and whether it is a hard-coded default.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path := "."
 		if len(args) > 0 {
 			path = args[0]
 		}
 
-		reg := detector.NewRegistry()
-		profile, err := reg.DetectFromPath(context.Background(), path)
+		opts, err := detectOptions(path)
+		if err != nil {
+			return err
+		}
+		profiles, err := detectWorkspace(context.Background(), opts)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
+		if len(profiles) == 0 {
 			fmt.Fprintln(os.Stderr, "no application stack detected")
 			os.Exit(1)
 		}
 
+		for _, profile := range profiles {
+			printWarnings(profile)
+		}
+
 		if jsonOutput {
 			enc := json.NewEncoder(os.Stdout)
 			enc.SetIndent("", "  ")
-			return enc.Encode(profile)
+			return enc.Encode(profiles)
 		}
 
-		fmt.Printf("Language:        %s\n", profile.Language)
-		if profile.Version != "" {
-			fmt.Printf("Version:         %s\n", profile.Version)
-		}
-		fmt.Printf("Package Manager: %s\n", profile.PackageManager)
-		if profile.Framework != "" {
-			fmt.Printf("Framework:       %s\n", profile.Framework)
-		}
-		if profile.BuildCommand != "" {
-			fmt.Printf("Build Command:   %s\n", profile.BuildCommand)
+		for i, profile := range profiles {
+			if i > 0 {
+				fmt.Println()
+			}
+			printProfile(profile)
+			if explain {
+				printEvidence(profile)
+			}
 		}
-		if profile.StartCommand != "" {
-			fmt.Printf("Start Command:   %s\n", profile.StartCommand)
+		return nil
+	},
+}
+
+// printProfile writes a human-readable summary of a profile to stdout.
+func printProfile(profile *flkr.AppProfile) {
+	if profile.Path != "" {
+		fmt.Printf("Path:            %s\n", profile.Path)
+	}
+	fmt.Printf("Language:        %s\n", profile.Language)
+	if profile.Version != "" {
+		fmt.Printf("Version:         %s\n", profile.Version)
+	}
+	if profile.Toolchain != "" {
+		fmt.Printf("Toolchain:       %s\n", profile.Toolchain)
+	}
+	if profile.BaseImage != "" {
+		fmt.Printf("Base Image:      %s\n", profile.BaseImage)
+	}
+	if profile.ErlangVersion != "" {
+		fmt.Printf("Erlang/OTP:      %s\n", profile.ErlangVersion)
+	}
+	if profile.ErlangToolchain != "" {
+		fmt.Printf("OTP Toolchain:   %s\n", profile.ErlangToolchain)
+	}
+	fmt.Printf("Package Manager: %s\n", profile.PackageManager)
+	if profile.Framework != "" {
+		fmt.Printf("Framework:       %s\n", profile.Framework)
+	}
+	if profile.BuildCommand != "" {
+		fmt.Printf("Build Command:   %s\n", profile.BuildCommand)
+	}
+	if profile.StartCommand != "" {
+		fmt.Printf("Start Command:   %s\n", profile.StartCommand)
+	}
+	if len(profile.Binaries) > 1 {
+		for _, b := range profile.Binaries {
+			fmt.Printf("Binary:          %s (%s)\n", b.Name, b.Package)
 		}
-		if profile.OutputDir != "" {
-			fmt.Printf("Output Dir:      %s\n", profile.OutputDir)
+	}
+	switch {
+	case profile.CGOEnabled == "1" && profile.CGOOptional:
+		fmt.Printf("Cgo:             enabled (optional)\n")
+	case profile.CGOEnabled == "1":
+		fmt.Printf("Cgo:             enabled\n")
+	case profile.CGOEnabled == "0":
+		fmt.Printf("Cgo:             disabled (static binary)\n")
+	}
+	if len(profile.Tags) > 0 {
+		fmt.Printf("Build Tags:      %s\n", strings.Join(profile.Tags, ","))
+	}
+	if len(profile.Ldflags) > 0 {
+		fmt.Printf("Ldflags:         %s\n", strings.Join(profile.Ldflags, " "))
+	}
+	for _, v := range profile.LinkVars {
+		value := v.Value
+		if v.From != "" {
+			value = "<" + v.From + ">"
 		}
-		if profile.Port != 0 {
-			fmt.Printf("Port:            %d\n", profile.Port)
+		fmt.Printf("Link Var:        %s=%s\n", v.Name, value)
+	}
+	for _, m := range profile.LocalModules {
+		fmt.Printf("Local Module:    %s\n", m)
+	}
+	if profile.Source != "" {
+		fmt.Printf("Source:          %s\n", profile.Source)
+	}
+	if profile.ReleaseCommand != "" {
+		fmt.Printf("Release Command: %s\n", profile.ReleaseCommand)
+	}
+	for _, proc := range profile.Processes {
+		fmt.Printf("Process:         %s: %s\n", proc.Name, proc.Command)
+	}
+	if profile.TestCommand != "" {
+		fmt.Printf("Test Command:    %s\n", profile.TestCommand)
+	}
+	if profile.HealthCheck != "" {
+		fmt.Printf("Health Check:    %s\n", profile.HealthCheck)
+	}
+	if profile.OutputDir != "" {
+		fmt.Printf("Output Dir:      %s\n", profile.OutputDir)
+	}
+	if profile.Port != 0 {
+		fmt.Printf("Port:            %d\n", profile.Port)
+	}
+	if profile.PortEnv != "" {
+		fmt.Printf("Port Env:        %s\n", profile.PortEnv)
+	}
+	if profile.PortProtocol != "" {
+		fmt.Printf("Port Protocol:   %s\n", profile.PortProtocol)
+	}
+	if len(profile.SystemDeps) > 0 {
+		fmt.Printf("System Deps:     %s\n", strings.Join(profile.SystemDeps, ", "))
+	}
+	if len(profile.BuildDeps) > 0 {
+		fmt.Printf("Build Deps:      %s\n", strings.Join(profile.BuildDeps, ", "))
+	}
+	if len(profile.EnvVars) > 0 {
+		fmt.Printf("Env Vars:        %s\n", formatEnvVars(profile))
+	}
+	for _, stage := range profile.Stages {
+		fmt.Printf("Build Stage:     %s\n", formatStage(stage))
+	}
+	for _, svc := range profile.Services {
+		fmt.Printf("Service:         %s: %s on port %d\n", svc.Name, svc.Package, svc.Port)
+	}
+	for _, v := range profile.ServiceEnv {
+		fmt.Printf("Service Env:     %s=%s\n", v.Name, v.Value)
+	}
+	fmt.Printf("Confidence:      %.0f%%\n", profile.Confidence*100)
+	for _, c := range profile.Alternatives {
+		fmt.Printf("Also Matches:    %s\n", c)
+	}
+}
+
+// formatEnvVars lists env vars on one line, marking the ones the app
+// requires and the defaults of optional ones.
+func formatEnvVars(profile *flkr.AppProfile) string {
+	vars := make([]string, 0, len(profile.EnvVars))
+	for _, name := range profile.EnvVars {
+		v, ok := profile.LookupEnv(name)
+		switch {
+		case !ok:
+			vars = append(vars, name)
+		case v.Required:
+			vars = append(vars, name+" (required)")
+		case v.Default != "":
+			vars = append(vars, name+"="+v.Default)
+		default:
+			vars = append(vars, name+" (optional)")
 		}
-		if len(profile.EnvVars) > 0 {
-			fmt.Printf("Env Vars:        %v\n", profile.EnvVars)
+	}
+	return strings.Join(vars, ", ")
+}
+
+// formatStage summarizes an auxiliary build stage on one line.
+func formatStage(stage flkr.BuildStage) string {
+	s := fmt.Sprintf("%s: %s in %s", stage.Name, stage.BuildCommand, stage.Dir)
+	if stage.OutputDir != "" {
+		s += " -> " + stage.OutputDir
+	}
+	return s
+}
+
+// printWarnings writes detection warnings and problems for a profile to
+// stderr.
+func printWarnings(profile *flkr.AppProfile) {
+	prefix := ""
+	if profile.Path != "" && profile.Path != "." {
+		prefix = profile.Path + ": "
+	}
+	for _, w := range profile.Warnings {
+		fmt.Fprintf(os.Stderr, "warning: %s%s\n", prefix, w)
+	}
+	for _, p := range profile.Problems {
+		fmt.Fprintf(os.Stderr, "error: %s%s\n", prefix, p)
+	}
+}
+
+// printEvidence writes the provenance of each detected value to stdout.
+func printEvidence(profile *flkr.AppProfile) {
+	if len(profile.Evidence) == 0 {
+		return
+	}
+	fmt.Println("Evidence:")
+	for _, e := range profile.Evidence {
+		source := "default"
+		if !e.Default {
+			source = e.File
+			if e.Line > 0 {
+				source = fmt.Sprintf("%s:%d", e.File, e.Line)
+			}
 		}
-		fmt.Printf("Confidence:      %.0f%%\n", profile.Confidence*100)
-		return nil
-	},
+		fmt.Printf("  %-15s %-30s %-22s [%s] %s\n", e.Field, e.Value, source, e.Detector, e.Rule)
+	}
 }
 
 func init() {
+	detectCmd.Flags().BoolVar(&explain, "explain", false, "show where each detected value came from")
 	rootCmd.AddCommand(detectCmd)
 }
@@ -4,12 +4,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"path/filepath"
 
-	"github.com/narvanalabs/flkr/internal/detector"
-	"github.com/narvanalabs/flkr/internal/generator"
-	"github.com/narvanalabs/flkr/internal/nixhash"
 	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/narvanalabs/flkr/pkg/flkr/engine"
 	"github.com/spf13/cobra"
 )
 
@@ -17,51 +14,76 @@ var (
 	dryRun          bool
 	templateVersion string
 	outputPath      string
+	workspace       bool
+	appNames        map[string]string
+	defaultApp      string
 )
 
 var generateCmd = &cobra.Command{
 	Use:   "generate [path]",
 	Short: "Generate a flake.nix for the detected application stack",
-	Args:  cobra.MaximumNArgs(1),
+	Long: `Generate a flake.nix for the detected application stack.
+
+With --workspace, every deployable workspace member becomes a named package
+and app in a single flake. Apps are named after their directory unless
+overridden with --name, e.g. --name services/api=api --name web=frontend.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		path := "."
 		if len(args) > 0 {
 			path = args[0]
 		}
 
-		reg := detector.NewRegistry()
-		profile, err := reg.DetectFromPath(context.Background(), path)
+		detectOpts, err := detectOptions(path)
 		if err != nil {
 			return err
 		}
-		if profile == nil {
-			fmt.Fprintln(os.Stderr, "no application stack detected")
-			os.Exit(1)
+		genOpts := flkr.GenerateOptions{
+			Path:            path,
+			OutputPath:      outputPath,
+			TemplateVersion: templateVersion,
+			DryRun:          dryRun,
+			Names:           appNames,
+			Default:         defaultApp,
 		}
 
-		// Compute vendorHash for Go projects.
-		if profile.Language == flkr.LangGo && !profile.HasVendor {
-			absPath, _ := filepath.Abs(path)
-			if hash, err := nixhash.GoVendorHash(absPath); err == nil {
-				profile.VendorHash = hash
-			} else if verbose {
-				fmt.Fprintf(os.Stderr, "warning: could not compute vendorHash: %v\n", err)
+		var result *flkr.GenerateResult
+		if workspace {
+			profiles, err := detectWorkspace(context.Background(), detectOpts)
+			if err != nil {
+				return err
+			}
+			if len(profiles) == 0 {
+				fmt.Fprintln(os.Stderr, "no application stack detected")
+				os.Exit(1)
+			}
+			for _, profile := range profiles {
+				printWarnings(profile)
+			}
+			result, err = engine.GenerateWorkspace(profiles, genOpts)
+			if err != nil {
+				return err
+			}
+		} else {
+			profile, err := detect(context.Background(), detectOpts)
+			if err != nil {
+				return err
+			}
+			if profile == nil {
+				fmt.Fprintln(os.Stderr, "no application stack detected")
+				os.Exit(1)
+			}
+			printWarnings(profile)
+			result, err = engine.Generate(profile, genOpts)
+			if err != nil {
+				return err
 			}
 		}
 
-		out := outputPath
-		if out == "" {
-			out = filepath.Join(path, "flake.nix")
-		}
-
-		gen := &generator.DefaultGenerator{}
-		result, err := gen.Generate(profile, generator.Options{
-			OutputPath:      out,
-			TemplateVersion: templateVersion,
-			DryRun:          dryRun,
-		})
-		if err != nil {
-			return err
+		if verbose {
+			for _, w := range result.Warnings {
+				fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+			}
 		}
 
 		if dryRun {
@@ -77,5 +99,8 @@ func init() {
 	generateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print flake.nix to stdout instead of writing")
 	generateCmd.Flags().StringVar(&templateVersion, "template-version", "", "pin flkr-templates to a specific revision")
 	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "", "output file path (default: <path>/flake.nix)")
+	generateCmd.Flags().BoolVar(&workspace, "workspace", false, "generate one package and app per workspace member")
+	generateCmd.Flags().StringToStringVar(&appNames, "name", nil, "name an app by its path (path=name), used with --workspace")
+	generateCmd.Flags().StringVar(&defaultApp, "default", "", "app exposed as the flake default, used with --workspace")
 	rootCmd.AddCommand(generateCmd)
 }
@@ -2,13 +2,17 @@ package cmd
 
 import (
 	"os"
+	"time"
 
+	"github.com/narvanalabs/flkr/pkg/flkr"
 	"github.com/spf13/cobra"
 )
 
 var (
-	verbose    bool
-	jsonOutput bool
+	verbose         bool
+	jsonOutput      bool
+	detectorTimeout time.Duration
+	languageFlags   []string
 )
 
 var rootCmd = &cobra.Command{
@@ -24,7 +28,23 @@ func Execute() {
 	}
 }
 
+// detectOptions builds detection options for path from the global flags.
+func detectOptions(path string) (flkr.DetectOptions, error) {
+	languages, err := parseLanguages(languageFlags)
+	if err != nil {
+		return flkr.DetectOptions{}, err
+	}
+	return flkr.DetectOptions{
+		Path:            path,
+		Verbose:         verbose,
+		Languages:       languages,
+		DetectorTimeout: detectorTimeout,
+	}, nil
+}
+
 func init() {
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "enable verbose output")
 	rootCmd.PersistentFlags().BoolVar(&jsonOutput, "json", false, "output in JSON format")
+	rootCmd.PersistentFlags().StringArrayVar(&languageFlags, "language", nil, "choose the app language when detection is ambiguous (lang, or path=lang for a workspace app)")
+	rootCmd.PersistentFlags().DurationVar(&detectorTimeout, "detector-timeout", 0, "time limit for each detector (default 10s)")
 }
@@ -1,7 +1,13 @@
 package detector
 
 import (
+	"fmt"
+	"io"
 	"io/fs"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
 // fileExists checks whether a file exists in the given filesystem.
@@ -23,3 +29,72 @@ func readFileString(root fs.FS, path string) string {
 	}
 	return string(data)
 }
+
+// readHead reads at most n bytes of a file as a string. complete reports
+// whether the whole file was read.
+func readHead(root fs.FS, path string, n int64) (content string, complete bool) {
+	f, err := root.Open(path)
+	if err != nil {
+		return "", true
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, n+1))
+	if err != nil {
+		return "", true
+	}
+	if int64(len(data)) > n {
+		return string(data[:n]), false
+	}
+	return string(data), true
+}
+
+// recorder attaches evidence to a profile on behalf of a detector.
+type recorder struct {
+	profile  *flkr.AppProfile
+	detector string
+}
+
+func newRecorder(profile *flkr.AppProfile, detector string) recorder {
+	return recorder{profile: profile, detector: detector}
+}
+
+// found records a value read from file at line (1-based, 0 if unknown).
+func (r recorder) found(field string, value any, file string, line int, rule string) {
+	r.profile.AddEvidence(flkr.Evidence{
+		Field:    field,
+		Value:    fmt.Sprint(value),
+		Detector: r.detector,
+		File:     file,
+		Line:     line,
+		Rule:     rule,
+	})
+}
+
+// assumed records a hard-coded default value.
+func (r recorder) assumed(field string, value any, rule string) {
+	r.profile.AddEvidence(flkr.Evidence{
+		Field:    field,
+		Value:    fmt.Sprint(value),
+		Detector: r.detector,
+		Rule:     rule,
+		Default:  true,
+	})
+}
+
+// reset drops the evidence this detector recorded for field, before a
+// better-founded value replaces it.
+func (r recorder) reset(field string) {
+	r.profile.Evidence = slices.DeleteFunc(r.profile.Evidence, func(e flkr.Evidence) bool {
+		return e.Field == field && e.Detector == r.detector
+	})
+}
+
+// lineOf returns the 1-based line of the first occurrence of substr in
+// content, or 0 if it does not occur.
+func lineOf(content, substr string) int {
+	i := strings.Index(content, substr)
+	if i == -1 {
+		return 0
+	}
+	return strings.Count(content[:i], "\n") + 1
+}
@@ -0,0 +1,272 @@
+package detector
+
+import (
+	"fmt"
+	"go/build/constraint"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+var (
+	// cgoImportRe matches the import of the C pseudo-package.
+	cgoImportRe = regexp.MustCompile(`(?m)^import\s+(?:\(\s*)?"C"`)
+
+	// cgoDirectiveRe matches a #cgo directive of a cgo preamble, with the
+	// build constraints it may be limited to.
+	cgoDirectiveRe = regexp.MustCompile(`(?m)^[ \t]*(?://)?[ \t]*#cgo[ \t]+(?:([^:\n]*?)[ \t]+)?(CFLAGS|CPPFLAGS|CXXFLAGS|LDFLAGS|pkg-config):(.*)$`)
+)
+
+// cgoSystemLibs are libraries that come with the C toolchain and need no
+// nixpkgs attribute.
+var cgoSystemLibs = []string{"c", "m", "dl", "pthread", "rt", "resolv", "util", "stdc++", "gcc", "gcc_s"}
+
+var (
+	knownGOOS   = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "hurd", "illumos", "ios", "js", "linux", "netbsd", "openbsd", "plan9", "solaris", "wasip1", "windows", "zos"}
+	knownGOARCH = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mips64", "mips64le", "mipsle", "ppc64", "ppc64le", "riscv64", "s390x", "wasm"}
+)
+
+// linuxTag reports whether a build tag holds on the Linux hosts Nix builds
+// on, with cgo as given. Custom tags are unset, as in a plain go build.
+func linuxTag(tag string, cgo bool) bool {
+	switch tag {
+	case "linux", "unix", "gc", "amd64", "arm64":
+		return true
+	case "cgo":
+		return cgo
+	}
+	return strings.HasPrefix(tag, "go1.")
+}
+
+// goFileBuilt reports whether the Go file name, with the build constraint
+// expr (nil if none), is part of a Linux build with cgo as given.
+func goFileBuilt(name string, expr constraint.Expr, cgo bool) bool {
+	parts := strings.Split(strings.TrimSuffix(path.Base(name), ".go"), "_")
+	if n := len(parts); n > 1 {
+		last := parts[n-1]
+		if slices.Contains(knownGOARCH, last) {
+			if !linuxTag(last, cgo) {
+				return false
+			}
+			if n > 2 && slices.Contains(knownGOOS, parts[n-2]) && parts[n-2] != "linux" {
+				return false
+			}
+		} else if slices.Contains(knownGOOS, last) && last != "linux" {
+			return false
+		}
+	}
+	return expr == nil || expr.Eval(func(tag string) bool { return linuxTag(tag, cgo) })
+}
+
+// goConstraint returns the //go:build constraint of a Go file, read from
+// the lines before its package clause.
+func goConstraint(content string) constraint.Expr {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "package ") {
+			break
+		}
+		if constraint.IsGoBuild(line) {
+			if expr, err := constraint.Parse(line); err == nil {
+				return expr
+			}
+		}
+	}
+	return nil
+}
+
+// addCgo records whether best, a Go app, uses cgo: through a module in the
+// go table of m, all of which wrap C libraries, or through its own
+// packages importing "C". The libraries named by their #cgo pkg-config and
+// LDFLAGS directives are mapped to nixpkgs attributes through the
+// pkgconfig and lib tables. Without cgo, or when the app's build turns it
+// off and it isn't required, CGOEnabled is left as the build config set
+// it, empty if it doesn't set CGO_ENABLED.
+func addCgo(root fs.FS, best *flkr.AppProfile, m systemDepMap) {
+	if best.Language != flkr.LangGo {
+		return
+	}
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, "cgo")
+	used, required := false, false
+
+	gomod := readFileString(root, "go.mod")
+	for _, name := range slices.Sorted(maps.Keys(m["go"])) {
+		loc := regexp.MustCompile(ecosystems[flkr.LangGo].sources[0].pattern(regexp.QuoteMeta(name))).FindStringIndex(gomod)
+		if loc == nil {
+			continue
+		}
+		used, required = true, true
+		ev.found("cgoEnabled", "1", "go.mod", strings.Count(gomod[:loc[0]], "\n")+1, "requires "+name+", which uses cgo")
+	}
+
+	byDir := map[string][]string{}
+	nested := map[string]bool{}
+	for _, name := range scanFiles(root, []string{"**/*.go"}) {
+		if !inNestedModule(root, path.Dir(name), nested) {
+			byDir[path.Dir(name)] = append(byDir[path.Dir(name)], name)
+		}
+	}
+	for _, dir := range slices.Sorted(maps.Keys(byDir)) {
+		var cgoFiles []string
+		optional, fallback := true, false
+		for _, name := range byDir[dir] {
+			content, _ := readHead(root, name, sourceMaxSize)
+			expr := goConstraint(content)
+			withCgo, withoutCgo := goFileBuilt(name, expr, true), goFileBuilt(name, expr, false)
+			if !cgoImportRe.MatchString(content) {
+				// A file built only without cgo is a pure-Go fallback.
+				fallback = fallback || withoutCgo && !withCgo
+				continue
+			}
+			if !withCgo {
+				continue
+			}
+			optional = optional && !withoutCgo
+			cgoFiles = append(cgoFiles, name)
+			addCgoDirectives(p, ev, name, content, m)
+		}
+		if len(cgoFiles) == 0 {
+			continue
+		}
+		used = true
+		rule := `imports "C"`
+		if optional || fallback {
+			rule += ", with a pure-Go fallback"
+		} else {
+			required = true
+		}
+		ev.found("cgoEnabled", "1", cgoFiles[0], lineOf(readFileString(root, cgoFiles[0]), `"C"`), rule)
+	}
+
+	// A build config that turns cgo off stands unless cgo is required.
+	off := best.CGOEnabled == "0" && slices.ContainsFunc(best.EvidenceFor("cgoEnabled"), func(e flkr.Evidence) bool { return !e.Default })
+	if off && !required {
+		return
+	}
+	// Without cgo, CGOEnabled stays as the build config left it, empty
+	// unless it sets CGO_ENABLED.
+	if used {
+		p.CGOEnabled = "1"
+		p.CGOOptional = !required
+	}
+	best.Merge(p)
+}
+
+// addCgoDirectives adds the libraries named by the #cgo directives of a
+// Go file to p.
+func addCgoDirectives(p *flkr.AppProfile, ev recorder, file, content string, m systemDepMap) {
+	add := func(list *[]string, field, dep string, line int, rule string) {
+		if !slices.Contains(*list, dep) {
+			*list = append(*list, dep)
+			ev.found(field, dep, file, line, rule)
+		}
+	}
+	for _, loc := range cgoDirectiveRe.FindAllStringSubmatchIndex(content, -1) {
+		line := strings.Count(content[:loc[0]], "\n") + 1
+		if loc[2] >= 0 {
+			expr, err := constraint.Parse("// +build " + content[loc[2]:loc[3]])
+			if err == nil && !expr.Eval(func(tag string) bool { return linuxTag(tag, true) }) {
+				continue
+			}
+		}
+		kind, args := content[loc[4]:loc[5]], strings.Fields(content[loc[6]:loc[7]])
+		switch kind {
+		case "pkg-config":
+			for _, mod := range args {
+				if strings.HasPrefix(mod, "-") {
+					continue
+				}
+				rule, ok := m["pkgconfig"][mod]
+				if !ok {
+					p.Warnings = append(p.Warnings, fmt.Sprintf("%s: no nixpkgs attribute known for pkg-config module %s; map it under [systemDepsMap.pkgconfig.%s] in %s", file, mod, tomlKey(mod), ConfigFile))
+					continue
+				}
+				add(&p.BuildDeps, "buildDeps", "pkg-config", line, "#cgo pkg-config: "+mod)
+				for _, dep := range rule.Build {
+					add(&p.BuildDeps, "buildDeps", dep, line, "#cgo pkg-config: "+mod)
+				}
+				for _, dep := range rule.Runtime {
+					add(&p.SystemDeps, "systemDeps", dep, line, "#cgo pkg-config: "+mod)
+				}
+			}
+		case "LDFLAGS":
+			// Libraries searched for in ${SRCDIR} ship with the module.
+			bundled := slices.ContainsFunc(args, func(a string) bool { return strings.Contains(a, "${SRCDIR}") })
+			for _, arg := range args {
+				lib, ok := strings.CutPrefix(arg, "-l")
+				if !ok || lib == "" || slices.Contains(cgoSystemLibs, lib) {
+					continue
+				}
+				rule, ok := m["lib"][lib]
+				if !ok {
+					if !bundled {
+						p.Warnings = append(p.Warnings, fmt.Sprintf("%s: no nixpkgs attribute known for library -l%s; map it under [systemDepsMap.lib.%s] in %s", file, lib, tomlKey(lib), ConfigFile))
+					}
+					continue
+				}
+				for _, dep := range rule.Build {
+					add(&p.BuildDeps, "buildDeps", dep, line, "#cgo LDFLAGS: -l"+lib)
+				}
+				for _, dep := range rule.Runtime {
+					add(&p.SystemDeps, "systemDeps", dep, line, "#cgo LDFLAGS: -l"+lib)
+				}
+			}
+		}
+	}
+}
+
+var bareKeyRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey quotes a TOML key unless it is bare.
+func tomlKey(key string) string {
+	if bareKeyRe.MatchString(key) {
+		return key
+	}
+	return `"` + key + `"`
+}
+
+// inNestedModule reports whether dir belongs to a Go module of its own
+// below the root, caching the answer per directory in seen.
+func inNestedModule(root fs.FS, dir string, seen map[string]bool) bool {
+	if dir == "." {
+		return false
+	}
+	if nested, ok := seen[dir]; ok {
+		return nested
+	}
+	nested := fileExists(root, dir+"/go.mod") || inNestedModule(root, path.Dir(dir), seen)
+	seen[dir] = nested
+	return nested
+}
+
+// dropCgoDeps removes the system dependencies that only cgo brought in,
+// those of the #cgo directives and of cgo modules in go.mod, for a build
+// with cgo turned off.
+func dropCgoDeps(profile *flkr.AppProfile) {
+	fromCgo := func(e flkr.Evidence) bool {
+		return e.Detector == "cgo" || e.Detector == "systemdeps" && path.Base(e.File) == "go.mod"
+	}
+	for _, field := range []string{"systemDeps", "buildDeps"} {
+		keep := map[string]bool{}
+		var drop []string
+		for _, e := range profile.EvidenceFor(field) {
+			if !fromCgo(e) {
+				keep[e.Value] = true
+			} else {
+				drop = append(drop, e.Value)
+			}
+		}
+		drop = slices.DeleteFunc(drop, func(dep string) bool { return keep[dep] })
+		if field == "systemDeps" {
+			profile.SystemDeps = removeValues(profile, field, profile.SystemDeps, drop)
+		} else {
+			profile.BuildDeps = removeValues(profile, field, profile.BuildDeps, drop)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCgo_Module(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte(`module example.com/api
+
+go 1.24
+
+require (
+	github.com/go-chi/chi/v5 v5.1.0
+	github.com/mattn/go-sqlite3 v1.14.22
+)
+`)},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	profile := &flkr.AppProfile{Language: flkr.LangGo}
+	addCgo(fsys, profile, systemDepsFor())
+	assert.Equal(t, "1", profile.CGOEnabled)
+	assert.False(t, profile.CGOOptional)
+	assert.Empty(t, profile.SystemDeps, "go-sqlite3 bundles SQLite")
+
+	ev := profile.EvidenceFor("cgoEnabled")
+	require.Len(t, ev, 1)
+	assert.Equal(t, flkr.Evidence{
+		Field: "cgoEnabled", Value: "1", Detector: "cgo",
+		File: "go.mod", Line: 7, Rule: "requires github.com/mattn/go-sqlite3, which uses cgo",
+	}, ev[0])
+}
+
+func TestCgo_Directives(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/thumbs\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"internal/img/vips.go": &fstest.MapFile{Data: []byte(`package img
+
+/*
+#cgo pkg-config: vips libheif
+#cgo linux LDFLAGS: -lz -lm -lpthread
+#cgo darwin LDFLAGS: -lSystem
+#include <vips/vips.h>
+*/
+import "C"
+`)},
+		"internal/img/vips_windows.go": &fstest.MapFile{Data: []byte("package img\n\n// #cgo LDFLAGS: -lgdi32\nimport \"C\"\n")},
+		"internal/img/bundled.go":      &fstest.MapFile{Data: []byte("package img\n\n// #cgo LDFLAGS: -L${SRCDIR}/lib -lturbo\nimport \"C\"\n")},
+	}
+
+	profile := &flkr.AppProfile{Language: flkr.LangGo}
+	addCgo(fsys, profile, systemDepsFor())
+	assert.Equal(t, "1", profile.CGOEnabled)
+	assert.False(t, profile.CGOOptional)
+	assert.Equal(t, []string{"vips", "zlib"}, profile.SystemDeps)
+	assert.Equal(t, []string{"pkg-config"}, profile.BuildDeps)
+	require.Len(t, profile.Warnings, 1, "libraries of other platforms or bundled with the module are left out")
+	assert.Contains(t, profile.Warnings[0], "pkg-config module libheif")
+	assert.Contains(t, profile.Warnings[0], "[systemDepsMap.pkgconfig.libheif]")
+
+	ev := profile.EvidenceFor("systemDeps")
+	require.Len(t, ev, 2)
+	assert.Equal(t, flkr.Evidence{
+		Field: "systemDeps", Value: "zlib", Detector: "cgo",
+		File: "internal/img/vips.go", Line: 5, Rule: "#cgo LDFLAGS: -lz",
+	}, ev[1])
+}
+
+func TestCgo_Optional(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/agent\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"sys/mem_cgo.go": &fstest.MapFile{Data: []byte(`//go:build cgo
+
+package sys
+
+// #include <unistd.h>
+import "C"
+`)},
+		"sys/mem_nocgo.go": &fstest.MapFile{Data: []byte("//go:build !cgo\n\npackage sys\n")},
+	}
+
+	profile := &flkr.AppProfile{Language: flkr.LangGo}
+	addCgo(fsys, profile, systemDepsFor())
+	assert.Equal(t, "1", profile.CGOEnabled)
+	assert.True(t, profile.CGOOptional)
+	assert.Equal(t, `imports "C", with a pure-Go fallback`, profile.EvidenceFor("cgoEnabled")[0].Rule)
+}
+
+func TestCgo_None(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":                 &fstest.MapFile{Data: []byte("module example.com/api\n")},
+		"main.go":                &fstest.MapFile{Data: []byte("package main\n")},
+		"tools/gen/main.go":      &fstest.MapFile{Data: []byte("//go:build ignore\n\npackage main\n\nimport \"C\"\n")},
+		"plugin/go.mod":          &fstest.MapFile{Data: []byte("module example.com/plugin\n")},
+		"plugin/plugin.go":       &fstest.MapFile{Data: []byte("package plugin\n\nimport \"C\"\n")},
+		"internal/x/x_darwin.go": &fstest.MapFile{Data: []byte("package x\n\nimport \"C\"\n")},
+	}
+
+	profile := &flkr.AppProfile{Language: flkr.LangGo}
+	addCgo(fsys, profile, systemDepsFor())
+	assert.Empty(t, profile.CGOEnabled, "no cgo evidence, so nothing to turn off")
+	assert.Empty(t, profile.EvidenceFor("cgoEnabled"))
+
+	node := &flkr.AppProfile{Language: flkr.LangNode}
+	addCgo(fsys, node, systemDepsFor())
+	assert.Empty(t, node.CGOEnabled)
+}
+
+func TestCgo_TurnedOff(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte(`module example.com/thumbs
+
+go 1.24
+
+require github.com/davidbyttow/govips/v2 v2.15.0
+`)},
+		"main.go":   &fstest.MapFile{Data: []byte("package main\n")},
+		"flkr.toml": &fstest.MapFile{Data: []byte("cgoEnabled = false\n\n[systemDeps]\nadd = [\"vips\"]\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "0", profile.CGOEnabled)
+	assert.Equal(t, []string{"vips"}, profile.SystemDeps, "deps also added by hand stay")
+	assert.Empty(t, profile.BuildDeps)
+	require.Len(t, profile.Warnings, 1)
+	assert.Equal(t, "cgo is turned off in flkr.toml, but go.mod requires github.com/davidbyttow/govips/v2, which uses cgo", profile.Warnings[0])
+
+	ev := profile.EvidenceFor("cgoEnabled")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "config", ev[0].Detector)
+}
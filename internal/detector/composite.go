@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// frontendManifests are the globs searched for frontend package.json files
+// accompanying a non-Node app: the app root and up to two levels below it
+// (web/, frontend/, app/frontend/, ...).
+var frontendManifests = []string{"package.json", "*/package.json", "*/*/package.json"}
+
+// serverFrameworks are Node frameworks that run their own server. A
+// package.json using one is a separate app, not a build stage.
+var serverFrameworks = map[flkr.Framework]bool{
+	flkr.FrameworkNextJS: true,
+	flkr.FrameworkNuxt:   true,
+	flkr.FrameworkRemix:  true,
+}
+
+// addBuildStages attaches the frontend builds that must run before the
+// primary build of a non-Node app, such as a Vite app under web/ whose
+// output a Go server embeds or a Rails app's jsbundling assets. Each stage
+// is detected by the registry's node detector, so disabling or replacing
+// it through the plugin API applies here too.
+func (r *Registry) addBuildStages(ctx context.Context, root fs.FS, best *flkr.AppProfile) []string {
+	if best.Language == flkr.LangNode {
+		return nil
+	}
+	var node Detector
+	for _, d := range r.detectors {
+		if d.Name() == "node" {
+			node = d
+		}
+	}
+	if node == nil {
+		return nil
+	}
+
+	var manifests []string
+	for _, pattern := range frontendManifests {
+		matches, _ := fs.Glob(root, pattern)
+		manifests = append(manifests, matches...)
+	}
+	sort.Strings(manifests)
+
+	var warnings []string
+	for _, manifest := range manifests {
+		dir := path.Dir(manifest)
+		sub := root
+		if dir != "." {
+			var err error
+			if sub, err = fs.Sub(root, dir); err != nil {
+				continue
+			}
+		}
+		res := runDetector(ctx, node, sub, r.timeout)
+		if res.err != nil {
+			warnings = append(warnings, "detector "+node.Name()+": "+dir+": "+res.err.Error())
+			continue
+		}
+		p := res.profile
+		if !res.matched || p == nil || p.BuildCommand == "" || serverFrameworks[p.Framework] {
+			continue
+		}
+		if dir == "." && p.StartCommand != "" {
+			// The root package.json serves the app itself.
+			continue
+		}
+
+		stage := flkr.BuildStage{
+			Name:              stageName(dir),
+			Language:          p.Language,
+			Version:           p.Version,
+			VersionConstraint: p.VersionConstraint,
+			PackageManager:    p.PackageManager,
+			HasLockfile:       p.HasLockfile,
+			Dir:               dir,
+			BuildCommand:      p.BuildCommand,
+		}
+		if out := stageOutputDir(sub, p); out != "" {
+			stage.OutputDir = path.Join(dir, out)
+		}
+		best.Stages = append(best.Stages, stage)
+
+		raw := readFileString(sub, "package.json")
+		best.AddEvidence(flkr.Evidence{
+			Field:    "stages",
+			Value:    dir + ": " + stage.BuildCommand,
+			Detector: node.Name(),
+			File:     manifest,
+			Line:     lineOf(raw, `"build"`),
+			Rule:     "frontend build runs before the " + string(best.Language) + " build",
+		})
+		r.logf("detector %s: build stage %s (%s)", node.Name(), dir, stage.BuildCommand)
+	}
+	return warnings
+}
+
+// isFrontendBuild reports whether p is a Node project that only builds
+// static assets: it has a build script but no server of its own.
+func isFrontendBuild(p *flkr.AppProfile) bool {
+	return p.Language == flkr.LangNode && p.BuildCommand != "" && p.StartCommand == "" && !serverFrameworks[p.Framework]
+}
+
+// stageName names a build stage after its directory.
+func stageName(dir string) string {
+	if dir == "." {
+		return "frontend"
+	}
+	return path.Base(dir)
+}
+
+var (
+	viteOutDirRe = regexp.MustCompile("outDir\\s*:\\s*['\"`]([^'\"`]+)['\"`]")
+	cliOutDirRe  = regexp.MustCompile(`--out-?dir[= ](\S+)`)
+	viteConfigs  = []string{"vite.config.ts", "vite.config.js", "vite.config.mts", "vite.config.mjs"}
+)
+
+// stageOutputDir returns where a frontend build writes its output,
+// relative to the frontend directory: an explicit outDir in the Vite config
+// or build command, else the framework default.
+func stageOutputDir(root fs.FS, p *flkr.AppProfile) string {
+	for _, name := range viteConfigs {
+		if m := viteOutDirRe.FindStringSubmatch(readFileString(root, name)); m != nil {
+			return path.Clean(m[1])
+		}
+	}
+	if m := cliOutDirRe.FindStringSubmatch(p.BuildCommand); m != nil {
+		return path.Clean(m[1])
+	}
+	return p.OutputDir
+}
@@ -0,0 +1,138 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildStages_GoWithViteFrontend(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/shop\n\ngo 1.22.0\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"web/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build", "dev": "vite"}, "devDependencies": {"vite": "5.0.0"}}`),
+		},
+		"web/pnpm-lock.yaml":     &fstest.MapFile{Data: []byte("lockfileVersion: '9.0'\n")},
+		"web/vite.config.ts":     &fstest.MapFile{Data: []byte("export default { build: { outDir: '../internal/ui/dist' } }\n")},
+		"tools/package.json":     &fstest.MapFile{Data: []byte(`{"devDependencies": {"prettier": "3.0.0"}}`)},
+		"docs/site/package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"build": "next build", "start": "next start"}, "dependencies": {"next": "14.0.0"}}`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.Equal(t, []flkr.BuildStage{{
+		Name:           "web",
+		Language:       flkr.LangNode,
+		PackageManager: flkr.PkgPNPM,
+		HasLockfile:    true,
+		Dir:            "web",
+		BuildCommand:   "vite build",
+		OutputDir:      "internal/ui/dist",
+	}}, profile.Stages)
+
+	ev := profile.EvidenceFor("stages")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "web/package.json", ev[0].File)
+}
+
+func TestBuildStages_FrontendAtRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module myapp\n\ngo 1.22.0\n")},
+		"package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "dependencies": {"vite": "5.0.0"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	require.Len(t, profile.Stages, 1)
+	assert.Equal(t, ".", profile.Stages[0].Dir)
+	assert.Equal(t, "dist", profile.Stages[0].OutputDir)
+}
+
+func TestBuildStages_RailsJSBundling(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Gemfile":      &fstest.MapFile{Data: []byte("gem 'rails'\ngem 'jsbundling-rails'\n")},
+		"Gemfile.lock": &fstest.MapFile{Data: []byte("GEM\n")},
+		"package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "esbuild app/javascript/*.* --bundle --outdir=app/assets/builds"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangRuby, profile.Language)
+	require.Len(t, profile.Stages, 1)
+	assert.Equal(t, "app/assets/builds", profile.Stages[0].OutputDir)
+}
+
+func TestBuildStages_NodeAppHasNone(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "dependencies": {"vite": "5.0.0"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.Empty(t, profile.Stages)
+}
+
+func TestBuildStages_WorkspaceMembersAreNotStages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.work":      &fstest.MapFile{Data: []byte("go 1.22\n\nuse ./api\n")},
+		"package.json": &fstest.MapFile{Data: []byte(`{"workspaces": ["web"]}`)},
+		"api/go.mod":   &fstest.MapFile{Data: []byte("module example.com/api\n")},
+		"api/main.go":  &fstest.MapFile{Data: []byte("package main\n")},
+		"api/ui/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "dependencies": {"vite": "5.0.0"}}`),
+		},
+		"web/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "dependencies": {"vite": "5.0.0"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "api", profiles[0].Path)
+	require.Len(t, profiles[0].Stages, 1)
+	assert.Equal(t, "ui", profiles[0].Stages[0].Dir)
+}
+
+func TestBuildStages_RootMemberSkipsMemberFrontend(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.work":      &fstest.MapFile{Data: []byte("go 1.22\n\nuse .\n")},
+		"go.mod":       &fstest.MapFile{Data: []byte("module example.com/app\n")},
+		"main.go":      &fstest.MapFile{Data: []byte("package main\n")},
+		"package.json": &fstest.MapFile{Data: []byte(`{"workspaces": ["web"]}`)},
+		"web/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "dependencies": {"vite": "5.0.0"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, ".", profiles[0].Path)
+	assert.Empty(t, profiles[0].Stages)
+	assert.Empty(t, profiles[0].EvidenceFor("stages"))
+}
@@ -0,0 +1,272 @@
+package detector
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// ConfigFile is the name of the project-level configuration file that pins
+// and edits detected values.
+const ConfigFile = "flkr.toml"
+
+// loadConfig reads flkr.toml from root along with its raw content. It
+// returns a nil config when the file does not exist.
+func loadConfig(root fs.FS) (*parser.FlkrTOML, string, error) {
+	cfg, err := parser.ParseFlkrTOML(root, ConfigFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, "", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return cfg, readFileString(root, ConfigFile), nil
+}
+
+// applyOverrides applies pinned values and list edits from a flkr.toml
+// section onto profile. section is the TOML table the overrides came from
+// ("" for the top level) and is only used to locate evidence lines in raw.
+func applyOverrides(profile *flkr.AppProfile, o parser.ProfileOverrides, raw, section string) {
+	pins := &flkr.AppProfile{
+		Language:       flkr.Language(o.Language),
+		Version:        o.Version,
+		Toolchain:      o.Toolchain,
+		PackageManager: flkr.PackageManager(o.PackageManager),
+		Framework:      flkr.Framework(o.Framework),
+		BuildCommand:   o.BuildCommand,
+		StartCommand:   o.StartCommand,
+		ReleaseCommand: o.ReleaseCommand,
+		TestCommand:    o.TestCommand,
+		HealthCheck:    o.HealthCheck,
+		OutputDir:      o.OutputDir,
+		Port:           o.Port,
+		PortEnv:        o.PortEnv,
+		PortProtocol:   o.PortProtocol,
+		AppVersion:     o.AppVersion,
+		LockfileType:   o.LockfileType,
+		HasLockfile:    o.LockfileType != "" || o.HasLockfile != nil && *o.HasLockfile,
+		SystemDeps:     o.SystemDeps.Add,
+		BuildDeps:      o.BuildDeps.Add,
+		EnvVars:        o.EnvVars.Add,
+	}
+
+	cgoRequired := profile.CGOEnabled == "1" && !profile.CGOOptional
+	cgoReason := profile.EvidenceFor("cgoEnabled")
+	if o.CGOEnabled != nil {
+		pins.CGOEnabled = "0"
+		if *o.CGOEnabled {
+			pins.CGOEnabled = "1"
+		}
+		pins.CGOOptional = profile.CGOOptional
+	}
+
+	ev := newRecorder(pins, "config")
+	pin := func(field, key string, value any, set bool) {
+		if set {
+			ev.found(field, value, ConfigFile, configLine(raw, section, key), "pinned in "+ConfigFile)
+		}
+	}
+	pin("language", "language", o.Language, o.Language != "")
+	pin("version", "version", o.Version, o.Version != "")
+	pin("toolchain", "toolchain", o.Toolchain, o.Toolchain != "")
+	pin("packageManager", "packageManager", o.PackageManager, o.PackageManager != "")
+	pin("framework", "framework", o.Framework, o.Framework != "")
+	pin("buildCommand", "buildCommand", o.BuildCommand, o.BuildCommand != "")
+	pin("startCommand", "startCommand", o.StartCommand, o.StartCommand != "")
+	pin("releaseCommand", "releaseCommand", o.ReleaseCommand, o.ReleaseCommand != "")
+	pin("testCommand", "testCommand", o.TestCommand, o.TestCommand != "")
+	pin("healthCheck", "healthCheck", o.HealthCheck, o.HealthCheck != "")
+	pin("outputDir", "outputDir", o.OutputDir, o.OutputDir != "")
+	pin("port", "port", o.Port, o.Port != 0)
+	pin("portEnv", "portEnv", o.PortEnv, o.PortEnv != "")
+	pin("portProtocol", "portProtocol", o.PortProtocol, o.PortProtocol != "")
+	pin("appVersion", "appVersion", o.AppVersion, o.AppVersion != "")
+	pin("lockfileType", "lockfileType", o.LockfileType, o.LockfileType != "")
+	pin("hasLockfile", "hasLockfile", pins.HasLockfile, o.HasLockfile != nil)
+	pin("cgoEnabled", "cgoEnabled", pins.CGOEnabled, o.CGOEnabled != nil)
+	for _, dep := range o.SystemDeps.Add {
+		ev.found("systemDeps", dep, ConfigFile, configLine(raw, section, "systemDeps"), "added in "+ConfigFile)
+	}
+	for _, dep := range o.BuildDeps.Add {
+		ev.found("buildDeps", dep, ConfigFile, configLine(raw, section, "buildDeps"), "added in "+ConfigFile)
+	}
+	for _, v := range o.EnvVars.Add {
+		ev.found("envVars", v, ConfigFile, configLine(raw, section, "envVars"), "added in "+ConfigFile)
+	}
+
+	if o.HasLockfile != nil && !*o.HasLockfile {
+		profile.HasLockfile = false
+		profile.LockfileType = ""
+		profile.Evidence = slices.DeleteFunc(profile.Evidence, func(e flkr.Evidence) bool {
+			return e.Field == "lockfileType"
+		})
+	}
+	profile.Merge(pins)
+	profile.SystemDeps = removeValues(profile, "systemDeps", profile.SystemDeps, o.SystemDeps.Remove)
+	profile.BuildDeps = removeValues(profile, "buildDeps", profile.BuildDeps, o.BuildDeps.Remove)
+	profile.EnvVars = removeValues(profile, "envVars", profile.EnvVars, o.EnvVars.Remove)
+	profile.Env = slices.DeleteFunc(profile.Env, func(v flkr.EnvVar) bool {
+		return slices.Contains(o.EnvVars.Remove, v.Name)
+	})
+	editBinaries(profile, o, raw, section)
+	profile.Tags = editList(profile, "tags", profile.Tags, o.Tags, raw, section)
+	profile.Ldflags = editList(profile, "ldflags", profile.Ldflags, o.Ldflags, raw, section)
+	pinProcesses(profile, o.Processes, raw, section)
+
+	if pins.CGOEnabled == "0" {
+		dropCgoDeps(profile)
+		if cgoRequired && len(cgoReason) > 0 {
+			r := cgoReason[0]
+			profile.Warnings = append(profile.Warnings, fmt.Sprintf("cgo is turned off in %s, but %s %s", ConfigFile, r.File, r.Rule))
+		}
+	}
+}
+
+// editBinaries adds and removes the main packages of a Go app. The build
+// and start commands are rebuilt from the binaries left when they are the
+// ones detection derived from the binaries, and not pinned.
+func editBinaries(profile *flkr.AppProfile, o parser.ProfileOverrides, raw, section string) {
+	if len(o.Binaries.Add) == 0 && len(o.Binaries.Remove) == 0 {
+		return
+	}
+	pkgPath := func(p string) string {
+		if p = path.Clean(p); p == "." {
+			return p
+		}
+		return "./" + strings.TrimPrefix(p, "./")
+	}
+	var build, start string
+	if len(profile.Binaries) > 0 {
+		build, start = goCommands(profile.Binaries)
+	}
+
+	var remove []string
+	for _, p := range o.Binaries.Remove {
+		remove = append(remove, pkgPath(p))
+	}
+	profile.Binaries = slices.DeleteFunc(profile.Binaries, func(b flkr.Binary) bool {
+		return slices.Contains(remove, b.Package)
+	})
+	removeValues(profile, "binaries", nil, remove)
+
+	ev := newRecorder(profile, "config")
+	line := configLine(raw, section, "binaries")
+	for _, p := range o.Binaries.Add {
+		p = pkgPath(p)
+		if slices.ContainsFunc(profile.Binaries, func(b flkr.Binary) bool { return b.Package == p }) {
+			continue
+		}
+		profile.Binaries = append(profile.Binaries, flkr.Binary{Name: path.Base(p), Package: p})
+		ev.found("binaries", p, ConfigFile, line, "added in "+ConfigFile)
+	}
+
+	if profile.Language != flkr.LangGo || len(profile.Binaries) == 0 {
+		return
+	}
+	newBuild, newStart := goCommands(profile.Binaries)
+	rebuild := func(field string, cmd *string, old, pinned, value string) {
+		if pinned != "" || *cmd != old || old == value {
+			return
+		}
+		*cmd = value
+		profile.Evidence = slices.DeleteFunc(profile.Evidence, func(e flkr.Evidence) bool { return e.Field == field })
+		ev.found(field, value, ConfigFile, line, "binaries edited in "+ConfigFile)
+	}
+	rebuild("buildCommand", &profile.BuildCommand, build, o.BuildCommand, newBuild)
+	rebuild("startCommand", &profile.StartCommand, start, o.StartCommand, newStart)
+}
+
+// editList removes and adds the entries of a list field that Merge
+// replaces rather than accumulates.
+func editList(profile *flkr.AppProfile, field string, list []string, e parser.ListEdit, raw, section string) []string {
+	list = removeValues(profile, field, list, e.Remove)
+	ev := newRecorder(profile, "config")
+	for _, v := range e.Add {
+		if slices.Contains(list, v) {
+			continue
+		}
+		list = append(list, v)
+		ev.found(field, v, ConfigFile, configLine(raw, section, field), "added in "+ConfigFile)
+	}
+	return list
+}
+
+// pinProcesses replaces or adds the process types pinned in flkr.toml,
+// and removes those pinned with an empty command.
+func pinProcesses(profile *flkr.AppProfile, procs map[string]string, raw, section string) {
+	names := slices.Sorted(maps.Keys(procs))
+	ev := newRecorder(profile, "config")
+	line := configLine(raw, section, "processes")
+	for _, name := range names {
+		profile.Processes = slices.DeleteFunc(profile.Processes, func(p flkr.Process) bool { return p.Name == name })
+		profile.Evidence = slices.DeleteFunc(profile.Evidence, func(e flkr.Evidence) bool {
+			return e.Field == "processes" && strings.HasPrefix(e.Value, name+": ")
+		})
+		if cmd := procs[name]; cmd != "" {
+			profile.Processes = append(profile.Processes, flkr.Process{Name: name, Command: cmd})
+			ev.found("processes", name+": "+cmd, ConfigFile, line, "pinned in "+ConfigFile)
+		}
+	}
+}
+
+// removeValues drops the given values from a list field along with their
+// evidence.
+func removeValues(profile *flkr.AppProfile, field string, list, remove []string) []string {
+	if len(remove) == 0 {
+		return list
+	}
+	drop := make(map[string]bool, len(remove))
+	for _, r := range remove {
+		drop[r] = true
+	}
+
+	var kept []string
+	for _, v := range list {
+		if !drop[v] {
+			kept = append(kept, v)
+		}
+	}
+
+	evidence := profile.Evidence[:0]
+	for _, e := range profile.Evidence {
+		if e.Field != field || !drop[e.Value] {
+			evidence = append(evidence, e)
+		}
+	}
+	profile.Evidence = evidence
+	return kept
+}
+
+// configLine returns the 1-based line setting key within a flkr.toml
+// section: a key = ... or key.sub = ... line in the section's body, or the
+// header of a [section.key] table. It returns 0 if there is none.
+func configLine(raw, section, key string) int {
+	table := key
+	if section != "" {
+		table = section + "." + key
+	}
+	keyRe := regexp.MustCompile(`^\s*(?:` + regexp.QuoteMeta(key) + `|"` + regexp.QuoteMeta(key) + `")\s*[.=]`)
+	in := section == ""
+	for i, line := range strings.Split(raw, "\n") {
+		if h, ok := strings.CutPrefix(strings.TrimSpace(line), "["); ok {
+			header := strings.TrimSpace(h[:max(strings.LastIndex(h, "]"), 0)])
+			if header == table {
+				return i + 1
+			}
+			in = header == section
+			continue
+		}
+		if in && keyRe.MatchString(line) {
+			return i + 1
+		}
+	}
+	return 0
+}
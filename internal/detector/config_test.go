@@ -0,0 +1,240 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfig_PinsAndListEdits(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mix.exs": &fstest.MapFile{Data: []byte(`defp deps do [{:phoenix, "~> 1.7"}] end`)},
+		".env.example": &fstest.MapFile{
+			Data: []byte("DATABASE_URL=\nDEBUG_TOOLBAR=\n"),
+		},
+		"config/dev.exs": &fstest.MapFile{Data: []byte(`config :app, toolbar: System.get_env("DEBUG_TOOLBAR")`)},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`port = 4100
+startCommand = "mix phx.server --no-halt"
+
+[systemDeps]
+add = ["openssl"]
+remove = ["inotify-tools"]
+
+[envVars]
+add = ["SECRET_KEY_BASE"]
+remove = ["DEBUG_TOOLBAR"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.FrameworkPhoenix, profile.Framework)
+	assert.Equal(t, 4100, profile.Port)
+	assert.Equal(t, "mix phx.server --no-halt", profile.StartCommand)
+	assert.Equal(t, []string{"openssl"}, profile.SystemDeps)
+	assert.Equal(t, []string{"DATABASE_URL", "SECRET_KEY_BASE"}, profile.EnvVars)
+	assert.Empty(t, profile.Env, "removed vars lose their details too")
+
+	port := profile.EvidenceFor("port")
+	require.Len(t, port, 1)
+	assert.Equal(t, flkr.Evidence{
+		Field: "port", Value: "4100", Detector: "config",
+		File: "flkr.toml", Line: 1, Rule: "pinned in flkr.toml",
+	}, port[0])
+	for _, e := range profile.EvidenceFor("systemDeps") {
+		assert.NotEqual(t, "inotify-tools", e.Value)
+	}
+}
+
+func TestConfig_ForceLanguage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pom.xml": &fstest.MapFile{
+			Data: []byte(`<project><parent><groupId>org.springframework.boot</groupId></parent></project>`),
+		},
+		"package.json": &fstest.MapFile{Data: []byte(`{"dependencies": {"next": "14.0.0"}}`)},
+		"flkr.toml":    &fstest.MapFile{Data: []byte("language = \"java\"\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangJava, profile.Language)
+	assert.Equal(t, flkr.FrameworkSpring, profile.Framework)
+	assert.Equal(t, "java", profile.DetectedBy)
+}
+
+func TestConfig_LanguageWithoutDetection(t *testing.T) {
+	fsys := fstest.MapFS{
+		"flkr.toml": &fstest.MapFile{Data: []byte(`language = "deno"
+packageManager = "deno"
+startCommand = "deno run -A main.ts"
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.Language("deno"), profile.Language)
+	assert.Equal(t, "config", profile.DetectedBy)
+	require.NoError(t, profile.Validate())
+}
+
+func TestConfig_WorkspaceApps(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"workspaces": ["apps/*"]}`)},
+		"apps/api/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node server.js"}}`),
+		},
+		"apps/web/package.json": &fstest.MapFile{
+			Data: []byte(`{"dependencies": {"vite": "5.0.0"}}`),
+		},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[apps."apps/api"]
+port = 4001
+
+[apps."apps/api".envVars]
+add = ["REDIS_URL"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, 4001, profiles[0].Port)
+	assert.Equal(t, []string{"REDIS_URL"}, profiles[0].EnvVars)
+	assert.Equal(t, 2, profiles[0].EvidenceFor("port")[0].Line)
+	assert.Equal(t, 3000, profiles[1].Port)
+}
+
+func TestConfig_Binaries(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":              &fstest.MapFile{Data: []byte("module example.com/acme\n\ngo 1.24\n")},
+		"cmd/api/main.go":     &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/cli/main.go":     &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/migrate/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[binaries]
+remove = ["cmd/cli"]
+add = ["./hack/seed"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, []flkr.Binary{
+		{Name: "api", Package: "./cmd/api"},
+		{Name: "migrate", Package: "./cmd/migrate"},
+		{Name: "seed", Package: "./hack/seed"},
+	}, profile.Binaries)
+	assert.Equal(t, "go build -o bin/ ./cmd/api ./cmd/migrate ./hack/seed", profile.BuildCommand)
+	assert.Equal(t, "./bin/api", profile.StartCommand)
+
+	build := profile.EvidenceFor("buildCommand")
+	require.Len(t, build, 1)
+	assert.Equal(t, "binaries edited in flkr.toml", build[0].Rule)
+	assert.NotEqual(t, "flkr.toml", profile.EvidenceFor("startCommand")[0].File, "the start command is unchanged")
+	for _, e := range profile.EvidenceFor("binaries") {
+		assert.NotEqual(t, "./cmd/cli", e.Value)
+	}
+}
+
+func TestConfig_ProcessesTagsLdflags(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":   &fstest.MapFile{Data: []byte("module example.com/acme\n\ngo 1.24\n")},
+		"main.go":  &fstest.MapFile{Data: []byte("package main\n")},
+		"Procfile": &fstest.MapFile{Data: []byte("web: ./acme\nworker: ./acme work\nclock: ./acme tick\n")},
+		"Makefile": &fstest.MapFile{Data: []byte("build:\n\tgo build -tags netgo -ldflags \"-s -w\" -o acme .\n")},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[processes]
+worker = "./acme work --queue=default"
+clock = ""
+
+[tags]
+add = ["osusergo"]
+remove = ["netgo"]
+
+[ldflags]
+remove = ["-w"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, []flkr.Process{{Name: "worker", Command: "./acme work --queue=default"}}, profile.Processes)
+	assert.Equal(t, []string{"osusergo"}, profile.Tags)
+	assert.Equal(t, []string{"-s"}, profile.Ldflags)
+
+	procs := profile.EvidenceFor("processes")
+	require.Len(t, procs, 1)
+	assert.Equal(t, "pinned in flkr.toml", procs[0].Rule)
+}
+
+func TestConfig_Lockfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Cargo.toml": &fstest.MapFile{Data: []byte("[package]\nname = \"acme\"\n")},
+		"Cargo.lock": &fstest.MapFile{Data: []byte("version = 3\n")},
+		"flkr.toml":  &fstest.MapFile{Data: []byte("hasLockfile = false\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.False(t, profile.HasLockfile)
+	assert.Empty(t, profile.LockfileType)
+	assert.Empty(t, profile.EvidenceFor("lockfileType"))
+
+	fsys["flkr.toml"] = &fstest.MapFile{Data: []byte("lockfileType = \"cargo\"\n")}
+	delete(fsys, "Cargo.lock")
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.True(t, profile.HasLockfile)
+	assert.Equal(t, "cargo", profile.LockfileType)
+}
+
+func TestConfig_Invalid(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":    &fstest.MapFile{Data: []byte("module myapp\n")},
+		"flkr.toml": &fstest.MapFile{Data: []byte("port = \"not a number\"\n")},
+	}
+
+	reg := NewRegistry()
+	_, err := reg.DetectBest(context.Background(), fsys)
+	assert.ErrorContains(t, err, "flkr.toml")
+}
+
+func TestConfigLine(t *testing.T) {
+	raw := `startCommand = "serve --port 80"
+port = 4000
+
+[systemDeps]
+add = ["x"]
+
+[apps."api"]
+# port = 1
+envVars.add = ["A"]
+port = 5000
+
+[apps."api".processes]
+worker = "w"
+`
+	assert.Equal(t, 2, configLine(raw, "", "port"))
+	assert.Equal(t, 4, configLine(raw, "", "systemDeps"))
+	assert.Equal(t, 0, configLine(raw, "", "envVars"))
+	assert.Equal(t, 10, configLine(raw, `apps."api"`, "port"))
+	assert.Equal(t, 9, configLine(raw, `apps."api"`, "envVars"))
+	assert.Equal(t, 12, configLine(raw, `apps."api"`, "processes"))
+	assert.Equal(t, 0, configLine(raw, `apps."api"`, "tags"))
+	assert.Equal(t, 0, configLine(raw, `apps."web"`, "port"))
+}
@@ -0,0 +1,155 @@
+package detector
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/narvanalabs/flkr/internal/parser"
+)
+
+// ConfigPin sets a top-level key of flkr.toml to Value, or removes the key
+// when Value is nil.
+type ConfigPin struct {
+	Key   string
+	Value any
+}
+
+// SaveConfigPins writes pins to the flkr.toml in dir, creating it if
+// needed. The file is edited in place: only the pinned keys change, and
+// the rest of it, comments included, is kept as written.
+func SaveConfigPins(dir string, pins []ConfigPin) error {
+	file := filepath.Join(dir, ConfigFile)
+	data, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	content, err := setConfigKeys(string(data), pins)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, []byte(content), 0o644)
+}
+
+// setConfigKeys applies pins to the top-level keys of a flkr.toml. A key
+// already set has its value replaced, keeping any trailing comment; a new
+// one is added after the last top-level key, or before the first table.
+func setConfigKeys(content string, pins []ConfigPin) (string, error) {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	for _, pin := range pins {
+		tables := len(lines)
+		last := -1
+		found := -1
+		for i, line := range lines {
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, "[") {
+				tables = i
+				break
+			}
+			if m := configKeyRe.FindStringSubmatch(line); m != nil {
+				last = i
+				if strings.Trim(m[1], `"'`) == pin.Key {
+					found = i
+				}
+			}
+		}
+
+		var value string
+		if pin.Value != nil {
+			v, err := encodeConfigValue(pin.Value)
+			if err != nil {
+				return "", err
+			}
+			value = pin.Key + " = " + v
+		}
+
+		switch {
+		case found >= 0:
+			line := lines[found]
+			eq := strings.Index(line, "=")
+			end, ok := configValueEnd(line[eq+1:])
+			if !ok {
+				return "", fmt.Errorf("%s: %s spans several lines; edit it by hand", ConfigFile, pin.Key)
+			}
+			if pin.Value == nil {
+				lines = append(lines[:found], lines[found+1:]...)
+				continue
+			}
+			indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+			lines[found] = indent + value + line[eq+1+end:]
+		case pin.Value == nil:
+		case last >= 0:
+			lines = append(lines[:last+1], append([]string{value}, lines[last+1:]...)...)
+		case tables < len(lines):
+			lines = append(lines[:tables], append([]string{value, ""}, lines[tables:]...)...)
+		default:
+			lines = append(lines, value)
+		}
+	}
+
+	out := strings.Join(lines, "\n")
+	if out != "" {
+		out += "\n"
+	}
+	var cfg parser.FlkrTOML
+	if err := toml.Unmarshal([]byte(out), &cfg); err != nil {
+		return "", fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+	return out, nil
+}
+
+var configKeyRe = regexp.MustCompile(`^\s*("[^"]*"|'[^']*'|[A-Za-z0-9_-]+)\s*=`)
+
+// encodeConfigValue renders v as a TOML value.
+func encodeConfigValue(v any) (string, error) {
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(map[string]any{"v": v}); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(strings.TrimPrefix(buf.String(), "v = ")), nil
+}
+
+// configValueEnd returns the length of the TOML value at the start of s,
+// including the blanks after it, and reports whether the value ends on
+// this line.
+func configValueEnd(s string) (int, bool) {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"', '\'':
+			if strings.HasPrefix(s[i:], strings.Repeat(string(c), 3)) {
+				return 0, false
+			}
+			j := i + 1
+			for ; j < len(s) && s[j] != c; j++ {
+				if c == '"' && s[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(s) {
+				return 0, false
+			}
+			i = j
+		case '[', '{':
+			depth++
+		case ']', '}':
+			depth--
+		case '#':
+			if depth > 0 {
+				return 0, false
+			}
+			return len(strings.TrimRight(s[:i], " \t")), true
+		}
+	}
+	return len(strings.TrimRight(s, " \t")), depth == 0
+}
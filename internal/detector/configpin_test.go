@@ -0,0 +1,73 @@
+package detector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveConfigPins_KeepsComments(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, ConfigFile)
+	require.NoError(t, os.WriteFile(file, []byte(`# Production settings.
+port = 4000 # behind the load balancer
+startCommand = "./bin/server --prod"
+
+# Image processing needs this.
+[systemDeps]
+add = ["imagemagick"]
+`), 0o644))
+
+	err := SaveConfigPins(dir, []ConfigPin{
+		{Key: "port", Value: 8080},
+		{Key: "startCommand", Value: nil},
+		{Key: "buildCommand", Value: `make "all"`},
+	})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, `# Production settings.
+port = 8080 # behind the load balancer
+buildCommand = "make \"all\""
+
+# Image processing needs this.
+[systemDeps]
+add = ["imagemagick"]
+`, string(data))
+}
+
+func TestSaveConfigPins_NewFile(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, SaveConfigPins(dir, []ConfigPin{
+		{Key: "language", Value: "go"},
+		{Key: "port", Value: 9000},
+	}))
+
+	data, err := os.ReadFile(filepath.Join(dir, ConfigFile))
+	require.NoError(t, err)
+	assert.Equal(t, "language = \"go\"\nport = 9000\n", string(data))
+}
+
+func TestSetConfigKeys(t *testing.T) {
+	// A key is added before the first table when there are no others.
+	out, err := setConfigKeys("[envVars]\nadd = [\"KEY\"]\n", []ConfigPin{{Key: "port", Value: 3000}})
+	require.NoError(t, err)
+	assert.Equal(t, "port = 3000\n\n[envVars]\nadd = [\"KEY\"]\n", out)
+
+	// Keys of tables are left alone.
+	out, err = setConfigKeys("[apps.\"web\"]\nport = 3000\n", []ConfigPin{{Key: "port", Value: 4000}})
+	require.NoError(t, err)
+	assert.Equal(t, "port = 4000\n\n[apps.\"web\"]\nport = 3000\n", out)
+
+	// A # inside a string is not a comment.
+	out, err = setConfigKeys(`startCommand = "run #1" # first`+"\n", []ConfigPin{{Key: "startCommand", Value: "run"}})
+	require.NoError(t, err)
+	assert.Equal(t, `startCommand = "run" # first`+"\n", out)
+
+	_, err = setConfigKeys("buildCommand = \"\"\"\nmake\n\"\"\"\n", []ConfigPin{{Key: "buildCommand", Value: "make all"}})
+	assert.ErrorContains(t, err, "buildCommand spans several lines")
+}
@@ -3,14 +3,18 @@ package detector
 import (
 	"context"
 	"io/fs"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
 // CrosscuttingDetector enriches a profile with data from .env.example,
-// Procfile, and Makefile. It doesn't match on its own — it's used as
-// a post-processing step.
+// the process types of a Procfile, the build, start and test targets of a
+// justfile, Taskfile or Makefile, and the backing services of
+// docker-compose.yml. It doesn't match on its own — it's used as a
+// post-processing step.
 type CrosscuttingDetector struct{}
 
 func (d *CrosscuttingDetector) Name() string  { return "crosscutting" }
@@ -18,17 +22,53 @@ func (d *CrosscuttingDetector) Priority() int { return 100 }
 
 func (d *CrosscuttingDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error) {
 	profile := &flkr.AppProfile{}
+	ev := newRecorder(profile, d.Name())
 	matched := false
 
 	// Parse .env.example for env var keys.
 	if envKeys := parseEnvExample(root); len(envKeys) > 0 {
-		profile.EnvVars = envKeys
+		content := readFileString(root, ".env.example")
+		for _, k := range envKeys {
+			profile.EnvVars = append(profile.EnvVars, k)
+			ev.found("envVars", k, ".env.example", lineOf(content, k+"="), "listed in .env.example")
+		}
+		matched = true
+	}
+
+	// Parse Procfile for the start command, release hook and other
+	// process types.
+	for _, proc := range parseProcfile(root) {
+		switch proc.name {
+		case "web":
+			profile.StartCommand = proc.command
+			ev.found("startCommand", proc.command, "Procfile", proc.line, "Procfile web process")
+			if m, ok := commandPort(proc.command); ok {
+				profile.Port, profile.PortEnv = m.port, m.env
+				if m.port != 0 {
+					ev.found("port", m.port, "Procfile", proc.line, m.rule)
+				}
+				if m.env != "" {
+					ev.found("portEnv", m.env, "Procfile", proc.line, m.rule)
+				}
+			}
+		case "release":
+			profile.ReleaseCommand = proc.command
+			ev.found("releaseCommand", proc.command, "Procfile", proc.line, "Procfile release process")
+		default:
+			profile.Processes = append(profile.Processes, flkr.Process{Name: proc.name, Command: proc.command})
+			ev.found("processes", proc.name+": "+proc.command, "Procfile", proc.line, "Procfile "+proc.name+" process")
+		}
+		matched = true
+	}
+
+	// Read the conventional targets of task runners for the commands
+	// the Procfile didn't set.
+	if addTaskTargets(root, profile, ev) {
 		matched = true
 	}
 
-	// Parse Procfile for start command.
-	if cmd := parseProcfile(root); cmd != "" {
-		profile.StartCommand = cmd
+	// Parse docker-compose.yml for backing services.
+	if findServices(root, ".", profile, ev) {
 		matched = true
 	}
 
@@ -57,17 +97,29 @@ func parseEnvExample(root fs.FS) []string {
 	return keys
 }
 
-// parseProcfile extracts the web process command from a Procfile.
-func parseProcfile(root fs.FS) string {
+// procfileEntry is a process type declared in a Procfile.
+type procfileEntry struct {
+	name    string
+	command string
+	line    int
+}
+
+var procfileRe = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// parseProcfile extracts the process types of a Procfile in file order,
+// each with its 1-based line number.
+func parseProcfile(root fs.FS) []procfileEntry {
 	content := readFileString(root, "Procfile")
 	if content == "" {
-		return ""
+		return nil
 	}
-	for _, line := range strings.Split(content, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "web:") {
-			return strings.TrimSpace(strings.TrimPrefix(line, "web:"))
+	var entries []procfileEntry
+	for i, line := range strings.Split(content, "\n") {
+		m := procfileRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil || slices.ContainsFunc(entries, func(e procfileEntry) bool { return e.name == m[1] }) {
+			continue
 		}
+		entries = append(entries, procfileEntry{name: m[1], command: strings.TrimSpace(m[2]), line: i + 1})
 	}
-	return ""
+	return entries
 }
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"testing/fstest"
 
+	"github.com/narvanalabs/flkr/pkg/flkr"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -35,6 +36,53 @@ func TestCrosscutting_Procfile(t *testing.T) {
 	require.NoError(t, err)
 	assert.True(t, matched)
 	assert.Equal(t, "node server.js", profile.StartCommand)
+	assert.Equal(t, []flkr.Process{{Name: "worker", Command: "node worker.js"}}, profile.Processes)
+
+	ev := profile.EvidenceFor("startCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "Procfile", ev[0].File)
+	assert.Equal(t, 1, ev[0].Line)
+}
+
+func TestCrosscutting_ProcfileProcessTypes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Procfile": &fstest.MapFile{Data: []byte(`# Heroku processes
+release: python manage.py migrate --noinput
+web: gunicorn app.wsgi --bind 0.0.0.0:$PORT
+worker: celery -A app worker -l info
+clock:   celery -A app beat
+`)},
+	}
+
+	profile, matched, err := (&CrosscuttingDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, "gunicorn app.wsgi --bind 0.0.0.0:$PORT", profile.StartCommand)
+	assert.Equal(t, "python manage.py migrate --noinput", profile.ReleaseCommand)
+	assert.Equal(t, []flkr.Process{
+		{Name: "worker", Command: "celery -A app worker -l info"},
+		{Name: "clock", Command: "celery -A app beat"},
+	}, profile.Processes)
+
+	assert.Equal(t, 2, profile.EvidenceFor("releaseCommand")[0].Line)
+	ev := profile.EvidenceFor("processes")
+	require.Len(t, ev, 2)
+	assert.Equal(t, "clock: celery -A app beat", ev[1].Value)
+	assert.Equal(t, 5, ev[1].Line)
+}
+
+func TestCrosscutting_ProcfileDetectBest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"start": "node server.js"}}`)},
+		"Procfile":     &fstest.MapFile{Data: []byte("release: npx prisma migrate deploy\nworker: node worker.js\n")},
+	}
+
+	profile, err := NewRegistry().DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "node server.js", profile.StartCommand)
+	assert.Equal(t, "npx prisma migrate deploy", profile.ReleaseCommand)
+	assert.Equal(t, []flkr.Process{{Name: "worker", Command: "node worker.js"}}, profile.Processes)
 }
 
 func TestCrosscutting_NoFiles(t *testing.T) {
@@ -44,3 +92,17 @@ func TestCrosscutting_NoFiles(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, matched)
 }
+
+func TestCrosscutting_ProcfilePort(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Procfile": &fstest.MapFile{Data: []byte("web: gunicorn app:app --bind 0.0.0.0:${PORT:-5001}\n")},
+	}
+
+	profile, matched, err := (&CrosscuttingDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, 5001, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	require.Len(t, profile.EvidenceFor("port"), 1)
+	assert.Equal(t, 1, profile.EvidenceFor("port")[0].Line)
+}
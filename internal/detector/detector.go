@@ -1,22 +1,10 @@
 package detector
 
 import (
-	"context"
-	"io/fs"
-
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
 // Detector analyzes a repository filesystem and returns an AppProfile
-// if the ecosystem is detected.
-type Detector interface {
-	// Name returns a human-readable identifier for this detector.
-	Name() string
-
-	// Detect inspects the filesystem and returns a profile if detected.
-	// The boolean indicates whether the detector matched at all.
-	Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error)
-
-	// Priority controls execution order; lower values run first.
-	Priority() int
-}
+// if the ecosystem is detected. It is an alias of the public flkr.Detector
+// so built-in and externally registered detectors are interchangeable.
+type Detector = flkr.Detector
@@ -0,0 +1,530 @@
+package detector
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// dockerfileNames are the files read by DockerfileDetector, in order of
+// precedence, after the Dockerfile named in the [build] section of a
+// fly.toml.
+var dockerfileNames = []string{"Dockerfile", "Containerfile"}
+
+// DockerfileDetector reads an existing Dockerfile, which often holds the
+// most reliable description of how an app is built and run. It doesn't
+// select a stack on its own: its profile carries the runtime of the base
+// image, which breaks ties between candidates, and fills the fields the
+// manifest-based detectors left empty or defaulted.
+type DockerfileDetector struct {
+	// deps maps apt and apk packages to nixpkgs attributes.
+	deps systemDepMap
+}
+
+func (d *DockerfileDetector) Name() string  { return "dockerfile" }
+func (d *DockerfileDetector) Priority() int { return 100 }
+
+// dockerImage maps an official base image to the language it provides.
+// version extracts the language version from the image tag.
+type dockerImage struct {
+	lang    flkr.Language
+	version *regexp.Regexp
+}
+
+var (
+	leadingVersionRe = regexp.MustCompile(`^v?(\d+(?:\.\d+)*)`)
+	jdkTagRe         = regexp.MustCompile(`(?:jdk-?|temurin-|openjdk-?|corretto-)(\d+)`)
+	pythonTagRe      = regexp.MustCompile(`python(\d+(?:\.\d+)*)`)
+	erlangTagRe      = regexp.MustCompile(`(?:erlang-|otp-)(\d+(?:\.\d+)*)`)
+)
+
+var dockerImages = map[string]dockerImage{
+	"node":            {flkr.LangNode, leadingVersionRe},
+	"python":          {flkr.LangPython, leadingVersionRe},
+	"uv":              {flkr.LangPython, pythonTagRe},
+	"golang":          {flkr.LangGo, leadingVersionRe},
+	"rust":            {flkr.LangRust, leadingVersionRe},
+	"ruby":            {flkr.LangRuby, leadingVersionRe},
+	"elixir":          {flkr.LangElixir, leadingVersionRe},
+	"php":             {flkr.LangPHP, leadingVersionRe},
+	"openjdk":         {flkr.LangJava, leadingVersionRe},
+	"eclipse-temurin": {flkr.LangJava, leadingVersionRe},
+	"amazoncorretto":  {flkr.LangJava, leadingVersionRe},
+	"maven":           {flkr.LangJava, jdkTagRe},
+	"gradle":          {flkr.LangJava, jdkTagRe},
+}
+
+var (
+	// buildStepRe matches the part of a RUN instruction that builds the app,
+	// as opposed to installing packages or dependencies.
+	buildStepRe = regexp.MustCompile(`^(?:\w+=\S* )*(?:(?:npm|pnpm|yarn|bun) (?:run )?build\b|go build\b|cargo build\b|` +
+		`(?:\./)?(?:mvnw?|gradlew?)\b|mix (?:compile|release|assets\.deploy)\b|` +
+		`(?:bundle exec )?(?:rails|rake) assets:precompile\b|python[\d.]* manage\.py collectstatic\b|make\b)`)
+
+	// pkgInstallRe matches an apt or apk command installing packages.
+	pkgInstallRe = regexp.MustCompile(`^(?:apt-get|apt) (?:[^&;|]* )?install |^apk (?:[^&;|]* )?add `)
+
+	// initWrappers are init processes that an entrypoint runs the app under.
+	initWrappers = []string{"tini", "dumb-init", "/sbin/tini", "/usr/bin/tini", "/usr/bin/dumb-init"}
+
+	// dockerNoiseRe matches ENV and ARG variables that configure the image
+	// or the toolchain rather than the app.
+	dockerNoiseRe = regexp.MustCompile(`^(?:PATH|HOME|USER|LANG|LANGUAGE|LC_\w+|TERM|TZ|DEBIAN_FRONTEND|` +
+		`PYTHONDONTWRITEBYTECODE|PYTHONUNBUFFERED|CGO_ENABLED|GOOS|GOARCH|GOPATH|GOFLAGS|` +
+		`\w+_VERSION|\w+_HOME|PIP_\w+|POETRY_\w+|UV_\w+|NPM_CONFIG_\w+|BUNDLE_\w+|GEM_\w+|TARGET\w*|BUILD\w*)$`)
+)
+
+func (d *DockerfileDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error) {
+	names := dockerfileNames
+	if fly, err := parser.ParseFlyTOML(root, "fly.toml"); err == nil && fly.Build.Dockerfile != "" {
+		names = append([]string{path.Clean(fly.Build.Dockerfile)}, names...)
+	}
+	var name string
+	for _, n := range names {
+		if fileExists(root, n) {
+			name = n
+			break
+		}
+	}
+	if name == "" {
+		return nil, false, nil
+	}
+	df, err := parser.ParseDockerfile(root, name)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(df.Stages) == 0 {
+		return nil, false, nil
+	}
+
+	profile := &flkr.AppProfile{}
+	ev := newRecorder(profile, d.Name())
+	args := map[string]string{}
+	for _, inst := range df.Args {
+		for _, v := range inst.Vars() {
+			args[v.Name] = v.Value
+		}
+	}
+
+	// The runtime comes from the last stage built on a language image,
+	// which is the final stage unless it copies a binary onto a bare one.
+	final := len(df.Stages) - 1
+	for i := final; i >= 0; i-- {
+		s := &df.Stages[i]
+		ref := os.Expand(baseImage(df, s), func(k string) string { return args[k] })
+		img, tag := splitImage(ref)
+		di, ok := dockerImages[img]
+		if !ok {
+			continue
+		}
+		profile.Language = di.lang
+		profile.BaseImage = ref
+		ev.found("baseImage", ref, name, s.Line, "FROM "+ref)
+		if m := di.version.FindStringSubmatch(tag); m != nil {
+			profile.Version = m[1]
+			ev.found("version", profile.Version, name, s.Line, "tag of base image "+ref)
+		}
+		if di.lang == flkr.LangElixir {
+			if m := erlangTagRe.FindStringSubmatch(tag); m != nil {
+				profile.ErlangVersion = m[1]
+				ev.found("erlangVersion", profile.ErlangVersion, name, s.Line, "tag of base image "+ref)
+			}
+		}
+		break
+	}
+	d.buildCommand(profile, ev, name, df, args)
+
+	chain := stageChain(df, final)
+	workdir := "/"
+	var entrypoint, cmd *parser.DockerInstruction
+	for _, inst := range chain {
+		switch inst.Cmd {
+		case "WORKDIR":
+			workdir = path.Join(workdir, inst.Args)
+		case "ENTRYPOINT":
+			entrypoint, cmd = &inst, nil
+		case "CMD":
+			cmd = &inst
+		case "EXPOSE":
+			if profile.Port != 0 {
+				continue
+			}
+			port, _, _ := strings.Cut(strings.Fields(inst.Args + " ")[0], "/")
+			if n, err := strconv.Atoi(port); err == nil {
+				profile.Port = n
+				ev.found("port", n, name, inst.Line, "EXPOSE")
+			}
+		case "ENV", "ARG":
+			d.addVars(profile, ev, name, inst)
+		}
+	}
+	if start, line := startCommand(entrypoint, cmd, workdir); start != "" {
+		profile.StartCommand = start
+		ev.found("startCommand", start, name, line, "CMD and ENTRYPOINT of the final stage")
+	}
+	if v, ok := profile.LookupEnv("PORT"); ok {
+		profile.PortEnv = "PORT"
+		e := profile.EvidenceFor("envVars")
+		line := e[slices.IndexFunc(e, func(e flkr.Evidence) bool { return e.Value == "PORT" })].Line
+		ev.found("portEnv", "PORT", name, line, "PORT set with ENV")
+		if n, err := strconv.Atoi(v.Default); err == nil && profile.Port == 0 {
+			profile.Port = n
+			ev.found("port", n, name, line, "PORT set with ENV")
+		}
+	}
+
+	for i := range df.Stages {
+		d.addPackages(profile, ev, name, df.Stages[i], i == final)
+	}
+
+	return profile, true, nil
+}
+
+// baseImage returns the image a stage is ultimately built from, following
+// references to earlier stages.
+func baseImage(df *parser.Dockerfile, s *parser.DockerStage) string {
+	for range df.Stages {
+		parent, ok := df.Stage(s.Image)
+		if !ok || parent == s {
+			break
+		}
+		s = parent
+	}
+	return s.Image
+}
+
+// stageChain returns the instructions in effect for stage i: those of the
+// stages it is built from, then its own.
+func stageChain(df *parser.Dockerfile, i int) []parser.DockerInstruction {
+	s := &df.Stages[i]
+	chain := slices.Clone(s.Instructions)
+	for range df.Stages {
+		parent, ok := df.Stage(s.Image)
+		if !ok || parent == s {
+			break
+		}
+		s = parent
+		chain = append(slices.Clone(s.Instructions), chain...)
+	}
+	return chain
+}
+
+// splitImage returns the repository name of an image reference without
+// its registry or namespace, and its tag.
+func splitImage(ref string) (name, tag string) {
+	ref, _, _ = strings.Cut(ref, "@")
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, tag = ref[:i], ref[i+1:]
+	}
+	return path.Base(ref), tag
+}
+
+// buildCommand records the RUN steps that build the app. They are taken
+// from the stages built on an image of the app's language, and from those
+// on other images that the final stage copies from, such as an alpine
+// stage installing its own toolchain. Steps writing to an absolute path
+// are left out, as a Nix build can only write to its own directories.
+func (d *DockerfileDetector) buildCommand(profile *flkr.AppProfile, ev recorder, file string, df *parser.Dockerfile, args map[string]string) {
+	copied := copiedStages(df, len(df.Stages)-1)
+	var steps []string
+	line := 0
+	for i := range df.Stages {
+		s := &df.Stages[i]
+		img, _ := splitImage(os.Expand(baseImage(df, s), func(k string) string { return args[k] }))
+		di, known := dockerImages[img]
+		if known && di.lang != profile.Language || !known && !copied[i] {
+			continue
+		}
+		for _, inst := range s.Instructions {
+			if inst.Cmd != "RUN" {
+				continue
+			}
+			for _, step := range runSteps(inst) {
+				if buildStepRe.MatchString(step) && !writesAbsolute(step) && !slices.Contains(steps, step) {
+					steps = append(steps, step)
+					if line == 0 {
+						line = inst.Line
+					}
+				}
+			}
+		}
+	}
+	if len(steps) > 0 {
+		profile.BuildCommand = strings.Join(steps, " && ")
+		ev.found("buildCommand", profile.BuildCommand, file, line, "RUN build steps")
+	}
+}
+
+// copiedStages returns the indexes of the stages that stage i, or a stage
+// it is built from, copies files from with COPY --from.
+func copiedStages(df *parser.Dockerfile, i int) map[int]bool {
+	copied := map[int]bool{}
+	for _, inst := range stageChain(df, i) {
+		if inst.Cmd != "COPY" && inst.Cmd != "ADD" {
+			continue
+		}
+		for _, w := range strings.Fields(inst.Args) {
+			from, ok := strings.CutPrefix(w, "--from=")
+			if !ok {
+				continue
+			}
+			if n, err := strconv.Atoi(from); err == nil {
+				copied[n] = true
+				continue
+			}
+			for j := range df.Stages {
+				if s, ok := df.Stage(from); ok && s == &df.Stages[j] {
+					copied[j] = true
+				}
+			}
+		}
+	}
+	return copied
+}
+
+// outputFlags are the flags naming where a build command writes.
+var outputFlags = []string{"-o", "--out", "--output", "--outdir", "--out-dir", "--target-dir", "--dest"}
+
+// writesAbsolute reports whether a build step writes its output to an
+// absolute path.
+func writesAbsolute(step string) bool {
+	words := strings.Fields(step)
+	for i, w := range words {
+		flag, value, hasValue := strings.Cut(w, "=")
+		if !hasValue && i+1 < len(words) {
+			value = words[i+1]
+		}
+		if (slices.Contains(outputFlags, flag) || flag == ">" || flag == ">>") && strings.HasPrefix(value, "/") {
+			return true
+		}
+		if strings.HasPrefix(w, ">/") || strings.HasPrefix(w, ">>/") {
+			return true
+		}
+	}
+	return false
+}
+
+// runSteps splits a RUN instruction into its commands, dropping RUN
+// flags such as --mount.
+func runSteps(inst parser.DockerInstruction) []string {
+	script := inst.Args
+	if args, ok := inst.Exec(); ok {
+		script = strings.Join(args, " ")
+	}
+	for strings.HasPrefix(script, "--") {
+		_, script, _ = strings.Cut(script, " ")
+		script = strings.TrimSpace(script)
+	}
+	var steps []string
+	for _, part := range regexp.MustCompile(`&&|\|\||;|\n`).Split(script, -1) {
+		if part = strings.Join(strings.Fields(part), " "); part != "" {
+			steps = append(steps, part)
+		}
+	}
+	return steps
+}
+
+// startCommand combines ENTRYPOINT and CMD into the command the container
+// runs, without init wrappers and with paths under workdir made relative.
+func startCommand(entrypoint, cmd *parser.DockerInstruction, workdir string) (string, int) {
+	var words []string
+	line := 0
+	for _, inst := range []*parser.DockerInstruction{entrypoint, cmd} {
+		if inst == nil {
+			continue
+		}
+		line = inst.Line
+		args, ok := inst.Exec()
+		if !ok {
+			// Shell form; an ENTRYPOINT in shell form ignores CMD.
+			words = append(words, inst.Args)
+			if inst == entrypoint {
+				break
+			}
+			continue
+		}
+		words = append(words, args...)
+	}
+	for len(words) > 0 && slices.Contains(initWrappers, words[0]) {
+		words = words[1:]
+		if len(words) > 0 && words[0] == "--" {
+			words = words[1:]
+		}
+	}
+	if len(words) == 3 && (words[0] == "sh" || words[0] == "/bin/sh" || words[0] == "bash" || words[0] == "/bin/bash") && words[1] == "-c" {
+		words = words[2:]
+	}
+	if len(words) == 0 {
+		return "", 0
+	}
+	if dir := strings.TrimSuffix(workdir, "/") + "/"; dir != "/" && strings.HasPrefix(words[0], dir) {
+		words[0] = "./" + strings.TrimPrefix(words[0], dir)
+	}
+	for i, w := range words {
+		if strings.ContainsAny(w, " \t") && len(words) > 1 {
+			words[i] = strconv.Quote(w)
+		}
+	}
+	return strings.Join(words, " "), line
+}
+
+// addVars records the app variables set by an ENV or ARG instruction. ENV
+// values become defaults; build arguments are optional.
+func (d *DockerfileDetector) addVars(profile *flkr.AppProfile, ev recorder, file string, inst parser.DockerInstruction) {
+	for _, v := range inst.Vars() {
+		if dockerNoiseRe.MatchString(v.Name) || slices.Contains(profile.EnvVars, v.Name) {
+			continue
+		}
+		profile.EnvVars = append(profile.EnvVars, v.Name)
+		profile.Env = append(profile.Env, flkr.EnvVar{Name: v.Name, Default: v.Value})
+		rule := "set with " + inst.Cmd
+		if v.Set {
+			rule += ", defaulting to " + strconv.Quote(v.Value)
+		}
+		ev.found("envVars", v.Name, file, inst.Line, rule)
+	}
+}
+
+// addPackages maps the apt and apk packages a stage installs to nixpkgs
+// attributes. Packages installed in an earlier stage are only needed to
+// build the app.
+func (d *DockerfileDetector) addPackages(profile *flkr.AppProfile, ev recorder, file string, s parser.DockerStage, final bool) {
+	for _, inst := range s.Instructions {
+		if inst.Cmd != "RUN" {
+			continue
+		}
+		for _, step := range runSteps(inst) {
+			loc := pkgInstallRe.FindStringIndex(step)
+			if loc == nil {
+				continue
+			}
+			eco := "apt"
+			if strings.HasPrefix(step, "apk") {
+				eco = "apk"
+			}
+			for _, pkg := range strings.Fields(step[loc[1]:]) {
+				if strings.HasPrefix(pkg, "-") {
+					continue
+				}
+				pkg, _, _ = strings.Cut(pkg, "=")
+				rule, ok := d.deps[eco][pkg]
+				if !ok {
+					continue
+				}
+				build, runtime := rule.Build, rule.Runtime
+				if !final {
+					build, runtime = append(slices.Clone(build), runtime...), nil
+				}
+				for _, dep := range build {
+					if !slices.Contains(profile.BuildDeps, dep) {
+						profile.BuildDeps = append(profile.BuildDeps, dep)
+						ev.found("buildDeps", dep, file, inst.Line, eco+" package "+pkg+" installed to build")
+					}
+				}
+				for _, dep := range runtime {
+					if !slices.Contains(profile.SystemDeps, dep) {
+						profile.SystemDeps = append(profile.SystemDeps, dep)
+						ev.found("systemDeps", dep, file, inst.Line, eco+" package "+pkg+" installed in the image")
+					}
+				}
+			}
+		}
+	}
+}
+
+// breakTies records the base image of the Dockerfile on the candidates
+// whose language it runs, and puts them first among candidates of equal
+// confidence. The base image doesn't add to the confidence itself.
+func breakTies(profiles []*flkr.AppProfile, df *flkr.AppProfile) {
+	if df.Language == "" {
+		return
+	}
+	for _, p := range profiles {
+		if p.Language != df.Language {
+			continue
+		}
+		p.BaseImage = df.BaseImage
+		p.Evidence = append(p.Evidence, df.EvidenceFor("baseImage")...)
+	}
+	slices.SortStableFunc(profiles, func(a, b *flkr.AppProfile) int {
+		switch {
+		case a.Confidence > b.Confidence:
+			return -1
+		case a.Confidence < b.Confidence:
+			return 1
+		case a.Language == df.Language && b.Language != df.Language:
+			return -1
+		case b.Language == df.Language && a.Language != df.Language:
+			return 1
+		}
+		return 0
+	})
+}
+
+// fillGaps merges the Dockerfile profile into best where best has no
+// value read from the repository. A Dockerfile for another runtime is
+// ignored.
+func fillGaps(best, df *flkr.AppProfile) {
+	if df.Language != "" && df.Language != best.Language {
+		return
+	}
+	gap := func(field string) bool {
+		return !slices.ContainsFunc(best.Evidence, func(e flkr.Evidence) bool {
+			return e.Field == field && !e.Default
+		})
+	}
+	fill := &flkr.AppProfile{
+		SystemDeps: df.SystemDeps,
+		BuildDeps:  df.BuildDeps,
+		EnvVars:    df.EnvVars,
+	}
+	fields := []string{"systemDeps", "buildDeps", "envVars"}
+	for _, v := range df.Env {
+		if _, ok := best.LookupEnv(v.Name); !ok {
+			fill.Env = append(fill.Env, v)
+		}
+	}
+	if df.Version != "" && gap("version") {
+		fill.Version = df.Version
+		fields = append(fields, "version")
+	}
+	if df.ErlangVersion != "" && gap("erlangVersion") {
+		fill.ErlangVersion = df.ErlangVersion
+		fields = append(fields, "erlangVersion")
+	}
+	if df.BuildCommand != "" && gap("buildCommand") {
+		fill.BuildCommand = df.BuildCommand
+		fields = append(fields, "buildCommand")
+	}
+	if df.StartCommand != "" && gap("startCommand") {
+		fill.StartCommand = df.StartCommand
+		fields = append(fields, "startCommand")
+	}
+	if df.PortEnv != "" && gap("portEnv") {
+		fill.PortEnv = df.PortEnv
+		fields = append(fields, "portEnv")
+	}
+	if df.Port != 0 && gap("port") {
+		fill.Port = df.Port
+		fields = append(fields, "port")
+		if fill.PortEnv == "" {
+			// Merge replaces the port env var along with the port.
+			fill.PortEnv = best.PortEnv
+			fill.Evidence = best.EvidenceFor("portEnv")
+		}
+	}
+	for _, e := range df.Evidence {
+		if slices.Contains(fields, e.Field) {
+			fill.AddEvidence(e)
+		}
+	}
+	best.Merge(fill)
+}
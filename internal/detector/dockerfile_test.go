@@ -0,0 +1,258 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const nodeDockerfile = `ARG NODE_VERSION=20
+FROM node:${NODE_VERSION}-alpine AS build
+WORKDIR /app
+COPY package*.json ./
+RUN npm ci
+COPY . .
+RUN npm run build \
+    && npm prune --omit=dev
+
+FROM node:${NODE_VERSION}-alpine
+WORKDIR /app
+# Image processing.
+RUN apk add --no-cache \
+    vips \
+    ffmpeg
+ENV NODE_ENV=production \
+    API_URL="https://api.example.com"
+COPY --from=build /app/dist ./dist
+EXPOSE 8080/tcp
+ENTRYPOINT ["/sbin/tini", "--"]
+CMD ["node", "dist/server.js"]
+`
+
+func TestDockerfileDetector(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Dockerfile": &fstest.MapFile{Data: []byte(nodeDockerfile)},
+	}
+
+	d := &DockerfileDetector{deps: systemDepsFor()}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.Equal(t, "node:20-alpine", profile.BaseImage)
+	assert.Equal(t, "20", profile.Version)
+	assert.Equal(t, "npm run build", profile.BuildCommand)
+	assert.Equal(t, "node dist/server.js", profile.StartCommand)
+	assert.Equal(t, 8080, profile.Port)
+	assert.Equal(t, []string{"NODE_ENV", "API_URL"}, profile.EnvVars)
+	assert.Equal(t, []flkr.EnvVar{
+		{Name: "NODE_ENV", Default: "production"},
+		{Name: "API_URL", Default: "https://api.example.com"},
+	}, profile.Env)
+	assert.Equal(t, []string{"vips", "ffmpeg"}, profile.SystemDeps)
+
+	ev := profile.EvidenceFor("buildCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "Dockerfile", ev[0].File)
+	assert.Equal(t, 7, ev[0].Line)
+	assert.Equal(t, 13, profile.EvidenceFor("systemDeps")[0].Line)
+	assert.Equal(t, 21, profile.EvidenceFor("startCommand")[0].Line)
+}
+
+func TestDockerfileDetector_Images(t *testing.T) {
+	tests := []struct {
+		from       string
+		lang       flkr.Language
+		version    string
+		erlang     string
+		noLanguage bool
+	}{
+		{from: "python:3.12-slim", lang: flkr.LangPython, version: "3.12"},
+		{from: "docker.io/library/golang:1.23-alpine AS builder", lang: flkr.LangGo, version: "1.23"},
+		{from: "--platform=$BUILDPLATFORM rust:1.78 AS build", lang: flkr.LangRust, version: "1.78"},
+		{from: "maven:3.9-eclipse-temurin-21", lang: flkr.LangJava, version: "21"},
+		{from: "hexpm/elixir:1.16.2-erlang-26.2.1-alpine-3.19.1", lang: flkr.LangElixir, version: "1.16.2", erlang: "26.2.1"},
+		{from: "ghcr.io/astral-sh/uv:python3.12-bookworm-slim", lang: flkr.LangPython, version: "3.12"},
+		{from: "ruby:latest", lang: flkr.LangRuby},
+		{from: "node@sha256:abc123", lang: flkr.LangNode},
+		{from: "alpine:3.20", noLanguage: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"Dockerfile": &fstest.MapFile{Data: []byte("FROM " + tt.from + "\n")},
+			}
+			profile, matched, err := (&DockerfileDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			require.True(t, matched)
+			if tt.noLanguage {
+				assert.Empty(t, profile.Language)
+				return
+			}
+			assert.Equal(t, tt.lang, profile.Language)
+			assert.Equal(t, tt.version, profile.Version)
+			assert.Equal(t, tt.erlang, profile.ErlangVersion)
+		})
+	}
+}
+
+func TestDockerfileDetector_MultiStageGo(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Dockerfile": &fstest.MapFile{Data: []byte(`FROM golang:1.24 AS build
+RUN apt-get update && apt-get install -y --no-install-recommends libvips-dev=8.14.1-3 pkg-config \
+    && rm -rf /var/lib/apt/lists/*
+WORKDIR /src
+COPY . .
+RUN CGO_ENABLED=1 go build -o /out/api ./cmd/api
+
+FROM gcr.io/distroless/base-debian12
+WORKDIR /app
+COPY --from=build /out/api /app/api
+ENV PORT=9000 LOG_LEVEL=info
+ARG GIT_SHA
+ENTRYPOINT ["/app/api", "serve"]
+`)},
+	}
+
+	profile, matched, err := (&DockerfileDetector{deps: systemDepsFor()}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.Equal(t, "1.24", profile.Version)
+	assert.Empty(t, profile.BuildCommand, "the build writes to /out, outside the Nix build")
+	assert.Equal(t, "./api serve", profile.StartCommand)
+	assert.Equal(t, 9000, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	assert.Equal(t, []string{"PORT", "LOG_LEVEL", "GIT_SHA"}, profile.EnvVars)
+	assert.Equal(t, []string{"vips", "pkg-config"}, profile.BuildDeps, "packages of a build stage")
+	assert.Empty(t, profile.SystemDeps)
+}
+
+func TestDockerfileDetector_BuildSteps(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Dockerfile": &fstest.MapFile{Data: []byte(`FROM node:20 AS web
+WORKDIR /web
+RUN npm ci && npm run build
+
+FROM alpine:3.20 AS tools
+RUN apk add --no-cache go && go build -o bin/migrate ./cmd/migrate
+
+FROM alpine:3.20 AS unused
+RUN make docs
+
+FROM golang:1.24 AS build
+RUN go build -o bin/api ./cmd/api && go build -o /usr/local/bin/healthcheck ./cmd/healthcheck
+
+FROM gcr.io/distroless/base-debian12
+COPY --from=tools /bin/migrate /app/migrate
+COPY --from=3 /src/bin/api /app/api
+COPY --from=web /web/dist /app/public
+ENTRYPOINT ["/app/api"]
+`)},
+	}
+
+	profile, _, err := (&DockerfileDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.Equal(t, "go build -o bin/migrate ./cmd/migrate && go build -o bin/api ./cmd/api", profile.BuildCommand,
+		"the Node stage, a stage the final one doesn't copy from and writes to absolute paths are left out")
+	assert.Equal(t, 6, profile.EvidenceFor("buildCommand")[0].Line)
+}
+
+func TestDockerfile_MixedStages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"requirements.txt": &fstest.MapFile{Data: []byte("flask==3.0.0\n")},
+		"app.py":           &fstest.MapFile{Data: []byte("from flask import Flask\n")},
+		"Dockerfile": &fstest.MapFile{Data: []byte(`FROM golang:1.22 AS builder
+WORKDIR /src
+COPY sidecar .
+RUN go build -o bin/app ./cmd/api
+
+FROM python:3.12-slim
+WORKDIR /app
+COPY . .
+RUN pip install -r requirements.txt
+CMD ["gunicorn", "app:app"]
+`)},
+	}
+
+	profile, err := NewRegistry().DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangPython, profile.Language)
+	assert.Equal(t, flkr.FrameworkFlask, profile.Framework)
+	assert.NotContains(t, profile.BuildCommand, "go build")
+}
+
+func TestDockerfile_FillsGaps(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"build": "tsc"}, "dependencies": {"express": "^4"}}`)},
+		"Dockerfile":   &fstest.MapFile{Data: []byte(nodeDockerfile)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.Equal(t, "tsc", profile.BuildCommand, "package.json wins over the Dockerfile")
+	assert.Equal(t, "node dist/server.js", profile.StartCommand)
+	assert.Equal(t, 8080, profile.Port)
+	assert.Equal(t, "20", profile.Version)
+	assert.Equal(t, "nodejs_20", profile.Toolchain)
+	assert.Equal(t, "node:20-alpine", profile.BaseImage)
+	assert.Contains(t, profile.SystemDeps, "vips")
+
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "dockerfile", ev[0].Detector)
+	assert.Equal(t, 19, ev[0].Line)
+}
+
+func TestDockerfile_BreaksTies(t *testing.T) {
+	fsys := fstest.MapFS{
+		"requirements.txt": &fstest.MapFile{Data: []byte("requests\n")},
+		"package.json":     &fstest.MapFile{Data: []byte(`{"dependencies": {"left-pad": "^1"}}`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	before := map[flkr.Language]float64{}
+	for _, c := range profile.Candidates() {
+		before[c.Language] = c.Confidence
+	}
+	require.Contains(t, before, flkr.LangPython)
+	require.True(t, profile.Ambiguous())
+
+	fsys["Dockerfile"] = &fstest.MapFile{Data: []byte("FROM python:3.12-slim\nCMD [\"python\", \"main.py\"]\n")}
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, flkr.LangPython, profile.Language)
+	assert.Greater(t, profile.Confidence, before[flkr.LangPython])
+	assert.Equal(t, "python main.py", profile.StartCommand)
+	assert.False(t, profile.Ambiguous(), "the Dockerfile settles the choice")
+	assert.Empty(t, profile.Alternatives)
+}
+
+func TestDockerfile_OtherRuntimeIgnored(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":     &fstest.MapFile{Data: []byte("module example.com/app\n\ngo 1.24\n")},
+		"main.go":    &fstest.MapFile{Data: []byte("package main\n")},
+		"Dockerfile": &fstest.MapFile{Data: []byte("FROM python:3.12\nEXPOSE 5000\nENV SECRET_KEY=dev\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.NotEqual(t, 5000, profile.Port)
+	assert.NotContains(t, profile.EnvVars, "SECRET_KEY")
+	assert.Empty(t, profile.BaseImage)
+}
@@ -9,7 +9,11 @@ import (
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
-var mixVersionRe = regexp.MustCompile(`version:\s*"([^"]+)"`)
+var (
+	mixVersionRe = regexp.MustCompile(`version:\s*"([^"]+)"`)
+	mixElixirRe  = regexp.MustCompile(`elixir:\s*"([^"]+)"`)
+	otpSuffixRe  = regexp.MustCompile(`-otp-(\d+)$`)
+)
 
 // extractMixVersion extracts the version from a mix.exs project definition.
 func extractMixVersion(content string) string {
@@ -34,35 +38,66 @@ func (d *ElixirDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfi
 	profile := &flkr.AppProfile{
 		Language:       flkr.LangElixir,
 		PackageManager: flkr.PkgMix,
-		Confidence:     0.8,
 		DetectedBy:     d.Name(),
 		BuildCommand:   "mix do deps.get, compile",
 		StartCommand:   "mix phx.server",
 		Port:           4000,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "mix.exs", 0, "mix.exs present")
+	ev.found("packageManager", profile.PackageManager, "mix.exs", 0, "mix.exs present")
+	ev.assumed("buildCommand", profile.BuildCommand, "fetch and compile mix dependencies")
+	ev.assumed("startCommand", profile.StartCommand, "conventional Phoenix start command")
+	ev.assumed("port", profile.Port, "Phoenix listens on 4000 by default")
 
 	if fileExists(root, "mix.lock") {
 		profile.HasLockfile = true
 		profile.LockfileType = "mix"
+		ev.found("lockfileType", profile.LockfileType, "mix.lock", 0, "mix.lock present")
+	}
+
+	// The Elixir requirement in mix.exs gives way to version manager
+	// pins such as .elixir-version.
+	mixExs := readFileString(root, "mix.exs")
+	if m := mixElixirRe.FindStringSubmatch(mixExs); m != nil {
+		profile.Version = cleanVersion(m[1])
+		profile.VersionConstraint = m[1]
+		ev.found("version", profile.Version, "mix.exs", lineOf(mixExs, "elixir:"), "project elixir requirement "+m[1])
 	}
+	pinVersion(root, profile, ev)
 
-	// Read .elixir-version if it exists.
-	if ver := readFileString(root, ".elixir-version"); ver != "" {
-		profile.Version = strings.TrimSpace(ver)
+	// Erlang/OTP comes from a version manager, or from the OTP release an
+	// Elixir build was compiled for, as in 1.16.2-otp-26.
+	if pin, ok := findVersionPin(root, "erlang"); ok {
+		profile.ErlangVersion = pin.version
+		ev.found("erlangVersion", pin.version, pin.file, pin.line, pin.rule)
+	} else if m := otpSuffixRe.FindStringSubmatch(profile.Version); m != nil {
+		profile.ErlangVersion = m[1]
+		if e := profile.EvidenceFor("version"); len(e) > 0 {
+			ev.found("erlangVersion", m[1], e[0].File, e[0].Line, "OTP release of the Elixir build")
+		}
 	}
 
 	// Extract project version from mix.exs.
-	mixExs := readFileString(root, "mix.exs")
 	if v := extractMixVersion(mixExs); v != "" {
 		profile.AppVersion = v
+		ev.found("appVersion", v, "mix.exs", lineOf(mixExs, "version:"), "project version")
 	}
 
 	// Detect Phoenix from mix.exs deps.
 	if strings.Contains(mixExs, ":phoenix") {
 		profile.Framework = flkr.FrameworkPhoenix
-		profile.Confidence = 0.9
 		profile.SystemDeps = []string{"inotify-tools"}
+		line := lineOf(mixExs, ":phoenix")
+		ev.found("framework", profile.Framework, "mix.exs", line, "depends on phoenix")
+		ev.found("systemDeps", "inotify-tools", "mix.exs", line, "Phoenix live reload needs inotify-tools")
+	}
+
+	if m, ok := findPort(root, elixirPortScan); ok {
+		m.apply(profile, ev)
 	}
 
+	addEnvVars(profile, ev, findEnvVars(root, elixirEnvScan))
+
 	return profile, true, nil
 }
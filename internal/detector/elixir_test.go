@@ -32,3 +32,25 @@ end
 	assert.Equal(t, 4000, profile.Port)
 	assert.True(t, profile.HasLockfile)
 }
+
+func TestElixirDetector_RuntimePort(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mix.exs": &fstest.MapFile{Data: []byte(`defp deps do [{:phoenix, "~> 1.7"}] end`)},
+		"config/runtime.exs": &fstest.MapFile{Data: []byte(`import Config
+
+config :app, AppWeb.Endpoint,
+  http: [ip: {0, 0, 0, 0}, port: String.to_integer(System.get_env("PORT") || "4040")]
+`)},
+	}
+
+	profile, matched, err := (&ElixirDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, 4040, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "config/runtime.exs", ev[0].File)
+	assert.Equal(t, 4, ev[0].Line)
+	assert.False(t, ev[0].Default)
+}
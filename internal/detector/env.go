@@ -0,0 +1,218 @@
+package detector
+
+import (
+	"io/fs"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// envRule recognizes a read of an environment variable. The pattern
+// captures the variable "name" and, for reads with a fallback, either a
+// literal "default" or a "computed" expression; alternatives may repeat a
+// group name. A read without a fallback is required in every ecosystem,
+// whether it fails or yields nothing when the variable is unset; it is
+// optional only when the rule is marked optional, for reads that check
+// whether the variable is set.
+type envRule struct {
+	re       *regexp.Regexp
+	optional bool
+	rule     string
+}
+
+// envScan describes where and how one ecosystem reads env vars. Rules are
+// tried in order and a read matched by an earlier rule is not matched
+// again, so more specific forms (with a default) come first.
+type envScan struct {
+	files []string
+	rules []envRule
+}
+
+// envRead is an env var found in the repository, located at its first
+// read.
+type envRead struct {
+	flkr.EnvVar
+	file string
+	line int
+	rule string
+}
+
+// findEnvVars scans the files described by s for env var reads. A
+// variable read anywhere without a fallback is required.
+func findEnvVars(root fs.FS, s envScan) []envRead {
+	type hit struct {
+		offset int
+		read   envRead
+	}
+	var reads []envRead
+	index := map[string]int{}
+	for _, name := range scanFiles(root, s.files) {
+		data, err := readLimit(root, name, sourceMaxSize)
+		if err != nil {
+			continue
+		}
+		content := string(data)
+
+		var hits []hit
+		seen := map[int]bool{}
+		for _, r := range s.rules {
+			for _, loc := range r.re.FindAllStringSubmatchIndex(content, -1) {
+				at, _, _ := group(r.re, loc, "name")
+				if seen[at] {
+					continue
+				}
+				seen[at] = true
+				v := flkr.EnvVar{Name: submatch(r.re, content, loc, "name"), Required: !r.optional}
+				rule := "read with " + r.rule
+				_, _, defaulted := group(r.re, loc, "default")
+				_, _, computed := group(r.re, loc, "computed")
+				switch {
+				case defaulted:
+					v.Required = false
+					v.Default = submatch(r.re, content, loc, "default")
+					rule += ", defaulting to " + strconv.Quote(v.Default)
+				case computed || r.optional:
+					v.Required = false
+					rule += ", optional"
+				}
+				hits = append(hits, hit{at, envRead{
+					EnvVar: v,
+					file:   name,
+					line:   strings.Count(content[:at], "\n") + 1,
+					rule:   rule,
+				}})
+			}
+		}
+		sort.SliceStable(hits, func(i, j int) bool { return hits[i].offset < hits[j].offset })
+
+		for _, h := range hits {
+			i, ok := index[h.read.Name]
+			if !ok {
+				index[h.read.Name] = len(reads)
+				reads = append(reads, h.read)
+				continue
+			}
+			existing := &reads[i]
+			switch {
+			case h.read.Required:
+				existing.Required = true
+				existing.Default = ""
+			case !existing.Required && existing.Default == "":
+				existing.Default = h.read.Default
+			}
+		}
+	}
+	return reads
+}
+
+// addEnvVars records env var reads on profile.
+func addEnvVars(profile *flkr.AppProfile, ev recorder, reads []envRead) {
+	for _, r := range reads {
+		if !slices.Contains(profile.EnvVars, r.Name) {
+			profile.EnvVars = append(profile.EnvVars, r.Name)
+		}
+		profile.Env = append(profile.Env, r.EnvVar)
+		ev.found("envVars", r.Name, r.file, r.line, r.rule)
+	}
+}
+
+// envRe compiles an env var pattern, substituting NAME with the "name"
+// group and DEFAULT with a fallback: a quoted string, number or boolean
+// captured as "default", or any other expression captured as "computed".
+func envRe(pattern string) *regexp.Regexp {
+	pattern = strings.ReplaceAll(pattern, "NAME", `(?P<name>[A-Z_][A-Z0-9_]*)`)
+	pattern = strings.ReplaceAll(pattern, "DEFAULT", `(?:"(?P<default>[^"]*)"|'(?P<default>[^']*)'|`+
+		"`(?P<default>[^`]*)`"+`|(?P<default>-?\d[\d._]*|true|false)\b|(?P<computed>[A-Za-z_$:@][\w.$:]*))`)
+	return regexp.MustCompile(pattern)
+}
+
+// Env var scans for each ecosystem.
+var (
+	goEnvScan = envScan{
+		files: []string{"**/*.go"},
+		rules: []envRule{
+			{re: envRe(`cmp\.Or\(\s*os\.Getenv\("NAME"\)\s*,\s*DEFAULT\s*\)`), rule: "os.Getenv"},
+			{re: envRe(`\b(?:(?:[Gg]et|[Ll]ookup|[Rr]ead|[Ff]etch|[Mm]ust(?:Get)?)?[Ee]nv)(?:As)?(?:Or|Default|Var|String|Str|Int64|Int|Bool|Duration|Float)*\(\s*"NAME"\s*,\s*DEFAULT\s*\)`), rule: "an env helper"},
+			{re: envRe(`os\.LookupEnv\("NAME"\)`), optional: true, rule: "os.LookupEnv"},
+			{re: envRe(`os\.Getenv\("NAME"\)`), rule: "os.Getenv"},
+		},
+	}
+
+	nodeEnvScan = envScan{
+		files: []string{"**/*.js", "**/*.mjs", "**/*.cjs", "**/*.ts"},
+		rules: []envRule{
+			{re: envRe(`process\.env(?:\.NAME|\[\s*["']NAME["']\s*\])\s*(?:\|\||\?\?)\s*DEFAULT`), rule: "process.env"},
+			{re: envRe(`process\.env(?:\.NAME|\[\s*["']NAME["']\s*\])`), rule: "process.env"},
+		},
+	}
+
+	pythonEnvScan = envScan{
+		files: []string{"**/*.py"},
+		rules: []envRule{
+			{re: envRe(`os\.(?:environ\.get|getenv)\(\s*["']NAME["']\s*,\s*DEFAULT\s*\)`), rule: "os.environ.get"},
+			{re: envRe(`os\.(?:environ\.get|getenv)\(\s*["']NAME["']\s*\)`), rule: "os.environ.get"},
+			{re: envRe(`os\.environ\[\s*["']NAME["']\s*\]`), rule: "os.environ"},
+			{re: envRe(`\benv(?:\.\w+)?\(\s*["']NAME["']\s*,\s*default\s*=\s*DEFAULT\s*\)`), rule: "django-environ"},
+			{re: envRe(`\benv(?:\.\w+)?\(\s*["']NAME["']\s*\)`), rule: "django-environ"},
+		},
+	}
+
+	rubyEnvScan = envScan{
+		files: []string{"**/*.rb", "config/**/*.yml"},
+		rules: []envRule{
+			{re: envRe(`ENV\.fetch\(\s*["']NAME["']\s*,\s*DEFAULT\s*\)`), rule: "ENV.fetch"},
+			{re: envRe(`ENV\.fetch\(\s*["']NAME["']\s*\)\s*\{\s*DEFAULT\s*\}`), rule: "ENV.fetch"},
+			{re: envRe(`ENV\.fetch\(\s*["']NAME["']\s*\)`), rule: "ENV.fetch"},
+			{re: envRe(`ENV\[\s*["']NAME["']\s*\]\s*\|\|\s*DEFAULT`), rule: "ENV[]"},
+			{re: envRe(`ENV\[\s*["']NAME["']\s*\]`), rule: "ENV[]"},
+		},
+	}
+
+	elixirEnvScan = envScan{
+		files: []string{"config/**/*.exs", "lib/**/*.ex"},
+		rules: []envRule{
+			{re: envRe(`System\.get_env\(\s*"NAME"\s*,\s*DEFAULT\s*\)`), rule: "System.get_env"},
+			{re: envRe(`System\.get_env\(\s*"NAME"\s*\)\s*\|\|\s*(?:raise|exit)\b`), rule: "System.get_env"},
+			{re: envRe(`System\.get_env\(\s*"NAME"\s*\)\s*\|\|\s*DEFAULT`), rule: "System.get_env"},
+			{re: envRe(`System\.fetch_env!\(\s*"NAME"\s*\)`), rule: "System.fetch_env!"},
+			{re: envRe(`System\.get_env\(\s*"NAME"\s*\)`), rule: "System.get_env"},
+		},
+	}
+
+	phpEnvScan = envScan{
+		files: []string{"**/*.php"},
+		rules: []envRule{
+			{re: envRe(`\benv\(\s*["']NAME["']\s*,\s*DEFAULT\s*\)`), rule: "env()"},
+			{re: envRe(`\benv\(\s*["']NAME["']\s*\)`), rule: "env()"},
+			{re: envRe(`\bgetenv\(\s*["']NAME["']\s*\)`), rule: "getenv()"},
+			{re: envRe(`\$_ENV\[\s*["']NAME["']\s*\]`), rule: "$_ENV"},
+		},
+	}
+
+	javaEnvScan = envScan{
+		files: []string{
+			"src/main/resources/**/*.properties",
+			"src/main/resources/**/*.yml",
+			"src/main/resources/**/*.yaml",
+			"src/main/**/*.java",
+			"src/main/**/*.kt",
+		},
+		rules: []envRule{
+			{re: regexp.MustCompile(`\$\{(?P<name>[A-Z_][A-Z0-9_]*)(?::(?P<default>[^}]*))?\}`), rule: "a ${} placeholder"},
+			{re: envRe(`System\.getenv\(\s*"NAME"\s*\)`), rule: "System.getenv"},
+		},
+	}
+
+	rustEnvScan = envScan{
+		files: []string{"**/*.rs"},
+		rules: []envRule{
+			{re: envRe(`env::var\("NAME"\)\s*\.unwrap_or(?:_else)?\(\s*(?:\|_?\|\s*)?DEFAULT`), rule: "env::var"},
+			{re: envRe(`env::var\("NAME"\)\s*\.(?:ok|is_ok|unwrap_or_default)\(`), optional: true, rule: "env::var"},
+			{re: envRe(`env::var(?:_os)?\("NAME"\)`), rule: "env::var"},
+		},
+	}
+)
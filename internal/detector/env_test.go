@@ -0,0 +1,186 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindEnvVars(t *testing.T) {
+	tests := []struct {
+		name string
+		scan envScan
+		file string
+		src  string
+		want []flkr.EnvVar
+	}{
+		{
+			name: "go",
+			scan: goEnvScan,
+			file: "cmd/api/main.go",
+			src: `dsn := os.Getenv("DATABASE_URL")
+addr := cmp.Or(os.Getenv("ADDR"), ":8080")
+level := getEnv("LOG_LEVEL", "info")
+workers := envAsInt("WORKERS", 4)
+setEnv("MODE", "dev")
+if v, ok := os.LookupEnv("DEBUG"); ok {}`,
+			want: []flkr.EnvVar{
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "ADDR", Default: ":8080"},
+				{Name: "LOG_LEVEL", Default: "info"},
+				{Name: "WORKERS", Default: "4"},
+				{Name: "DEBUG"},
+			},
+		},
+		{
+			name: "node",
+			file: "src/index.ts",
+			scan: nodeEnvScan,
+			src: `const url = process.env.DATABASE_URL;
+const port = process.env.PORT || 3000;
+const mode = process.env["NODE_ENV"] ?? 'development';
+const key = process.env.API_KEY || config.apiKey;`,
+			want: []flkr.EnvVar{
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "PORT", Default: "3000"},
+				{Name: "NODE_ENV", Default: "development"},
+				{Name: "API_KEY"},
+			},
+		},
+		{
+			name: "python",
+			file: "app/settings.py",
+			scan: pythonEnvScan,
+			src: `SECRET_KEY = os.environ["SECRET_KEY"]
+DEBUG = os.getenv("DEBUG", "false")
+SENTRY_DSN = os.environ.get("SENTRY_DSN")`,
+			want: []flkr.EnvVar{
+				{Name: "SECRET_KEY", Required: true},
+				{Name: "DEBUG", Default: "false"},
+				{Name: "SENTRY_DSN", Required: true},
+			},
+		},
+		{
+			name: "ruby",
+			file: "config/database.yml",
+			scan: rubyEnvScan,
+			src: `pool: <%= ENV.fetch("RAILS_MAX_THREADS") { 5 } %>
+url: <%= ENV.fetch("DATABASE_URL") %>
+host: <%= ENV["DB_HOST"] %>`,
+			want: []flkr.EnvVar{
+				{Name: "RAILS_MAX_THREADS", Default: "5"},
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "DB_HOST", Required: true},
+			},
+		},
+		{
+			name: "elixir",
+			file: "config/runtime.exs",
+			scan: elixirEnvScan,
+			src: `database_url = System.get_env("DATABASE_URL") || raise "DATABASE_URL is missing"
+secret = System.fetch_env!("SECRET_KEY_BASE")
+host = System.get_env("PHX_HOST") || "example.com"
+pool = System.get_env("POOL_SIZE", "10")
+dsn = System.get_env("SENTRY_DSN")`,
+			want: []flkr.EnvVar{
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "SECRET_KEY_BASE", Required: true},
+				{Name: "PHX_HOST", Default: "example.com"},
+				{Name: "POOL_SIZE", Default: "10"},
+				{Name: "SENTRY_DSN", Required: true},
+			},
+		},
+		{
+			name: "laravel",
+			file: "config/app.php",
+			scan: phpEnvScan,
+			src: `'name' => env('APP_NAME', 'Laravel'),
+'key' => env('APP_KEY'),
+'debug' => (bool) env('APP_DEBUG', false),`,
+			want: []flkr.EnvVar{
+				{Name: "APP_NAME", Default: "Laravel"},
+				{Name: "APP_KEY", Required: true},
+				{Name: "APP_DEBUG", Default: "false"},
+			},
+		},
+		{
+			name: "spring",
+			file: "src/main/resources/application.yml",
+			scan: javaEnvScan,
+			src: `spring:
+  datasource:
+    url: ${DATABASE_URL}
+    password: ${DB_PASSWORD:}
+server:
+  port: ${PORT:8080}
+  name: ${spring.application.name}`,
+			want: []flkr.EnvVar{
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "DB_PASSWORD"},
+				{Name: "PORT", Default: "8080"},
+			},
+		},
+		{
+			name: "rust",
+			file: "src/main.rs",
+			scan: rustEnvScan,
+			src: `let url = env::var("DATABASE_URL").expect("DATABASE_URL");
+let host = env::var("HOST").unwrap_or_else(|_| "0.0.0.0".into());
+let token = std::env::var("TOKEN").ok();`,
+			want: []flkr.EnvVar{
+				{Name: "DATABASE_URL", Required: true},
+				{Name: "HOST", Default: "0.0.0.0"},
+				{Name: "TOKEN"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{tt.file: &fstest.MapFile{Data: []byte(tt.src)}}
+			var got []flkr.EnvVar
+			for _, r := range findEnvVars(fsys, tt.scan) {
+				got = append(got, r.EnvVar)
+				assert.Equal(t, tt.file, r.file)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestFindEnvVars_RequiredWins(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.go":      &fstest.MapFile{Data: []byte(`x := cmp.Or(os.Getenv("REGION"), "eu")`)},
+		"b.go":      &fstest.MapFile{Data: []byte("\n" + `y := os.Getenv("REGION")`)},
+		"b_test.go": &fstest.MapFile{Data: []byte(`os.Getenv("TEST_ONLY")`)},
+	}
+
+	reads := findEnvVars(fsys, goEnvScan)
+	require.Len(t, reads, 1)
+	assert.Equal(t, flkr.EnvVar{Name: "REGION", Required: true}, reads[0].EnvVar)
+	assert.Equal(t, "a.go", reads[0].file, "evidence points at the first read")
+	assert.Equal(t, 1, reads[0].line)
+}
+
+func TestGoDetector_EnvVars(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/app\n\ngo 1.22\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n\nfunc main() {\n\tdsn := os.Getenv(\"DATABASE_URL\")\n}\n")},
+	}
+
+	profile, matched, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, []string{"DATABASE_URL"}, profile.EnvVars)
+	v, ok := profile.LookupEnv("DATABASE_URL")
+	require.True(t, ok)
+	assert.True(t, v.Required)
+	ev := profile.EvidenceFor("envVars")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "main.go", ev[0].File)
+	assert.Equal(t, 4, ev[0].Line)
+	assert.Equal(t, "read with os.Getenv", ev[0].Rule)
+}
@@ -0,0 +1,294 @@
+package detector
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// goReleaserDefaultLdflags are the ldflags GoReleaser builds with when a
+// build sets none, but for main.builtBy.
+const goReleaserDefaultLdflags = "-s -w -X main.version={{.Version}} -X main.commit={{.Commit}} -X main.date={{.Date}}"
+
+// goBuildFlags are the flags of a Go build read from a config file.
+type goBuildFlags struct {
+	file    string
+	line    int
+	rule    string
+	ldflags string
+	tags    []string
+	cgo     string // CGO_ENABLED, if set
+	binary  string // output name, if set
+	pkg     string // package built, if known
+}
+
+// addGoBuildFlags sets the ldflags, link variables and build tags of a Go
+// app from its GoReleaser config, or else from the go build line of its
+// Makefile. A GoReleaser build also names the primary binary and may turn
+// cgo off.
+func addGoBuildFlags(root fs.FS, profile *flkr.AppProfile, ev recorder) {
+	flags, ok := goReleaserFlags(root, profile)
+	if !ok {
+		if flags, ok = makefileGoFlags(root); !ok {
+			return
+		}
+	}
+
+	var vars []string
+	profile.Ldflags, vars = splitLdflags(flags.ldflags)
+	for _, v := range vars {
+		name, value, _ := strings.Cut(v, "=")
+		if lv, ok := linkVar(name, value); ok {
+			profile.LinkVars = append(profile.LinkVars, lv)
+		}
+	}
+	if len(profile.Ldflags) > 0 || len(profile.LinkVars) > 0 {
+		ev.found("ldflags", strings.Join(profile.Ldflags, " "), flags.file, flags.line, flags.rule)
+		for _, v := range profile.LinkVars {
+			ev.found("linkVars", v.Name, flags.file, flags.line, flags.rule)
+		}
+	}
+	profile.Tags = flags.tags
+	if len(profile.Tags) > 0 {
+		ev.found("tags", strings.Join(profile.Tags, ","), flags.file, flags.line, flags.rule)
+	}
+	if flags.cgo != "" {
+		profile.CGOEnabled = flags.cgo
+		ev.found("cgoEnabled", flags.cgo, flags.file, flags.line, "CGO_ENABLED in "+flags.rule)
+	}
+
+	// A GoReleaser build names the binary of its package.
+	if flags.binary == "" || strings.Contains(flags.binary, "{{") {
+		return
+	}
+	for i, b := range profile.Binaries {
+		if b.Package != flags.pkg || b.Name == path.Base(flags.binary) {
+			continue
+		}
+		profile.Binaries[i].Name = path.Base(flags.binary)
+		profile.BuildCommand, profile.StartCommand = goCommands(profile.Binaries)
+		ev.reset("buildCommand")
+		ev.reset("startCommand")
+		ev.found("buildCommand", profile.BuildCommand, flags.file, flags.line, "binary of "+flags.rule)
+		ev.found("startCommand", profile.StartCommand, flags.file, flags.line, "binary of "+flags.rule)
+	}
+}
+
+// goReleaserFlags reads the Go build of a GoReleaser config that builds
+// the primary binary of profile, or its first Go build.
+func goReleaserFlags(root fs.FS, profile *flkr.AppProfile) (goBuildFlags, bool) {
+	for _, name := range []string{".goreleaser.yaml", ".goreleaser.yml", "goreleaser.yaml", "goreleaser.yml"} {
+		if !fileExists(root, name) {
+			continue
+		}
+		cfg, err := parser.ParseGoReleaser(root, name)
+		if err != nil {
+			profile.Warnings = append(profile.Warnings, fmt.Sprintf("%s: %v", name, err))
+			return goBuildFlags{}, false
+		}
+		primary := "."
+		if len(profile.Binaries) > 0 {
+			primary = profile.Binaries[0].Package
+		}
+
+		pick := -1
+		for i, b := range cfg.Builds {
+			if b.Builder != "" && b.Builder != "go" {
+				continue
+			}
+			if pick == -1 {
+				pick = i
+			}
+			if goReleaserPackage(b.Main) == primary {
+				pick = i
+				break
+			}
+		}
+		if pick == -1 {
+			return goBuildFlags{}, false
+		}
+
+		b := cfg.Builds[pick]
+		flags := goBuildFlags{
+			file: name,
+			line: b.Lines["ldflags"],
+			rule: fmt.Sprintf("builds[%d] of %s", pick, name),
+			ldflags: goTemplateRe.ReplaceAllStringFunc(strings.Join(b.Ldflags, " "), func(t string) string {
+				// Keep each template one word.
+				return strings.Join(strings.Fields(t), "")
+			}),
+			tags:   b.Tags,
+			binary: b.Binary,
+			pkg:    goReleaserPackage(b.Main),
+		}
+		if b.Ldflags == nil {
+			flags.ldflags = goReleaserDefaultLdflags
+			flags.rule += ", default ldflags"
+		}
+		if flags.line == 0 {
+			flags.line = b.Lines["main"]
+		}
+		for _, f := range goBuildArgs(b.Flags) {
+			if f.name == "tags" {
+				flags.tags = append(flags.tags, splitTags(f.value)...)
+			}
+		}
+		flags.tags = splitTags(strings.Join(flags.tags, ","))
+		for _, e := range b.Env {
+			if v, ok := strings.CutPrefix(e, "CGO_ENABLED="); ok && (v == "0" || v == "1") {
+				flags.cgo = v
+			}
+		}
+		return flags, true
+	}
+	return goBuildFlags{}, false
+}
+
+var goTemplateRe = regexp.MustCompile(`\{\{.*?\}\}`)
+
+// goReleaserPackage returns the package path of a build's main, which may
+// name a file.
+func goReleaserPackage(main string) string {
+	if main == "" {
+		return "."
+	}
+	if strings.HasSuffix(main, ".go") {
+		main = path.Dir(main)
+	}
+	if main = path.Clean(main); main == "." {
+		return main
+	}
+	return "./" + strings.TrimPrefix(main, "./")
+}
+
+// goBuildRe matches a go build or go install command in a recipe.
+var goBuildRe = regexp.MustCompile(`(?:^|[\s;&|(])go\s+(?:build|install)\s`)
+
+// makefileGoFlags reads the flags of the go build command in the Makefile,
+// preferring one run by the build target.
+func makefileGoFlags(root fs.FS) (goBuildFlags, bool) {
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		mf, err := parser.ParseMakefile(root, name)
+		if err != nil {
+			continue
+		}
+		targets := mf.Targets
+		if t, ok := mf.Target("build"); ok {
+			targets = append([]parser.MakeTarget{*t}, targets...)
+		}
+		for _, t := range targets {
+			for _, line := range t.Recipe {
+				cmd, _ := expandMake(strings.TrimLeft(line, "@-+"), mf.Vars, 0)
+				loc := goBuildRe.FindStringIndex(cmd)
+				if loc == nil {
+					continue
+				}
+				flags := goBuildFlags{file: name, line: t.Line, rule: "go build in " + name + " target " + t.Name}
+				for _, w := range parser.ShellWords(cmd[:loc[0]]) {
+					if v, ok := strings.CutPrefix(w, "CGO_ENABLED="); ok && (v == "0" || v == "1") {
+						flags.cgo = v
+					}
+				}
+				for _, f := range goBuildArgs(parser.ShellWords(cmd[loc[1]:])) {
+					switch f.name {
+					case "ldflags":
+						flags.ldflags = f.value
+					case "tags":
+						flags.tags = splitTags(f.value)
+					}
+				}
+				return flags, true
+			}
+		}
+	}
+	return goBuildFlags{}, false
+}
+
+// goFlag is a flag of a go command with its value.
+type goFlag struct {
+	name  string
+	value string
+}
+
+// goBuildArgs returns the -ldflags and -tags flags among the words of a
+// go build command, in either the "-flag value" or "-flag=value" form.
+func goBuildArgs(words []string) []goFlag {
+	var flags []goFlag
+	for i := 0; i < len(words); i++ {
+		name, value, hasValue := strings.Cut(strings.TrimLeft(words[i], "-"), "=")
+		if !strings.HasPrefix(words[i], "-") || name != "ldflags" && name != "tags" {
+			continue
+		}
+		if !hasValue && i+1 < len(words) {
+			i++
+			value = words[i]
+		}
+		flags = append(flags, goFlag{name: name, value: value})
+	}
+	return flags
+}
+
+// splitTags splits build tags written comma- or space-separated.
+func splitTags(s string) []string {
+	var tags []string
+	for _, t := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !strings.Contains(t, "{{") && !strings.Contains(t, "$") {
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// splitLdflags splits an -ldflags value into the linker flags and the
+// name=value arguments of its -X flags.
+func splitLdflags(s string) (flags, vars []string) {
+	words := parser.ShellWords(s)
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		switch {
+		case w == "-X" && i+1 < len(words):
+			i++
+			vars = append(vars, words[i])
+		case strings.HasPrefix(w, "-X="):
+			vars = append(vars, strings.TrimPrefix(w, "-X="))
+		case w == "-extldflags" && i+1 < len(words):
+			i++
+			flags = append(flags, w, words[i])
+		default:
+			flags = append(flags, w)
+		}
+	}
+	return flags, vars
+}
+
+var (
+	// versionVarRe, commitVarRe and dateVarRe match the names of link
+	// variables, and the templates or commands filling them, that carry
+	// the version, revision or build date.
+	versionVarRe = regexp.MustCompile(`(?i)^(?:app|build|release)?_?version$|\{\{-?\s*\.(?:Version|RawVersion|Tag)\s*-?\}\}|git describe`)
+	commitVarRe  = regexp.MustCompile(`(?i)^(?:git)?_?(?:commit|revision|sha|commit_?hash)$|\{\{-?\s*\.(?:Commit|FullCommit|ShortCommit)\s*-?\}\}|git rev-parse`)
+	dateVarRe    = regexp.MustCompile(`(?i)^(?:build|commit)?_?(?:date|time|timestamp)$|\{\{-?\s*\.(?:Date|CommitDate|Timestamp|CommitTimestamp)\s*-?\}\}|\bdate\b`)
+)
+
+// linkVar classifies the -X variable name=value by what it carries. A
+// variable filled by a template or command of another kind is dropped.
+func linkVar(name, value string) (flkr.LinkVar, bool) {
+	short := name[strings.LastIndex(name, ".")+1:]
+	dynamic := strings.Contains(value, "{{") || strings.Contains(value, "$(") || strings.Contains(value, "`")
+	switch {
+	case versionVarRe.MatchString(short) || dynamic && versionVarRe.MatchString(value):
+		return flkr.LinkVar{Name: name, From: "version"}, true
+	case commitVarRe.MatchString(short) || dynamic && commitVarRe.MatchString(value):
+		return flkr.LinkVar{Name: name, From: "commit"}, true
+	case dateVarRe.MatchString(short) || dynamic && dateVarRe.MatchString(value):
+		return flkr.LinkVar{Name: name, From: "date"}, true
+	case dynamic || strings.Contains(value, "$"):
+		return flkr.LinkVar{}, false
+	}
+	return flkr.LinkVar{Name: name, Value: value}, true
+}
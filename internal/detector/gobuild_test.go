@@ -0,0 +1,136 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGoBuildFlags_GoReleaser(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":              &fstest.MapFile{Data: []byte("module example.com/acme\n\ngo 1.24\n")},
+		"cmd/api/main.go":     &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/migrate/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		".goreleaser.yaml": &fstest.MapFile{Data: []byte(`version: 2
+
+builds:
+  - id: migrate
+    main: ./cmd/migrate
+  - id: api
+    main: ./cmd/api/main.go
+    binary: acme-api
+    env:
+      - CGO_ENABLED=0
+    flags:
+      - -trimpath
+      - -tags=timetzdata
+    tags: [netgo, osusergo]
+    ldflags:
+      - -s -w
+      - -X example.com/acme/internal/build.Version={{.Version}}
+      - -X example.com/acme/internal/build.Commit={{ .ShortCommit }}
+      - -X main.env=production
+      - -X main.builder={{ .Env.USER }}
+`)},
+	}
+
+	profile, matched, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, []string{"-s", "-w"}, profile.Ldflags)
+	assert.Equal(t, []flkr.LinkVar{
+		{Name: "example.com/acme/internal/build.Version", From: "version"},
+		{Name: "example.com/acme/internal/build.Commit", From: "commit"},
+		{Name: "main.env", Value: "production"},
+	}, profile.LinkVars)
+	assert.Equal(t, []string{"netgo", "osusergo", "timetzdata"}, profile.Tags)
+	assert.Equal(t, "0", profile.CGOEnabled)
+	assert.Equal(t, flkr.Binary{Name: "acme-api", Package: "./cmd/api"}, profile.Binaries[0])
+	assert.Equal(t, "go build -o bin/ ./cmd/api ./cmd/migrate", profile.BuildCommand)
+	assert.Equal(t, "./bin/acme-api", profile.StartCommand)
+
+	ev := profile.EvidenceFor("ldflags")
+	require.Len(t, ev, 1)
+	assert.Equal(t, ".goreleaser.yaml", ev[0].File)
+	assert.Equal(t, 15, ev[0].Line)
+	assert.Equal(t, "builds[1] of .goreleaser.yaml", ev[0].Rule)
+}
+
+func TestGoBuildFlags_GoReleaserDefaults(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":           &fstest.MapFile{Data: []byte("module example.com/tool\n")},
+		"main.go":          &fstest.MapFile{Data: []byte("package main\n")},
+		".goreleaser.yml":  &fstest.MapFile{Data: []byte("builds:\n  - builder: rust\n  - goos: [linux]\n")},
+		"Makefile":         &fstest.MapFile{Data: []byte("build:\n\tgo build -tags sqlite_omit_load_extension .\n")},
+		"internal/x/x.go":  &fstest.MapFile{Data: []byte("package x\n")},
+		"internal/x/y.txt": &fstest.MapFile{Data: []byte("\n")},
+	}
+
+	profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-s", "-w"}, profile.Ldflags)
+	assert.Equal(t, []flkr.LinkVar{
+		{Name: "main.version", From: "version"},
+		{Name: "main.commit", From: "commit"},
+		{Name: "main.date", From: "date"},
+	}, profile.LinkVars)
+	assert.Empty(t, profile.Tags, "GoReleaser wins over the Makefile")
+	assert.Equal(t, "builds[1] of .goreleaser.yml, default ldflags", profile.EvidenceFor("ldflags")[0].Rule)
+}
+
+func TestGoBuildFlags_Makefile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module github.com/narvanalabs/flkr\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"Makefile": &fstest.MapFile{Data: []byte(`MODULE := github.com/narvanalabs/flkr
+VERSION ?= dev
+SHA = $(shell git rev-parse --short HEAD)
+LDFLAGS := -ldflags "-s -X $(MODULE)/cmd.Version=$(VERSION) -X '$(MODULE)/cmd.Commit=$(SHA)'"
+
+lint:
+	go vet ./...
+
+build:
+	CGO_ENABLED=0 go build $(LDFLAGS) -tags=netgo,osusergo -o flkr .
+`)},
+	}
+
+	profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"-s"}, profile.Ldflags)
+	assert.Equal(t, []flkr.LinkVar{
+		{Name: "github.com/narvanalabs/flkr/cmd.Version", From: "version"},
+		{Name: "github.com/narvanalabs/flkr/cmd.Commit", From: "commit"},
+	}, profile.LinkVars)
+	assert.Equal(t, []string{"netgo", "osusergo"}, profile.Tags)
+	assert.Equal(t, "0", profile.CGOEnabled)
+
+	ev := profile.EvidenceFor("tags")
+	require.Len(t, ev, 1)
+	assert.Equal(t, 9, ev[0].Line)
+	assert.Equal(t, "go build in Makefile target build", ev[0].Rule)
+}
+
+func TestGoBuildFlags_CgoTurnedOff(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/agent\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"sys/mem_cgo.go": &fstest.MapFile{Data: []byte(`//go:build cgo
+
+package sys
+
+import "C"
+`)},
+		"Makefile": &fstest.MapFile{Data: []byte("build:\n\tCGO_ENABLED=0 go build -o agent .\n")},
+	}
+
+	profile, err := NewRegistry().DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "0", profile.CGOEnabled, "optional cgo stays off")
+	assert.Equal(t, "go", profile.EvidenceFor("cgoEnabled")[0].Detector)
+}
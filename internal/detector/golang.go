@@ -2,9 +2,14 @@ package detector
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
+	"path"
+	"regexp"
+	"slices"
 	"strings"
 
+	"github.com/narvanalabs/flkr/internal/parser"
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
@@ -22,33 +27,52 @@ func (d *GoDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile,
 	profile := &flkr.AppProfile{
 		Language:       flkr.LangGo,
 		PackageManager: flkr.PkgGoMod,
-		Confidence:     0.8,
 		DetectedBy:     d.Name(),
 		BuildCommand:   "go build -o app .",
 		StartCommand:   "./app",
 		Port:           8080,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "go.mod", 0, "go.mod present")
+	ev.found("packageManager", profile.PackageManager, "go.mod", 0, "go.mod present")
+	ev.assumed("port", profile.Port, "Go services listen on 8080 by default")
 
 	if fileExists(root, "go.sum") {
 		profile.HasLockfile = true
 		profile.LockfileType = "gomod"
+		ev.found("lockfileType", profile.LockfileType, "go.sum", 0, "go.sum present")
 	}
 
 	if fileExists(root, "vendor") {
 		profile.HasVendor = true
 	}
 
-	// Parse go.mod for module name, Go version, and framework detection.
-	content := readFileString(root, "go.mod")
-	moduleName := ""
-	for _, line := range strings.Split(content, "\n") {
-		line = strings.TrimSpace(line)
-		if strings.HasPrefix(line, "module ") {
-			moduleName = strings.TrimPrefix(line, "module ")
+	// Parse go.mod for module name, Go version, local modules and
+	// framework detection.
+	mod, err := parser.ParseGoMod(root, "go.mod")
+	if err != nil {
+		return nil, false, err
+	}
+	moduleName := mod.Module
+	switch {
+	case mod.Toolchain != "":
+		// The toolchain directive names the Go release to build with and
+		// takes precedence over the minimum in the go directive.
+		profile.Version = mod.Toolchain
+		ev.found("version", profile.Version, "go.mod", mod.Lines["toolchain"], "toolchain directive")
+	case mod.Go != "":
+		profile.Version = mod.Go
+		ev.found("version", profile.Version, "go.mod", mod.Lines["go"], "go directive")
+	}
+	for _, r := range mod.Replace {
+		if !r.Local() {
+			continue
 		}
-		if strings.HasPrefix(line, "go ") {
-			profile.Version = strings.TrimPrefix(line, "go ")
+		dir := path.Clean(r.New)
+		if !slices.Contains(profile.LocalModules, dir) {
+			profile.LocalModules = append(profile.LocalModules, dir)
 		}
+		ev.found("localModules", dir, "go.mod", r.Line, "replace "+r.Old+" => "+r.New)
 	}
 
 	// Default binary name from module path.
@@ -58,61 +82,247 @@ func (d *GoDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile,
 		binName = parts[len(parts)-1]
 	}
 
-	// Find where package main lives.
-	mainPkg := findMainPackage(root, binName)
-	profile.BuildCommand = "go build -o " + mainPkg.binName + " " + mainPkg.pkgPath
-	profile.StartCommand = "./" + mainPkg.binName
+	// Find the main packages; the primary one is what the app runs.
+	mains, skipped := findMainPackages(root, binName)
+	profile.Warnings = append(profile.Warnings, skipped...)
+	if len(mains) == 0 {
+		profile.BuildCommand = "go build -o " + binName + " ."
+		profile.StartCommand = "./" + binName
+		ev.assumed("buildCommand", profile.BuildCommand, "no package main found; building the module root")
+		ev.assumed("startCommand", profile.StartCommand, "no package main found; running the module root binary")
+	}
+	for _, m := range mains {
+		profile.Binaries = append(profile.Binaries, flkr.Binary{Name: m.binName, Package: m.pkgPath})
+		ev.found("binaries", m.pkgPath, m.file, 0, "package main")
+	}
+	if len(mains) > 0 {
+		profile.BuildCommand, profile.StartCommand = goCommands(profile.Binaries)
+		rule := "package main in " + mains[0].pkgPath
+		if len(mains) > 1 {
+			rule = fmt.Sprintf("%d main packages", len(mains))
+		}
+		ev.found("buildCommand", profile.BuildCommand, mains[0].file, 0, rule)
+		ev.found("startCommand", profile.StartCommand, mains[0].file, 0, "binary built from "+mains[0].pkgPath)
+	}
+
+	addGoBuildFlags(root, profile, ev)
 
-	// Detect Gin framework.
-	if strings.Contains(content, "github.com/gin-gonic/gin") {
-		profile.Framework = flkr.FrameworkGin
-		profile.Confidence = 0.9
+	detectGoFramework(root, mod, profile, ev)
+
+	if m, ok := findPort(root, goPortScan); ok {
+		m.apply(profile, ev)
 	}
 
+	pinVersion(root, profile, ev)
+
+	addEnvVars(profile, ev, findEnvVars(root, goEnvScan))
+
 	return profile, true, nil
 }
 
+// goFramework is a Go web framework or RPC server, recognized by a module
+// go.mod requires directly.
+type goFramework struct {
+	framework flkr.Framework
+	modules   []string
+
+	// server, if set, matches the call that starts a server, which must
+	// be made in a Go file for the module to be served rather than only
+	// used as a client.
+	server *regexp.Regexp
+
+	// port is the port the framework listens on by default or in its
+	// documentation, if not the Go default.
+	port int
+}
+
+var (
+	// grpcServerRe matches the start of a gRPC server.
+	grpcServerRe = regexp.MustCompile(`\bgrpc\.NewServer\(`)
+
+	// h2cRe matches serving HTTP/2 without TLS, which lets gRPC clients
+	// reach Connect handlers.
+	h2cRe = regexp.MustCompile(`\bh2c\.NewHandler\(|\.SetUnencryptedHTTP2\(`)
+)
+
+// goFrameworks are in order of precedence: full frameworks before the
+// routers and RPC libraries they are often combined with.
+var goFrameworks = []goFramework{
+	{framework: flkr.FrameworkGin, modules: []string{"github.com/gin-gonic/gin"}},
+	{framework: flkr.FrameworkEcho, modules: []string{"github.com/labstack/echo/v4", "github.com/labstack/echo"}, port: 1323},
+	{framework: flkr.FrameworkFiber, modules: []string{"github.com/gofiber/fiber/v3", "github.com/gofiber/fiber/v2"}, port: 3000},
+	{framework: flkr.FrameworkConnect, modules: connectModules},
+	{framework: flkr.FrameworkChi, modules: []string{"github.com/go-chi/chi/v5", "github.com/go-chi/chi"}},
+	{framework: flkr.FrameworkGorilla, modules: []string{"github.com/gorilla/mux"}},
+	{framework: flkr.FrameworkGRPC, modules: grpcModules, server: grpcServerRe, port: 50051},
+}
+
+var (
+	grpcModules    = []string{"google.golang.org/grpc"}
+	connectModules = []string{"connectrpc.com/connect", "github.com/bufbuild/connect-go"}
+)
+
+// detectGoFramework sets the framework of a Go app from the modules its
+// go.mod requires, with the framework's default port, and the protocol
+// spoken on the port.
+func detectGoFramework(root fs.FS, mod *parser.GoMod, profile *flkr.AppProfile, ev recorder) {
+	for _, fw := range goFrameworks {
+		req, ok := requiresDirectly(mod, fw.modules)
+		if !ok {
+			continue
+		}
+		if fw.server != nil {
+			if file, _ := findGoSource(root, fw.server); file == "" {
+				continue
+			}
+		}
+
+		profile.Framework = fw.framework
+		ev.found("framework", profile.Framework, "go.mod", req.Line, "requires "+req.Path)
+		if fw.port != 0 {
+			profile.Port = fw.port
+			ev.reset("port")
+			ev.assumed("port", profile.Port, fmt.Sprintf("conventional %s port", fw.framework))
+		}
+		break
+	}
+	detectGoProtocol(root, mod, profile, ev)
+}
+
+// detectGoProtocol sets PortProtocol to "grpc" for a Go app that starts a
+// gRPC server, whatever framework it also uses, or that serves Connect
+// handlers over h2c, where they answer gRPC clients too. Connect handlers
+// behind TLS or HTTP/1.1 only are left to plain HTTP.
+func detectGoProtocol(root fs.FS, mod *parser.GoMod, profile *flkr.AppProfile, ev recorder) {
+	if _, ok := requiresDirectly(mod, grpcModules); ok {
+		if file, line := findGoSource(root, grpcServerRe); file != "" {
+			profile.PortProtocol = "grpc"
+			ev.found("portProtocol", profile.PortProtocol, file, line, "grpc.NewServer in source")
+			return
+		}
+	}
+	if _, ok := requiresDirectly(mod, connectModules); ok {
+		if file, line := findGoSource(root, h2cRe); file != "" {
+			profile.PortProtocol = "grpc"
+			ev.found("portProtocol", profile.PortProtocol, file, line, "Connect handlers served over h2c")
+		}
+	}
+}
+
+// requiresDirectly returns the first of modules go.mod requires, leaving
+// out // indirect requirements.
+func requiresDirectly(mod *parser.GoMod, modules []string) (parser.GoRequire, bool) {
+	for _, m := range modules {
+		if r, ok := mod.Requires(m); ok && !r.Indirect {
+			return r, true
+		}
+	}
+	return parser.GoRequire{}, false
+}
+
+// findGoSource returns the first Go file of the module matching re, and the
+// line of the match.
+func findGoSource(root fs.FS, re *regexp.Regexp) (string, int) {
+	for _, name := range scanFiles(root, []string{"**/*.go"}) {
+		content, _ := readHead(root, name, sourceMaxSize)
+		if loc := re.FindStringIndex(content); loc != nil {
+			return name, strings.Count(content[:loc[0]], "\n") + 1
+		}
+	}
+	return "", 0
+}
+
 type mainPackageInfo struct {
 	binName string // binary name (e.g. "flkr", "server")
 	pkgPath string // Go package path (e.g. ".", "./cmd/server")
+	file    string // file declaring package main
 }
 
-// findMainPackage locates the main package in a Go project.
-// Checks root first, then cmd/<name>/ directories.
-func findMainPackage(root fs.FS, moduleBinName string) mainPackageInfo {
-	// Check root directory for package main.
-	if dirHasMainPackage(root, ".") {
-		return mainPackageInfo{binName: moduleBinName, pkgPath: "."}
-	}
-
-	// Check cmd/ subdirectories.
-	cmdEntries, err := fs.ReadDir(root, "cmd")
-	if err == nil {
-		// Prefer a subdirectory matching the module name.
-		for _, e := range cmdEntries {
-			if e.IsDir() && e.Name() == moduleBinName {
-				if dirHasMainPackage(root, "cmd/"+e.Name()) {
-					return mainPackageInfo{binName: moduleBinName, pkgPath: "./cmd/" + moduleBinName}
-				}
+// serverBinNames are binary names that suggest the app's server among
+// several main packages.
+var serverBinNames = []string{"server", "api", "web", "app", "serve", "http", "service"}
+
+// listenCallRe matches calls that start a server in a main package.
+var listenCallRe = regexp.MustCompile(`\b(?:ListenAndServe(?:TLS)?|net\.Listen|\.Serve|\.Start|\.Run)\(`)
+
+// findMainPackages returns the main packages of a Go module, the primary
+// one first: the module root, a cmd/ directory named after the module,
+// then one that looks like a server, by name or by starting one, with
+// cmd/ directories ahead of others. Nested modules, examples and
+// directories the go command ignores are skipped. So are packages whose
+// binary would be named like one found earlier, with a warning each.
+func findMainPackages(root fs.FS, moduleBinName string) ([]mainPackageInfo, []string) {
+	var mains []mainPackageInfo
+	var warnings []string
+	_ = fs.WalkDir(root, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if name != "." {
+			switch d.Name() {
+			case "examples", "example", "_examples":
+				return fs.SkipDir
+			}
+			if skipScanDir(d.Name()) || strings.HasPrefix(d.Name(), "_") || fileExists(root, name+"/go.mod") {
+				return fs.SkipDir
 			}
 		}
-		// Otherwise take the first cmd/ subdirectory with package main.
-		for _, e := range cmdEntries {
-			if e.IsDir() && dirHasMainPackage(root, "cmd/"+e.Name()) {
-				return mainPackageInfo{binName: e.Name(), pkgPath: "./cmd/" + e.Name()}
+		file := mainPackageFile(root, name)
+		if file == "" {
+			return nil
+		}
+		m := mainPackageInfo{binName: path.Base(name), pkgPath: "./" + name, file: file}
+		if name == "." {
+			m.binName, m.pkgPath = moduleBinName, "."
+		}
+		if i := slices.IndexFunc(mains, func(o mainPackageInfo) bool { return o.binName == m.binName }); i >= 0 {
+			warnings = append(warnings, fmt.Sprintf("%s: main package skipped; its binary would be named %s, like that of %s", m.pkgPath, m.binName, mains[i].pkgPath))
+			return nil
+		}
+		mains = append(mains, m)
+		return nil
+	})
+
+	rank := func(m mainPackageInfo) int {
+		inCmd := path.Dir(strings.TrimPrefix(m.pkgPath, "./")) == "cmd"
+		switch {
+		case m.pkgPath == ".":
+			return 0
+		case inCmd && m.binName == moduleBinName:
+			return 1
+		case slices.Contains(serverBinNames, m.binName) || listenCallRe.MatchString(readFileString(root, m.file)):
+			if inCmd {
+				return 2
 			}
+			return 3
+		case inCmd:
+			return 4
 		}
+		return 5
 	}
+	slices.SortStableFunc(mains, func(a, b mainPackageInfo) int { return rank(a) - rank(b) })
+	return mains, warnings
+}
 
-	// Fallback: assume root.
-	return mainPackageInfo{binName: moduleBinName, pkgPath: "."}
+// goCommands returns the command building the given binaries and the one
+// running the first. Several binaries are built into bin/.
+func goCommands(bins []flkr.Binary) (build, start string) {
+	if len(bins) == 1 {
+		return "go build -o " + bins[0].Name + " " + bins[0].Package, "./" + bins[0].Name
+	}
+	pkgs := make([]string, len(bins))
+	for i, b := range bins {
+		pkgs[i] = b.Package
+	}
+	return "go build -o bin/ " + strings.Join(pkgs, " "), "./bin/" + bins[0].Name
 }
 
-// dirHasMainPackage checks if a directory contains a Go file with "package main".
-func dirHasMainPackage(root fs.FS, dir string) bool {
+// mainPackageFile returns the path of the first Go file in dir declaring
+// "package main", or an empty string if there is none.
+func mainPackageFile(root fs.FS, dir string) string {
 	entries, err := fs.ReadDir(root, dir)
 	if err != nil {
-		return false
+		return ""
 	}
 	for _, e := range entries {
 		if e.IsDir() || !strings.HasSuffix(e.Name(), ".go") {
@@ -126,18 +336,44 @@ func dirHasMainPackage(root fs.FS, dir string) bool {
 		if dir != "." {
 			path = dir + "/" + e.Name()
 		}
-		content := readFileString(root, path)
+		if isMainPackage(root, path) {
+			return path
+		}
+	}
+	return ""
+}
+
+// packageClauseHead is how much of a Go file is read to find its package
+// clause, which follows only comments and build constraints.
+const packageClauseHead = 4096
+
+// isMainPackage reports whether the Go file at path declares package main
+// and is built. Only the head of the file is read unless the package
+// clause lies beyond it.
+func isMainPackage(root fs.FS, path string) bool {
+	content, complete := readHead(root, path, packageClauseHead)
+	for {
 		// Check the first non-empty, non-comment line for package declaration.
-		for _, line := range strings.Split(content, "\n") {
+		lines := strings.Split(content, "\n")
+		if !complete {
+			// The last line may be cut short.
+			lines = lines[:len(lines)-1]
+		}
+		for _, line := range lines {
 			line = strings.TrimSpace(line)
+			// Files excluded with an ignore constraint, such as go
+			// generate helpers, aren't part of the package.
+			if c, ok := strings.CutPrefix(line, "//go:build "); ok && slices.Contains(strings.Fields(c), "ignore") {
+				return false
+			}
 			if line == "" || strings.HasPrefix(line, "//") {
 				continue
 			}
-			if line == "package main" {
-				return true
-			}
-			break
+			return line == "package main"
+		}
+		if complete {
+			return false
 		}
+		content, complete = readFileString(root, path), true
 	}
-	return false
 }
@@ -2,6 +2,7 @@ package detector
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"testing/fstest"
 
@@ -27,6 +28,177 @@ func TestGoDetector_Gin(t *testing.T) {
 	assert.Equal(t, flkr.FrameworkGin, profile.Framework)
 	assert.Equal(t, "1.22.0", profile.Version)
 	assert.True(t, profile.HasLockfile)
+
+	fw := profile.EvidenceFor("framework")
+	require.Len(t, fw, 1)
+	assert.Equal(t, flkr.Evidence{
+		Field: "framework", Value: "gin", Detector: "go",
+		File: "go.mod", Line: 5, Rule: "requires github.com/gin-gonic/gin",
+	}, fw[0])
+	port := profile.EvidenceFor("port")
+	require.Len(t, port, 1)
+	assert.True(t, port[0].Default)
+}
+
+func TestGoDetector_GoMod(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte(`module example.com/api // the API
+
+go 1.23
+
+toolchain go1.24.2
+
+require (
+	github.com/gin-gonic/gin v1.10.0
+	golang.org/x/sync v0.8.0 // indirect
+)
+
+replace (
+	example.com/shared => ../shared
+	example.com/proto v1.2.0 => ./internal/proto
+	golang.org/x/sync => golang.org/x/sync v0.7.0
+)
+
+replace example.com/vendored => "./third_party/vendored"
+`)},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	profile, matched, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, "api", profile.Binaries[0].Name)
+	assert.Equal(t, "1.24.2", profile.Version)
+	assert.Equal(t, 5, profile.EvidenceFor("version")[0].Line)
+	assert.Equal(t, flkr.FrameworkGin, profile.Framework)
+	assert.Equal(t, 8, profile.EvidenceFor("framework")[0].Line)
+	assert.Equal(t, []string{"../shared", "internal/proto", "third_party/vendored"}, profile.LocalModules)
+
+	ev := profile.EvidenceFor("localModules")
+	require.Len(t, ev, 3)
+	assert.Equal(t, flkr.Evidence{
+		Field: "localModules", Value: "../shared", Detector: "go",
+		File: "go.mod", Line: 13, Rule: "replace example.com/shared => ../shared",
+	}, ev[0])
+}
+
+func TestGoDetector_Frameworks(t *testing.T) {
+	tests := []struct {
+		name      string
+		require   string
+		main      string
+		framework flkr.Framework
+		port      int
+	}{
+		{"echo", "github.com/labstack/echo/v4 v4.12.0", "e := echo.New()\n\te.Logger.Fatal(e.Start(\":1323\"))", flkr.FrameworkEcho, 1323},
+		{"echo default port", "github.com/labstack/echo/v4 v4.12.0", "e := echo.New()\n\te.Logger.Fatal(e.Start(addr))", flkr.FrameworkEcho, 1323},
+		{"fiber", "github.com/gofiber/fiber/v2 v2.52.5", "app := fiber.New()\n\tapp.ListenTLS(\":8443\", cert, key)", flkr.FrameworkFiber, 8443},
+		{"chi", "github.com/go-chi/chi/v5 v5.1.0", "r := chi.NewRouter()\n\thttp.ListenAndServe(\":3333\", r)", flkr.FrameworkChi, 3333},
+		{"gorilla/mux", "github.com/gorilla/mux v1.8.1", "r := mux.NewRouter()\n\thttp.ListenAndServe(\":8000\", r)", flkr.FrameworkGorilla, 8000},
+		{"connect over chi", "(\n\tconnectrpc.com/connect v1.17.0\n\tgithub.com/go-chi/chi/v5 v5.1.0\n)", "mux := http.NewServeMux()", flkr.FrameworkConnect, 8080},
+		{"echo over indirect gin", "(\n\tgithub.com/gin-gonic/gin v1.10.0 // indirect\n\tgithub.com/labstack/echo/v4 v4.12.0\n)", "", flkr.FrameworkEcho, 1323},
+		{"grpc client only", "google.golang.org/grpc v1.67.0", "conn, _ := grpc.NewClient(target)", flkr.FrameworkNone, 8080},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"go.mod":  &fstest.MapFile{Data: []byte("module example.com/svc\n\ngo 1.24\n\nrequire " + tt.require + "\n")},
+				"main.go": &fstest.MapFile{Data: []byte("package main\n\nfunc main() {\n\t" + tt.main + "\n}\n")},
+			}
+
+			profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			assert.Equal(t, tt.framework, profile.Framework)
+			assert.Equal(t, tt.port, profile.Port)
+			assert.Empty(t, profile.PortProtocol)
+		})
+	}
+}
+
+func TestGoDetector_GRPC(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte(`module example.com/greeter
+
+go 1.24
+
+require (
+	google.golang.org/grpc v1.67.0
+	google.golang.org/protobuf v1.35.1
+)
+`)},
+		"main.go": &fstest.MapFile{Data: []byte(`package main
+
+var port = flag.Int("port", 50052, "The server port")
+
+func main() {
+	flag.Parse()
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
+	if err != nil {
+		log.Fatalf("failed to listen: %v", err)
+	}
+	s := grpc.NewServer()
+	pb.RegisterGreeterServer(s, &server{})
+	s.Serve(lis)
+}
+`)},
+	}
+
+	profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, flkr.FrameworkGRPC, profile.Framework)
+	assert.Equal(t, 50052, profile.Port)
+	assert.Equal(t, "grpc", profile.PortProtocol)
+
+	ev := profile.EvidenceFor("portProtocol")
+	require.Len(t, ev, 1)
+	assert.Equal(t, flkr.Evidence{
+		Field: "portProtocol", Value: "grpc", Detector: "go",
+		File: "main.go", Line: 11, Rule: "grpc.NewServer in source",
+	}, ev[0])
+	port := profile.EvidenceFor("port")
+	require.Len(t, port, 1)
+	assert.Equal(t, "listen flag default", port[0].Rule)
+
+	delete(fsys, "main.go")
+	fsys["server.go"] = &fstest.MapFile{Data: []byte("package main\n\nfunc serve() { grpc.NewServer() }\n")}
+	profile, _, err = (&GoDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, 50051, profile.Port, "conventional gRPC port")
+	assert.True(t, profile.EvidenceFor("port")[0].Default)
+}
+
+func TestGoDetector_PortProtocol(t *testing.T) {
+	tests := []struct {
+		name      string
+		require   string
+		main      string
+		framework flkr.Framework
+		protocol  string
+	}{
+		{"gin beside a grpc server", "(\n\tgithub.com/gin-gonic/gin v1.10.0\n\tgoogle.golang.org/grpc v1.67.0\n)", "r := gin.Default()\n\ts := grpc.NewServer()", flkr.FrameworkGin, "grpc"},
+		{"chi with a grpc client", "(\n\tgithub.com/go-chi/chi/v5 v5.1.0\n\tgoogle.golang.org/grpc v1.67.0\n)", "conn, _ := grpc.NewClient(target)", flkr.FrameworkChi, ""},
+		{"connect over h2c", "connectrpc.com/connect v1.17.0", "http.ListenAndServe(\":8080\", h2c.NewHandler(mux, &http2.Server{}))", flkr.FrameworkConnect, "grpc"},
+		{"connect over unencrypted http2", "connectrpc.com/connect v1.17.0", "p := new(http.Protocols)\n\tp.SetUnencryptedHTTP2(true)", flkr.FrameworkConnect, "grpc"},
+		{"connect over http/1.1", "connectrpc.com/connect v1.17.0", "http.ListenAndServe(\":8080\", mux)", flkr.FrameworkConnect, ""},
+		{"h2c without connect", "github.com/go-chi/chi/v5 v5.1.0", "http.ListenAndServe(\":8080\", h2c.NewHandler(r, &http2.Server{}))", flkr.FrameworkChi, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"go.mod":  &fstest.MapFile{Data: []byte("module example.com/svc\n\ngo 1.24\n\nrequire " + tt.require + "\n")},
+				"main.go": &fstest.MapFile{Data: []byte("package main\n\nfunc main() {\n\t" + tt.main + "\n}\n")},
+			}
+
+			profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			assert.Equal(t, tt.framework, profile.Framework)
+			assert.Equal(t, tt.protocol, profile.PortProtocol)
+			if tt.protocol != "" {
+				require.Len(t, profile.EvidenceFor("portProtocol"), 1)
+				assert.Equal(t, "main.go", profile.EvidenceFor("portProtocol")[0].File)
+			}
+		})
+	}
 }
 
 func TestGoDetector_Plain(t *testing.T) {
@@ -40,3 +212,106 @@ func TestGoDetector_Plain(t *testing.T) {
 	assert.True(t, matched)
 	assert.Equal(t, flkr.FrameworkNone, profile.Framework)
 }
+
+func TestGoDetector_MainPackageAfterLongHeader(t *testing.T) {
+	header := strings.Repeat("// Copyright notice line that goes on for a while.\n", 200)
+	fsys := fstest.MapFS{
+		"go.mod":                 &fstest.MapFile{Data: []byte("module example.com/myapp\n")},
+		"cmd/server/main.go":     &fstest.MapFile{Data: []byte(header + "package main\n")},
+		"cmd/server/handlers.go": &fstest.MapFile{Data: []byte(header + "package server\n")},
+	}
+
+	d := &GoDetector{}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "go build -o server ./cmd/server", profile.BuildCommand)
+}
+
+func TestGoDetector_MainPackages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":                     &fstest.MapFile{Data: []byte("module example.com/acme\n\ngo 1.24\n")},
+		"cmd/cli/main.go":            &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/migrate/main.go":        &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/api/main.go":            &fstest.MapFile{Data: []byte("package main\n\nfunc main() { http.ListenAndServe(\":8080\", nil) }\n")},
+		"tools/seed/main.go":         &fstest.MapFile{Data: []byte("package main\n")},
+		"tools/migrate/main.go":      &fstest.MapFile{Data: []byte("package main\n")},
+		"internal/gen/main.go":       &fstest.MapFile{Data: []byte("//go:build ignore\n\npackage main\n")},
+		"internal/store/store.go":    &fstest.MapFile{Data: []byte("package store\n")},
+		"examples/hello/main.go":     &fstest.MapFile{Data: []byte("package main\n")},
+		"plugins/go.mod":             &fstest.MapFile{Data: []byte("module example.com/plugins\n")},
+		"plugins/cmd/plugin/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	d := &GoDetector{}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, []flkr.Binary{
+		{Name: "api", Package: "./cmd/api"},
+		{Name: "cli", Package: "./cmd/cli"},
+		{Name: "migrate", Package: "./cmd/migrate"},
+		{Name: "seed", Package: "./tools/seed"},
+	}, profile.Binaries)
+	assert.Equal(t, "go build -o bin/ ./cmd/api ./cmd/cli ./cmd/migrate ./tools/seed", profile.BuildCommand)
+	assert.Equal(t, "./bin/api", profile.StartCommand)
+	assert.Len(t, profile.EvidenceFor("binaries"), 4)
+	assert.Equal(t, "4 main packages", profile.EvidenceFor("buildCommand")[0].Rule)
+	assert.Equal(t, []string{"./tools/migrate: main package skipped; its binary would be named migrate, like that of ./cmd/migrate"}, profile.Warnings)
+}
+
+func TestGoDetector_PrimaryMainPackage(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+		want  string
+	}{
+		{name: "module root", files: []string{"main.go", "cmd/server/main.go"}, want: "./bin/acme"},
+		{name: "named after the module", files: []string{"cmd/api/main.go", "cmd/acme/main.go"}, want: "./bin/acme"},
+		{name: "server name", files: []string{"cmd/admin/main.go", "cmd/server/main.go"}, want: "./bin/server"},
+		{name: "server outside cmd", files: []string{"cmd/tool/main.go", "server/main.go"}, want: "./bin/server"},
+		{name: "cmd ahead of others", files: []string{"tools/gen/main.go", "cmd/tool/main.go"}, want: "./bin/tool"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"go.mod": &fstest.MapFile{Data: []byte("module example.com/acme\n")}}
+			for _, f := range tt.files {
+				fsys[f] = &fstest.MapFile{Data: []byte("package main\n")}
+			}
+			profile, _, err := (&GoDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, profile.StartCommand)
+		})
+	}
+}
+
+func TestGoDetector_ListenPort(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		port int
+		env  string
+	}{
+		{"literal", `http.ListenAndServe(":9000", mux)`, 9000, ""},
+		{"env with fallback", "port := os.Getenv(\"PORT\")\n\tif port == \"\" {\n\t\tport = \"8181\"\n\t}", 8181, "PORT"},
+		{"env only", `http.ListenAndServe(":"+os.Getenv("PORT"), nil)`, 8080, "PORT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"go.mod":       &fstest.MapFile{Data: []byte("module example.com/app\n\ngo 1.22\n")},
+				"main.go":      &fstest.MapFile{Data: []byte("package main\n\nfunc main() {\n\t" + tt.src + "\n}\n")},
+				"main_test.go": &fstest.MapFile{Data: []byte("package main\n\nvar _ = http.ListenAndServe(\":1234\", nil)\n")},
+			}
+			profile, matched, err := (&GoDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			require.True(t, matched)
+			assert.Equal(t, tt.port, profile.Port)
+			assert.Equal(t, tt.env, profile.PortEnv)
+			ev := profile.EvidenceFor("port")
+			require.Len(t, ev, 1)
+			assert.False(t, ev[0].Default, "a port read from source is evidence, not a default")
+			assert.Equal(t, "main.go", ev[0].File)
+		})
+	}
+}
@@ -24,36 +24,62 @@ func (d *JavaDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 
 	profile := &flkr.AppProfile{
 		Language:   flkr.LangJava,
-		Confidence: 0.7,
 		DetectedBy: d.Name(),
 		Port:       8080,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.assumed("port", profile.Port, "Java web servers listen on 8080 by default")
 
 	if hasGradle {
+		gradleFile := "build.gradle"
+		if !fileExists(root, gradleFile) {
+			gradleFile = "build.gradle.kts"
+		}
 		profile.PackageManager = flkr.PkgGradle
 		profile.BuildCommand = "./gradlew build"
 		profile.StartCommand = "java -jar build/libs/*.jar"
+		ev.found("language", profile.Language, gradleFile, 0, gradleFile+" present")
+		ev.found("packageManager", profile.PackageManager, gradleFile, 0, gradleFile+" present")
 	}
 
 	if hasPom {
 		profile.PackageManager = flkr.PkgMaven
 		profile.BuildCommand = "mvn package -DskipTests"
 		profile.StartCommand = "java -jar target/*.jar"
+		ev.found("language", profile.Language, "pom.xml", 0, "pom.xml present")
+		ev.found("packageManager", profile.PackageManager, "pom.xml", 0, "pom.xml present")
 
 		pom, err := parser.ParsePomXML(root, "pom.xml")
 		if err == nil {
+			raw := readFileString(root, "pom.xml")
 			if pom.Version != "" {
 				profile.AppVersion = pom.Version
+				ev.found("appVersion", profile.AppVersion, "pom.xml", lineOf(raw, "<version>"+pom.Version), "project version")
 			}
 			if v := pom.JavaVersion(); v != "" {
 				profile.Version = v
+				line := lineOf(raw, "<java.version>")
+				if line == 0 {
+					line = lineOf(raw, "<maven.compiler.source>")
+				}
+				ev.found("version", profile.Version, "pom.xml", line, "Java version property")
 			}
 			if pom.IsSpringBoot() {
 				profile.Framework = flkr.FrameworkSpring
-				profile.Confidence = 0.9
+				ev.found("framework", profile.Framework, "pom.xml", lineOf(raw, "org.springframework.boot"), "uses org.springframework.boot")
 			}
 		}
 	}
+	ev.assumed("buildCommand", profile.BuildCommand, "conventional "+string(profile.PackageManager)+" build")
+	ev.assumed("startCommand", profile.StartCommand, "run the packaged jar")
+
+	if m, ok := findSpringPort(root); ok {
+		m.apply(profile, ev)
+	}
+
+	pinVersion(root, profile, ev)
+
+	addEnvVars(profile, ev, findEnvVars(root, javaEnvScan))
 
 	return profile, true, nil
 }
@@ -55,3 +55,33 @@ func TestJavaDetector_Gradle(t *testing.T) {
 	assert.True(t, matched)
 	assert.Equal(t, flkr.PkgGradle, profile.PackageManager)
 }
+
+func TestJavaDetector_SpringServerPort(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		port    int
+		env     string
+	}{
+		{"properties", "application.properties", "spring.application.name=demo\nserver.port=9090\n", 9090, ""},
+		{"placeholder", "application.properties", "server.port=${PORT:8081}\n", 8081, "PORT"},
+		{"yaml", "application.yml", "server:\n  port: 7070\n", 7070, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{
+				"pom.xml":                       &fstest.MapFile{Data: []byte("<project></project>")},
+				"src/main/resources/" + tt.file: &fstest.MapFile{Data: []byte(tt.content)},
+			}
+			profile, matched, err := (&JavaDetector{}).Detect(context.Background(), fsys)
+			require.NoError(t, err)
+			require.True(t, matched)
+			assert.Equal(t, tt.port, profile.Port)
+			assert.Equal(t, tt.env, profile.PortEnv)
+			ev := profile.EvidenceFor("port")
+			require.Len(t, ev, 1)
+			assert.Equal(t, "src/main/resources/"+tt.file, ev[0].File)
+		})
+	}
+}
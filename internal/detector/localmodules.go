@@ -0,0 +1,131 @@
+package detector
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// placeLocalModules checks the local modules of a Go app at dir, relative
+// to the repository root. One outside the repository is a problem, as the
+// flake can't see it; the others widen the app's Source to the directory
+// holding them all.
+func placeLocalModules(profile *flkr.AppProfile, dir string) {
+	src := dir
+	if profile.Source != "" {
+		src = profile.Source
+	}
+	for _, m := range profile.LocalModules {
+		target := path.Join(dir, m)
+		if !path.IsAbs(m) && target != ".." && !strings.HasPrefix(target, "../") {
+			src = commonDir(src, target)
+			continue
+		}
+		where := "go.mod"
+		for _, e := range profile.EvidenceFor("localModules") {
+			if e.Value == m {
+				where = fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Rule)
+				break
+			}
+		}
+		profile.Problems = append(profile.Problems, fmt.Sprintf("%s points outside the source tree, where the flake can't see it; move the module into the repository or require a published version", where))
+	}
+	if src != dir {
+		profile.Source = src
+	}
+}
+
+// addWorkModules adds to the profile of a Go workspace member at dir the
+// modules of the go.work workspace it requires, and the local modules of
+// the workspace's replace directives, and carries over the workspace's
+// toolchain. A member built in workspace mode needs go.work in its source,
+// so its Source becomes the repository root.
+func addWorkModules(root fs.FS, profile *flkr.AppProfile, dir string) {
+	if profile.Language != flkr.LangGo || !fileExists(root, "go.work") {
+		return
+	}
+	work, err := parser.ParseGoWork(root, "go.work")
+	if err != nil {
+		return
+	}
+	mod, err := parser.ParseGoMod(root, path.Join(dir, "go.mod"))
+	if err != nil {
+		return
+	}
+	ev := newRecorder(profile, "go")
+
+	// In workspace mode the toolchain of go.work wins over that of go.mod,
+	// but not over a version pinned in a file of its own.
+	fromGoMod := !slices.ContainsFunc(profile.EvidenceFor("version"), func(e flkr.Evidence) bool { return e.File != "go.mod" })
+	if work.Toolchain != "" && fromGoMod {
+		profile.Version = work.Toolchain
+		ev.reset("version")
+		ev.found("version", profile.Version, "go.work", work.Lines["toolchain"], "toolchain directive (workspace root)")
+	}
+
+	uses := false
+	for _, use := range work.Use {
+		use = path.Clean(use)
+		if use == dir {
+			continue
+		}
+		other, err := parser.ParseGoMod(root, path.Join(use, "go.mod"))
+		if err != nil || other.Module == "" {
+			continue
+		}
+		if req, ok := mod.Requires(other.Module); ok {
+			uses = true
+			addLocalModule(profile, ev, relDir(dir, use), "go.mod", req.Line, "requires "+other.Module+", a go.work module")
+		}
+	}
+	for _, r := range work.Replace {
+		if _, ok := mod.Requires(r.Old); ok && r.Local() {
+			uses = true
+			addLocalModule(profile, ev, relDir(dir, path.Clean(r.New)), "go.work", r.Line, "replace "+r.Old+" => "+r.New+" (workspace root)")
+		}
+	}
+	if uses {
+		profile.Source = "."
+	}
+}
+
+// addLocalModule records the local module directory m of a Go app.
+func addLocalModule(profile *flkr.AppProfile, ev recorder, m, file string, line int, rule string) {
+	if slices.Contains(profile.LocalModules, m) {
+		return
+	}
+	profile.LocalModules = append(profile.LocalModules, m)
+	ev.found("localModules", m, file, line, rule)
+}
+
+// commonDir returns the deepest directory holding both a and b, which are
+// clean paths relative to the same root.
+func commonDir(a, b string) string {
+	for a != "." && b != a && !strings.HasPrefix(b, a+"/") {
+		a = path.Dir(a)
+	}
+	return a
+}
+
+// relDir returns the path of target relative to dir, both clean paths
+// relative to the same root. An absolute target is returned as is.
+func relDir(dir, target string) string {
+	if path.IsAbs(target) {
+		return target
+	}
+	base := commonDir(dir, target)
+	up := ""
+	for d := dir; d != base; d = path.Dir(d) {
+		up += "../"
+	}
+	rest := strings.TrimPrefix(strings.TrimPrefix(target, base), "/")
+	if base == "." {
+		rest = target
+	}
+	return path.Clean(up + rest)
+}
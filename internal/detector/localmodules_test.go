@@ -0,0 +1,78 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalModules_OutsideSourceTree(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte(`module example.com/api
+
+go 1.24
+
+require example.com/shared v0.0.0
+
+replace example.com/shared => ../shared
+`)},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	profile, err := NewRegistry().DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	require.Len(t, profile.Problems, 1)
+	assert.Contains(t, profile.Problems[0], "go.mod:7: replace example.com/shared => ../shared points outside the source tree")
+	assert.Empty(t, profile.Source)
+}
+
+func TestLocalModules_Workspace(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.work": &fstest.MapFile{Data: []byte("go 1.23\n\ntoolchain go1.24.1\n\nuse (\n\t./services/api\n\t./services/worker\n\t./libs/auth\n)\n\nreplace example.com/metrics => ./libs/metrics\n")},
+		"services/api/go.mod": &fstest.MapFile{Data: []byte(`module example.com/api
+
+go 1.23
+
+require (
+	example.com/auth v0.0.0
+	example.com/metrics v0.1.0
+)
+`)},
+		"services/api/main.go":    &fstest.MapFile{Data: []byte("package main\n")},
+		"services/worker/go.mod":  &fstest.MapFile{Data: []byte("module example.com/worker\n\ngo 1.23\n\nrequire example.com/queue v0.0.0\n\nreplace example.com/queue => ../queue\n")},
+		"services/worker/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"services/queue/go.mod":   &fstest.MapFile{Data: []byte("module example.com/queue\n")},
+		"libs/auth/go.mod":        &fstest.MapFile{Data: []byte("module example.com/auth\n")},
+		"libs/auth/auth.go":       &fstest.MapFile{Data: []byte("package auth\n")},
+		"libs/metrics/go.mod":     &fstest.MapFile{Data: []byte("module example.com/metrics\n")},
+	}
+
+	profiles, err := NewRegistry().DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"services/api", "services/worker"}, profilePaths(profiles))
+
+	api := profiles[0]
+	assert.Equal(t, []string{"../../libs/auth", "../../libs/metrics"}, api.LocalModules)
+	assert.Equal(t, ".", api.Source, "the build needs go.work")
+	assert.Equal(t, "1.24.1", api.Version)
+	assert.Equal(t, "go.work", api.EvidenceFor("version")[0].File)
+	assert.Empty(t, api.Problems)
+
+	worker := profiles[1]
+	assert.Equal(t, []string{"../queue"}, worker.LocalModules)
+	assert.Equal(t, "services", worker.Source)
+	assert.Empty(t, worker.Problems)
+}
+
+func TestRelDir(t *testing.T) {
+	assert.Equal(t, "../../libs/auth", relDir("services/api", "libs/auth"))
+	assert.Equal(t, "../queue", relDir("services/worker", "services/queue"))
+	assert.Equal(t, "libs/auth", relDir(".", "libs/auth"))
+	assert.Equal(t, "..", relDir("api", "."))
+	assert.Equal(t, "services", commonDir("services/worker", "services/queue"))
+	assert.Equal(t, ".", commonDir("api", "apiv2"))
+}
@@ -3,6 +3,7 @@ package detector
 import (
 	"context"
 	"io/fs"
+	"regexp"
 	"strings"
 
 	"github.com/narvanalabs/flkr/internal/parser"
@@ -27,13 +28,17 @@ func (d *NodeDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 
 	profile := &flkr.AppProfile{
 		Language:   flkr.LangNode,
-		Confidence: 0.7,
 		DetectedBy: d.Name(),
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "package.json", 0, "package.json present")
+	raw := readFileString(root, "package.json")
 
 	// Detect Node version from engines field.
 	if pkg.Engines.Node != "" {
 		profile.Version = cleanVersion(pkg.Engines.Node)
+		profile.VersionConstraint = strings.TrimSpace(pkg.Engines.Node)
+		ev.found("version", profile.Version, "package.json", lineOf(raw, `"engines"`), "engines.node "+pkg.Engines.Node)
 	}
 
 	// Detect package manager from lockfiles.
@@ -42,69 +47,93 @@ func (d *NodeDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 		profile.PackageManager = flkr.PkgPNPM
 		profile.HasLockfile = true
 		profile.LockfileType = "pnpm"
+		ev.found("packageManager", profile.PackageManager, "pnpm-lock.yaml", 0, "pnpm lockfile present")
 	case fileExists(root, "yarn.lock"):
 		profile.PackageManager = flkr.PkgYarn
 		profile.HasLockfile = true
 		profile.LockfileType = "yarn"
+		ev.found("packageManager", profile.PackageManager, "yarn.lock", 0, "yarn lockfile present")
 	default:
 		profile.PackageManager = flkr.PkgNPM
 		if fileExists(root, "package-lock.json") {
 			profile.HasLockfile = true
 			profile.LockfileType = "npm"
+			ev.found("packageManager", profile.PackageManager, "package-lock.json", 0, "npm lockfile present")
+		} else {
+			ev.assumed("packageManager", profile.PackageManager, "no lockfile; npm is the default")
 		}
 	}
 
 	// Extract project version.
 	if pkg.Version != "" {
 		profile.AppVersion = pkg.Version
+		ev.found("appVersion", profile.AppVersion, "package.json", lineOf(raw, `"version"`), "version field")
 	}
 
 	// Detect framework.
-	d.detectFramework(pkg, profile)
+	d.detectFramework(pkg, profile, ev, raw)
 
 	// Detect build/start commands from scripts.
 	if cmd, ok := pkg.Scripts["build"]; ok {
 		profile.BuildCommand = cmd
+		ev.found("buildCommand", cmd, "package.json", lineOf(raw, `"build"`), "scripts.build")
 	}
 	if cmd, ok := pkg.Scripts["start"]; ok {
 		profile.StartCommand = cmd
+		ev.found("startCommand", cmd, "package.json", lineOf(raw, `"start"`), "scripts.start")
 	}
 
-	// Default port.
-	if profile.Port == 0 {
-		profile.Port = 3000
+	// Detect the listen port, preferring flags in the start script.
+	profile.Port = 3000
+	ev.assumed("port", profile.Port, "Node apps listen on 3000 by default")
+	if m, ok := commandPort(profile.StartCommand); ok {
+		m.file, m.line = "package.json", lineOf(raw, `"start"`)
+		m.apply(profile, ev)
+	} else if m, ok := findPort(root, nodePortScan); ok {
+		m.apply(profile, ev)
 	}
 
+	pinVersion(root, profile, ev)
+
+	addEnvVars(profile, ev, findEnvVars(root, nodeEnvScan))
+
 	return profile, true, nil
 }
 
-func (d *NodeDetector) detectFramework(pkg *parser.PackageJSON, profile *flkr.AppProfile) {
+func (d *NodeDetector) detectFramework(pkg *parser.PackageJSON, profile *flkr.AppProfile, ev recorder, raw string) {
+	var dep string
 	switch {
 	case pkg.HasDep("next"):
+		dep = "next"
 		profile.Framework = flkr.FrameworkNextJS
 		profile.OutputDir = ".next"
-		profile.Confidence = 0.9
 	case pkg.HasDep("nuxt"):
+		dep = "nuxt"
 		profile.Framework = flkr.FrameworkNuxt
 		profile.OutputDir = ".output"
-		profile.Confidence = 0.9
 	case pkg.HasDep("@remix-run/node") || pkg.HasDep("@remix-run/react"):
+		dep = "@remix-run/node"
+		if !pkg.HasDep(dep) {
+			dep = "@remix-run/react"
+		}
 		profile.Framework = flkr.FrameworkRemix
 		profile.OutputDir = "build"
-		profile.Confidence = 0.85
 	case pkg.HasDep("vite"):
+		dep = "vite"
 		profile.Framework = flkr.FrameworkVite
 		profile.OutputDir = "dist"
-		profile.Confidence = 0.8
+	default:
+		return
 	}
+	line := lineOf(raw, `"`+dep+`"`)
+	ev.found("framework", profile.Framework, "package.json", line, "depends on "+dep)
+	ev.assumed("outputDir", profile.OutputDir, string(profile.Framework)+" build output directory")
 }
 
-// cleanVersion strips common version prefixes/ranges to extract a bare version.
+// cleanVersion extracts the first version number of a constraint, such
+// as 20.0.0 from ">=20.0.0" or 3.2 from "~> 3.2".
 func cleanVersion(v string) string {
-	v = strings.TrimSpace(v)
-	v = strings.TrimLeft(v, ">=^~<>!v")
-	if i := strings.IndexAny(v, " |&"); i != -1 {
-		v = v[:i]
-	}
-	return v
+	return versionNumberRe.FindString(v)
 }
+
+var versionNumberRe = regexp.MustCompile(`\d+(?:\.\d+)*`)
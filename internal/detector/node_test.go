@@ -36,7 +36,7 @@ func TestNodeDetector_NextJS(t *testing.T) {
 	assert.Equal(t, "next start", profile.StartCommand)
 	assert.True(t, profile.HasLockfile)
 	assert.Equal(t, 3000, profile.Port)
-	assert.InDelta(t, 0.9, profile.Confidence, 0.01)
+	assert.InDelta(t, 0.81, profile.Score(), 0.01)
 }
 
 func TestNodeDetector_Yarn(t *testing.T) {
@@ -76,3 +76,53 @@ func TestNodeDetector_NoPackageJSON(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, matched)
 }
+
+func TestNodeDetector_ListenPort(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		port  int
+		env   string
+		file  string
+	}{
+		{
+			name:  "start script flag",
+			files: fstest.MapFS{"server.js": &fstest.MapFile{Data: []byte("app.listen(4001)\n")}},
+			port:  5050,
+			file:  "package.json",
+		},
+		{
+			name:  "listen call",
+			files: fstest.MapFS{"src/server.js": &fstest.MapFile{Data: []byte("const app = express()\napp.listen(4001)\n")}},
+			port:  4001,
+			file:  "src/server.js",
+		},
+		{
+			name:  "env with fallback",
+			files: fstest.MapFS{"index.ts": &fstest.MapFile{Data: []byte("const port = process.env.PORT ?? 8787\n")}},
+			port:  8787,
+			env:   "PORT",
+			file:  "index.ts",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := "node server.js"
+			if tt.file == "package.json" {
+				start = "next start -p 5050"
+			}
+			tt.files["package.json"] = &fstest.MapFile{Data: []byte(`{"scripts": {"start": "` + start + `"}}`)}
+			tt.files["server.test.js"] = &fstest.MapFile{Data: []byte("app.listen(9999)\n")}
+
+			profile, matched, err := (&NodeDetector{}).Detect(context.Background(), tt.files)
+			require.NoError(t, err)
+			require.True(t, matched)
+			assert.Equal(t, tt.port, profile.Port)
+			assert.Equal(t, tt.env, profile.PortEnv)
+			ev := profile.EvidenceFor("port")
+			require.Len(t, ev, 1)
+			assert.False(t, ev[0].Default)
+			assert.Equal(t, tt.file, ev[0].File)
+		})
+	}
+}
@@ -3,6 +3,7 @@ package detector
 import (
 	"context"
 	"io/fs"
+	"strings"
 
 	"github.com/narvanalabs/flkr/internal/parser"
 	"github.com/narvanalabs/flkr/pkg/flkr"
@@ -22,38 +23,54 @@ func (d *PHPDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile,
 	profile := &flkr.AppProfile{
 		Language:       flkr.LangPHP,
 		PackageManager: flkr.PkgComposer,
-		Confidence:     0.7,
 		DetectedBy:     d.Name(),
 		Port:           8000,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "composer.json", 0, "composer.json present")
+	ev.found("packageManager", profile.PackageManager, "composer.json", 0, "composer.json present")
+	ev.assumed("port", profile.Port, "PHP built-in server listens on 8000 by default")
 
 	if fileExists(root, "composer.lock") {
 		profile.HasLockfile = true
 		profile.LockfileType = "composer"
+		ev.found("lockfileType", profile.LockfileType, "composer.lock", 0, "composer.lock present")
 	}
 
 	// Parse composer.json for framework detection.
 	comp, err := parser.ParseComposerJSON(root, "composer.json")
 	if err == nil {
+		raw := readFileString(root, "composer.json")
+
 		// Extract project version.
 		if comp.Version != "" {
 			profile.AppVersion = comp.Version
+			ev.found("appVersion", profile.AppVersion, "composer.json", lineOf(raw, `"version"`), "version field")
 		}
 
 		// Detect PHP version.
 		if v, ok := comp.Require["php"]; ok {
 			profile.Version = cleanVersion(v)
+			profile.VersionConstraint = strings.TrimSpace(v)
+			ev.found("version", profile.Version, "composer.json", lineOf(raw, `"php"`), "require.php "+v)
 		}
 
 		// Detect Laravel.
 		if comp.HasRequire("laravel/framework") {
 			profile.Framework = flkr.FrameworkLaravel
-			profile.Confidence = 0.9
 			profile.BuildCommand = "composer install --no-dev --optimize-autoloader"
 			profile.StartCommand = "php artisan serve --host=0.0.0.0 --port=8000"
 			profile.OutputDir = "public"
+			ev.found("framework", profile.Framework, "composer.json", lineOf(raw, `"laravel/framework"`), "requires laravel/framework")
+			ev.assumed("buildCommand", profile.BuildCommand, "optimized production composer install")
+			ev.assumed("startCommand", profile.StartCommand, "artisan development server")
+			ev.assumed("outputDir", profile.OutputDir, "Laravel public web root")
 		}
 	}
 
+	pinVersion(root, profile, ev)
+
+	addEnvVars(profile, ev, findEnvVars(root, phpEnvScan))
+
 	return profile, true, nil
 }
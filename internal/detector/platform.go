@@ -0,0 +1,410 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// PlatformDetector reads the config of the platform an app is deployed to
+// today: nixpacks.toml, railway.json, Heroku's app.json, Render's
+// render.yaml and fly.toml. These state how the app is built and run in
+// production, so they override what was inferred from the code. When
+// several are present, later ones in that list win. Like
+// CrosscuttingDetector, it doesn't match on its own.
+type PlatformDetector struct {
+	// deps maps apt packages and Heroku buildpacks to nixpkgs attributes.
+	deps systemDepMap
+}
+
+func (d *PlatformDetector) Name() string  { return "platform" }
+func (d *PlatformDetector) Priority() int { return 100 }
+
+func (d *PlatformDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error) {
+	profile := &flkr.AppProfile{}
+	matched := false
+	for _, read := range []func(fs.FS) (*flkr.AppProfile, error){d.nixpacks, d.railway, d.heroku, d.render, d.fly} {
+		p, err := read(root)
+		if err != nil {
+			profile.Warnings = append(profile.Warnings, err.Error())
+			continue
+		}
+		if p != nil {
+			profile.Merge(p)
+			matched = true
+		}
+	}
+	return profile, matched, nil
+}
+
+// nixpacks reads nixpacks.toml.
+func (d *PlatformDetector) nixpacks(root fs.FS) (*flkr.AppProfile, error) {
+	const file = "nixpacks.toml"
+	if !fileExists(root, file) {
+		return nil, nil
+	}
+	np, err := parser.ParseNixpacksTOML(root, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	content := readFileString(root, file)
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, d.Name())
+
+	for _, name := range keysInOrder(np.Variables, content) {
+		addPlatformEnv(p, ev, name, fmt.Sprint(np.Variables[name]), false, file, keyLine(content, name), "nixpacks.toml variables")
+	}
+
+	setup := np.Phases["setup"]
+	for _, attr := range slices.Concat(setup.NixPkgs, setup.NixLibs) {
+		if attr == "..." || isToolchainAttr(attr) || slices.Contains(p.SystemDeps, attr) {
+			continue
+		}
+		p.SystemDeps = append(p.SystemDeps, attr)
+		ev.found("systemDeps", attr, file, lineOf(content, strconv.Quote(attr)), "installed in the nixpacks.toml setup phase")
+	}
+	d.addPackages(p, ev, "apt", setup.AptPkgs, file, keyLine(content, "aptPkgs"), "listed in nixpacks.toml aptPkgs")
+
+	if cmd := joinCommands(np.Phases["build"].Cmds); cmd != "" {
+		p.BuildCommand = cmd
+		ev.found("buildCommand", cmd, file, lineOf(content, "[phases.build]"), "nixpacks.toml build phase")
+	}
+	if np.Start.Cmd != "" {
+		setPlatformStart(p, ev, np.Start.Cmd, file, keyLine(content, "cmd"), "nixpacks.toml start command")
+	}
+	return p, nil
+}
+
+// railway reads railway.json or railway.toml.
+func (d *PlatformDetector) railway(root fs.FS) (*flkr.AppProfile, error) {
+	var file string
+	for _, n := range []string{"railway.json", "railway.toml"} {
+		if fileExists(root, n) {
+			file = n
+			break
+		}
+	}
+	if file == "" {
+		return nil, nil
+	}
+	cfg, err := parser.ParseRailwayConfig(root, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	content := readFileString(root, file)
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, d.Name())
+
+	if cmd := cfg.Build.BuildCommand; cmd != "" {
+		p.BuildCommand = cmd
+		ev.found("buildCommand", cmd, file, keyLine(content, "buildCommand"), "Railway build command")
+	}
+	if cmd := cfg.Deploy.StartCommand; cmd != "" {
+		setPlatformStart(p, ev, cmd, file, keyLine(content, "startCommand"), "Railway start command")
+	}
+	if cmd := joinCommands(cfg.Deploy.PreDeployCommand); cmd != "" {
+		p.ReleaseCommand = cmd
+		ev.found("releaseCommand", cmd, file, keyLine(content, "preDeployCommand"), "Railway pre-deploy command")
+	}
+	if hc := cfg.Deploy.HealthcheckPath; hc != "" {
+		p.HealthCheck = hc
+		ev.found("healthCheck", hc, file, keyLine(content, "healthcheckPath"), "Railway health check")
+	}
+	return p, nil
+}
+
+// heroku reads a Heroku app.json and the Aptfile of the apt buildpack.
+func (d *PlatformDetector) heroku(root fs.FS) (*flkr.AppProfile, error) {
+	const file = "app.json"
+	if !fileExists(root, file) {
+		return nil, nil
+	}
+	app, err := parser.ParseAppJSON(root, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	content := readFileString(root, file)
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, d.Name())
+
+	for _, name := range keysInOrder(app.Env, content) {
+		e := app.Env[name]
+		// Generated values such as secrets must be set by hand outside
+		// Heroku.
+		required := e.Value == "" && (e.Required || e.Generator != "")
+		addPlatformEnv(p, ev, name, e.Value, required, file, keyLine(content, name), "app.json env")
+	}
+
+	for _, bp := range app.Buildpacks {
+		name := path.Base(strings.TrimSuffix(bp.URL, ".git"))
+		line := lineOf(content, bp.URL)
+		if name == "apt" || name == "heroku-buildpack-apt" {
+			aptfile := readFileString(root, "Aptfile")
+			var pkgs []string
+			for _, l := range strings.Split(aptfile, "\n") {
+				l = strings.TrimSpace(l)
+				if l == "" || strings.HasPrefix(l, "#") || strings.HasPrefix(l, ":") || strings.Contains(l, "://") {
+					continue
+				}
+				pkgs = append(pkgs, l)
+			}
+			d.addPackages(p, ev, "apt", pkgs, "Aptfile", 0, "listed in Aptfile")
+			continue
+		}
+		d.addPackages(p, ev, "buildpack", []string{name}, file, line, "installed by a Heroku buildpack")
+	}
+
+	// postdeploy runs once after the app is created, typically to seed
+	// its database, so it is kept as a process to run by hand.
+	if cmd := app.Scripts["postdeploy"]; cmd != "" {
+		p.Processes = append(p.Processes, flkr.Process{Name: "postdeploy", Command: cmd})
+		ev.found("processes", "postdeploy: "+cmd, file, keyLine(content, "postdeploy"), "app.json postdeploy script")
+	}
+	return p, nil
+}
+
+// renderServiceTypes are the Render service types that run the app's code.
+var renderServiceTypes = []string{"web", "pserv", "worker", "cron"}
+
+// render reads a Render blueprint. Its first web or private service
+// rooted at the app directory is the app; its other workers and cron
+// jobs become processes.
+func (d *PlatformDetector) render(root fs.FS) (*flkr.AppProfile, error) {
+	const file = "render.yaml"
+	if !fileExists(root, file) {
+		return nil, nil
+	}
+	bp, err := parser.ParseRenderYAML(root, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	var services []parser.RenderService
+	for _, s := range bp.Services {
+		if slices.Contains(renderServiceTypes, s.Type) && path.Clean(s.RootDir) == "." {
+			services = append(services, s)
+		}
+	}
+	app := slices.IndexFunc(services, func(s parser.RenderService) bool { return s.Type == "web" })
+	if app == -1 {
+		app = slices.IndexFunc(services, func(s parser.RenderService) bool { return s.Type == "pserv" })
+	}
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, d.Name())
+	for i, s := range services {
+		if i != app {
+			if s.StartCommand != "" {
+				p.Processes = append(p.Processes, flkr.Process{Name: s.Name, Command: s.StartCommand})
+				ev.found("processes", s.Name+": "+s.StartCommand, file, s.Lines["startCommand"], "Render "+s.Type+" "+s.Name)
+			}
+			continue
+		}
+		rule := "Render service " + s.Name
+		for _, v := range s.EnvVars {
+			if v.Key != "" {
+				addPlatformEnv(p, ev, v.Key, v.Value, v.Value == "", file, v.Line, rule+" envVars")
+			}
+		}
+		if s.BuildCommand != "" {
+			p.BuildCommand = s.BuildCommand
+			ev.found("buildCommand", s.BuildCommand, file, s.Lines["buildCommand"], rule+" buildCommand")
+		}
+		if s.StartCommand != "" {
+			setPlatformStart(p, ev, s.StartCommand, file, s.Lines["startCommand"], rule+" startCommand")
+		}
+		if s.PreDeployCommand != "" {
+			p.ReleaseCommand = s.PreDeployCommand
+			ev.found("releaseCommand", s.PreDeployCommand, file, s.Lines["preDeployCommand"], rule+" preDeployCommand")
+		}
+		if s.HealthCheckPath != "" {
+			p.HealthCheck = s.HealthCheckPath
+			ev.found("healthCheck", s.HealthCheckPath, file, s.Lines["healthCheckPath"], rule+" healthCheckPath")
+		}
+	}
+	return p, nil
+}
+
+// fly reads fly.toml. Its web process is the one its HTTP service routes
+// to, "app" unless stated otherwise.
+func (d *PlatformDetector) fly(root fs.FS) (*flkr.AppProfile, error) {
+	const file = "fly.toml"
+	if !fileExists(root, file) {
+		return nil, nil
+	}
+	fly, err := parser.ParseFlyTOML(root, file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	content := readFileString(root, file)
+	p := &flkr.AppProfile{}
+	ev := newRecorder(p, d.Name())
+
+	for _, name := range keysInOrder(fly.Env, content) {
+		addPlatformEnv(p, ev, name, fmt.Sprint(fly.Env[name]), false, file, keyLine(content, name), "fly.toml [env]")
+	}
+
+	services := fly.Services
+	if fly.HTTPService != nil {
+		services = append([]parser.FlyService{*fly.HTTPService}, services...)
+	}
+	web := "app"
+	if len(services) > 0 && len(services[0].Processes) > 0 {
+		web = services[0].Processes[0]
+	}
+	for _, name := range keysInOrder(fly.Processes, content) {
+		cmd := fly.Processes[name]
+		line := keyLine(content, name)
+		if name == web || len(fly.Processes) == 1 {
+			setPlatformStart(p, ev, cmd, file, line, "fly.toml "+name+" process")
+			continue
+		}
+		p.Processes = append(p.Processes, flkr.Process{Name: name, Command: cmd})
+		ev.found("processes", name+": "+cmd, file, line, "fly.toml "+name+" process")
+	}
+
+	if cmd := fly.Deploy.ReleaseCommand; cmd != "" {
+		p.ReleaseCommand = cmd
+		ev.found("releaseCommand", cmd, file, keyLine(content, "release_command"), "fly.toml release_command")
+	}
+
+	if len(services) > 0 && services[0].InternalPort != 0 {
+		port := services[0].InternalPort
+		ev.reset("port")
+		p.Port = port
+		ev.found("port", port, file, keyLine(content, "internal_port"), "fly.toml internal_port")
+	}
+
+	var checks []parser.FlyCheck
+	for _, s := range services {
+		checks = append(append(checks, s.Checks...), s.HTTPChecks...)
+	}
+	for _, name := range keysInOrder(fly.Checks, content) {
+		checks = append(checks, fly.Checks[name])
+	}
+	for _, c := range checks {
+		if c.Path != "" {
+			p.HealthCheck = c.Path
+			ev.found("healthCheck", c.Path, file, lineOf(content, c.Path), "fly.toml health check")
+			break
+		}
+	}
+	return p, nil
+}
+
+// addPackages maps the packages of an ecosystem, such as apt packages or
+// Heroku buildpacks, to nixpkgs attributes.
+func (d *PlatformDetector) addPackages(p *flkr.AppProfile, ev recorder, eco string, pkgs []string, file string, line int, rule string) {
+	for _, pkg := range pkgs {
+		deps, ok := d.deps[eco][pkg]
+		if !ok {
+			continue
+		}
+		for _, dep := range deps.Build {
+			if !slices.Contains(p.BuildDeps, dep) {
+				p.BuildDeps = append(p.BuildDeps, dep)
+				ev.found("buildDeps", dep, file, line, pkg+" "+rule)
+			}
+		}
+		for _, dep := range deps.Runtime {
+			if !slices.Contains(p.SystemDeps, dep) {
+				p.SystemDeps = append(p.SystemDeps, dep)
+				ev.found("systemDeps", dep, file, line, pkg+" "+rule)
+			}
+		}
+	}
+}
+
+// setPlatformStart sets the start command of p and the port it passes to
+// the app, if any.
+func setPlatformStart(p *flkr.AppProfile, ev recorder, cmd, file string, line int, rule string) {
+	p.StartCommand = cmd
+	ev.found("startCommand", cmd, file, line, rule)
+	if m, ok := commandPort(cmd); ok {
+		ev.reset("port")
+		ev.reset("portEnv")
+		p.Port, p.PortEnv = m.port, m.env
+		if m.port != 0 {
+			ev.found("port", m.port, file, line, m.rule)
+		}
+		if m.env != "" {
+			ev.found("portEnv", m.env, file, line, m.rule)
+		}
+	}
+}
+
+// addPlatformEnv records an env var set by a platform config. A numeric
+// PORT is the port the platform expects the app to listen on.
+func addPlatformEnv(p *flkr.AppProfile, ev recorder, name, value string, required bool, file string, line int, rule string) {
+	p.EnvVars = append(p.EnvVars, name)
+	p.Env = append(p.Env, flkr.EnvVar{Name: name, Required: required, Default: value})
+	if value != "" {
+		rule += ", set to " + strconv.Quote(value)
+	}
+	ev.found("envVars", name, file, line, rule)
+	if port, err := strconv.Atoi(value); err == nil && name == "PORT" && port > 0 {
+		p.Port, p.PortEnv = port, name
+		ev.found("port", port, file, line, rule)
+		ev.found("portEnv", name, file, line, rule)
+	}
+}
+
+// joinCommands joins a list of commands into one shell command, dropping
+// the "..." placeholder nixpacks uses for the provider's defaults.
+func joinCommands(cmds []string) string {
+	return strings.Join(slices.DeleteFunc(slices.Clone(cmds), func(c string) bool {
+		return c == "..." || strings.TrimSpace(c) == ""
+	}), " && ")
+}
+
+// keysInOrder returns the keys of m in the order they appear in content,
+// the file m was decoded from.
+func keysInOrder[V any](m map[string]V, content string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.SortFunc(keys, func(a, b string) int {
+		if la, lb := keyLine(content, a), keyLine(content, b); la != lb {
+			return la - lb
+		}
+		return strings.Compare(a, b)
+	})
+	return keys
+}
+
+// keyLine returns the 1-based line on which key is assigned in a TOML,
+// JSON or YAML file, including inline objects, or opens a TOML table such
+// as [checks.key]. It returns 0 if key is not found.
+func keyLine(content, key string) int {
+	re, err := regexp.Compile(`(?m)(?:^|[{,])[ \t]*(?:\[\w+\.)?["']?` + regexp.QuoteMeta(key) + `["']?\]?[ \t]*[=:\n]`)
+	if err != nil {
+		return 0
+	}
+	loc := re.FindStringIndex(content)
+	if loc == nil {
+		return 0
+	}
+	return strings.Count(content[:loc[0]], "\n") + 1
+}
+
+// isToolchainAttr reports whether attr is a language toolchain in the
+// catalog, which flkr resolves on its own rather than as a system dep.
+func isToolchainAttr(attr string) bool {
+	for _, set := range toolchainCatalog() {
+		if set.Default == attr || slices.ContainsFunc(set.Toolchains, func(t toolchain) bool { return t.Attr == attr }) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlatformDetector_Fly(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fly.toml": &fstest.MapFile{Data: []byte(`app = "acme-api"
+primary_region = "ams"
+
+[build]
+  dockerfile = "Dockerfile.fly"
+
+[deploy]
+  release_command = "bin/rails db:migrate"
+
+[env]
+  RAILS_ENV = "production"
+  WEB_CONCURRENCY = 2
+
+[processes]
+  app = "bin/rails server"
+  worker = "bundle exec sidekiq"
+
+[http_service]
+  internal_port = 3000
+  processes = ["app"]
+
+  [[http_service.checks]]
+    interval = "15s"
+    method = "GET"
+    path = "/up"
+`)},
+	}
+
+	d := &PlatformDetector{deps: systemDepsFor()}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Empty(t, profile.Language)
+	assert.Equal(t, "bin/rails server", profile.StartCommand)
+	assert.Equal(t, "bin/rails db:migrate", profile.ReleaseCommand)
+	assert.Equal(t, []flkr.Process{{Name: "worker", Command: "bundle exec sidekiq"}}, profile.Processes)
+	assert.Equal(t, 3000, profile.Port)
+	assert.Equal(t, "/up", profile.HealthCheck)
+	assert.Equal(t, []string{"RAILS_ENV", "WEB_CONCURRENCY"}, profile.EnvVars)
+	assert.Equal(t, []flkr.EnvVar{
+		{Name: "RAILS_ENV", Default: "production"},
+		{Name: "WEB_CONCURRENCY", Default: "2"},
+	}, profile.Env)
+
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "fly.toml", ev[0].File)
+	assert.Equal(t, 19, ev[0].Line)
+	assert.Equal(t, 8, profile.EvidenceFor("releaseCommand")[0].Line)
+	assert.Equal(t, 25, profile.EvidenceFor("healthCheck")[0].Line)
+}
+
+func TestPlatformDetector_Render(t *testing.T) {
+	fsys := fstest.MapFS{
+		"render.yaml": &fstest.MapFile{Data: []byte(`services:
+  - type: web
+    name: api
+    runtime: python
+    buildCommand: pip install -r requirements.txt && python manage.py collectstatic --noinput
+    startCommand: gunicorn app.wsgi --bind 0.0.0.0:$PORT
+    preDeployCommand: python manage.py migrate
+    healthCheckPath: /healthz
+    envVars:
+      - key: DJANGO_SETTINGS_MODULE
+        value: app.settings.production
+      - key: SECRET_KEY
+        generateValue: true
+      - key: DATABASE_URL
+        fromDatabase:
+          name: db
+          property: connectionString
+  - type: worker
+    name: celery
+    startCommand: celery -A app worker
+  - type: web
+    name: docs
+    rootDir: docs
+    startCommand: mkdocs serve
+databases:
+  - name: db
+`)},
+	}
+
+	d := &PlatformDetector{deps: systemDepsFor()}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, "pip install -r requirements.txt && python manage.py collectstatic --noinput", profile.BuildCommand)
+	assert.Equal(t, "gunicorn app.wsgi --bind 0.0.0.0:$PORT", profile.StartCommand)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	assert.Equal(t, "python manage.py migrate", profile.ReleaseCommand)
+	assert.Equal(t, "/healthz", profile.HealthCheck)
+	assert.Equal(t, []flkr.Process{{Name: "celery", Command: "celery -A app worker"}}, profile.Processes)
+	assert.Equal(t, []flkr.EnvVar{
+		{Name: "DJANGO_SETTINGS_MODULE", Default: "app.settings.production"},
+		{Name: "SECRET_KEY", Required: true},
+		{Name: "DATABASE_URL", Required: true},
+	}, profile.Env)
+	assert.Equal(t, 6, profile.EvidenceFor("startCommand")[0].Line)
+	assert.Equal(t, 12, profile.EvidenceFor("envVars")[1].Line)
+}
+
+func TestPlatformDetector_Heroku(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.json": &fstest.MapFile{Data: []byte(`{
+  "name": "acme",
+  "env": {
+    "NODE_ENV": "production",
+    "SESSION_SECRET": {"description": "Signs cookies", "generator": "secret"},
+    "SENTRY_DSN": {"required": false},
+    "STRIPE_KEY": {"description": "Stripe API key"}
+  },
+  "buildpacks": [
+    {"url": "heroku-community/apt"},
+    {"url": "https://github.com/jonathanong/heroku-buildpack-ffmpeg-latest.git"},
+    {"url": "heroku/nodejs"}
+  ],
+  "scripts": {"postdeploy": "npm run seed"}
+}`)},
+		"Aptfile": &fstest.MapFile{Data: []byte("# image processing\nlibvips-dev\n:repo:deb http://archive.ubuntu.com/ubuntu jammy main\n")},
+	}
+
+	d := &PlatformDetector{deps: systemDepsFor()}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, []flkr.EnvVar{
+		{Name: "NODE_ENV", Default: "production"},
+		{Name: "SESSION_SECRET", Required: true},
+		{Name: "SENTRY_DSN"},
+		{Name: "STRIPE_KEY", Required: true},
+	}, profile.Env)
+	assert.Equal(t, []string{"vips", "ffmpeg"}, profile.SystemDeps)
+	assert.Equal(t, []flkr.Process{{Name: "postdeploy", Command: "npm run seed"}}, profile.Processes)
+
+	ev := profile.EvidenceFor("systemDeps")
+	require.Len(t, ev, 2)
+	assert.Equal(t, "Aptfile", ev[0].File)
+	assert.Equal(t, "app.json", ev[1].File)
+	assert.Equal(t, 11, ev[1].Line)
+}
+
+func TestPlatformDetector_RailwayAndNixpacks(t *testing.T) {
+	fsys := fstest.MapFS{
+		"railway.json": &fstest.MapFile{Data: []byte(`{
+  "$schema": "https://railway.com/railway.schema.json",
+  "build": {"builder": "NIXPACKS", "buildCommand": "pnpm build"},
+  "deploy": {
+    "startCommand": "node dist/index.js",
+    "preDeployCommand": ["pnpm db:migrate", "pnpm db:seed"],
+    "healthcheckPath": "/health"
+  }
+}`)},
+		"nixpacks.toml": &fstest.MapFile{Data: []byte(`providers = ["node"]
+
+[variables]
+PORT = "4000"
+
+[phases.setup]
+nixPkgs = ["...", "nodejs_22", "ffmpeg"]
+aptPkgs = ["libvips-dev"]
+
+[phases.build]
+cmds = ["pnpm install", "pnpm build:all"]
+
+[start]
+cmd = "pnpm start"
+`)},
+	}
+
+	d := &PlatformDetector{deps: systemDepsFor()}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, "pnpm build", profile.BuildCommand, "railway.json wins over nixpacks.toml")
+	assert.Equal(t, "node dist/index.js", profile.StartCommand)
+	assert.Equal(t, "pnpm db:migrate && pnpm db:seed", profile.ReleaseCommand)
+	assert.Equal(t, "/health", profile.HealthCheck)
+	assert.Equal(t, 4000, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	assert.Equal(t, []string{"ffmpeg", "vips"}, profile.SystemDeps, "toolchains are left to flkr")
+
+	ev := profile.EvidenceFor("buildCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "railway.json", ev[0].File)
+	assert.Equal(t, 3, ev[0].Line)
+}
+
+func TestPlatformDetector_InvalidConfig(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fly.toml":     &fstest.MapFile{Data: []byte("[http_service\n")},
+		"railway.json": &fstest.MapFile{Data: []byte(`{"deploy": {"startCommand": "./server"}}`)},
+	}
+
+	profile, matched, err := (&PlatformDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, "./server", profile.StartCommand)
+	require.Len(t, profile.Warnings, 1)
+	assert.Contains(t, profile.Warnings[0], "fly.toml")
+}
+
+func TestPlatform_OverridesDetected(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"build": "tsc", "start": "node server.js"}, "dependencies": {"express": "^4"}}`)},
+		"server.js":    &fstest.MapFile{Data: []byte("app.listen(process.env.PORT || 3000)\n")},
+		"fly.toml": &fstest.MapFile{Data: []byte(`app = "acme"
+
+[processes]
+  app = "node dist/server.js"
+
+[[services]]
+  internal_port = 8080
+  protocol = "tcp"
+
+  [[services.http_checks]]
+    path = "/healthz"
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.Equal(t, "tsc", profile.BuildCommand)
+	assert.Equal(t, "node dist/server.js", profile.StartCommand)
+	assert.Equal(t, 8080, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv, "the app still reads its port from PORT")
+	assert.Equal(t, "/healthz", profile.HealthCheck)
+
+	ev := profile.EvidenceFor("startCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "platform", ev[0].Detector)
+}
+
+func TestPlatform_FlyDockerfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"fly.toml":          &fstest.MapFile{Data: []byte("[build]\n  dockerfile = \"deploy/Dockerfile\"\n")},
+		"Dockerfile":        &fstest.MapFile{Data: []byte("FROM node:20\n")},
+		"deploy/Dockerfile": &fstest.MapFile{Data: []byte("FROM python:3.12\n")},
+	}
+
+	profile, matched, err := (&DockerfileDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, flkr.LangPython, profile.Language)
+}
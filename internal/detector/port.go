@@ -0,0 +1,247 @@
+package detector
+
+import (
+	"io/fs"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"gopkg.in/yaml.v3"
+)
+
+// portRule recognizes where an application listens. The pattern may
+// capture a "port" number, an "env" variable the port is read from, or
+// both (an env var with a fallback). Alternatives may repeat a group name.
+type portRule struct {
+	re   *regexp.Regexp
+	rule string
+}
+
+// portScan describes where to look for the listen port of one ecosystem.
+type portScan struct {
+	// files are paths or "**" patterns of the files to scan.
+	files []string
+
+	// rules are tried in order; the first rule matching any file wins.
+	rules []portRule
+
+	// fallback finds the default used when a rule only matched an env
+	// var, e.g. `port = "8080"` after `os.Getenv("PORT")` in Go. It is
+	// searched in the same file and must capture the port number.
+	fallback *regexp.Regexp
+}
+
+// portMatch is a listen port found in the repository.
+type portMatch struct {
+	port int    // 0 if only an env var was found
+	env  string // env var the port is read from, if any
+	file string
+	line int
+	rule string
+}
+
+// apply sets the matched port on profile, replacing the ecosystem default
+// and its evidence. A port read from an env var keeps the default number
+// unless the source gives a fallback.
+func (m portMatch) apply(profile *flkr.AppProfile, ev recorder) {
+	ev.reset("port")
+	if m.port != 0 {
+		profile.Port = m.port
+	}
+	ev.found("port", profile.Port, m.file, m.line, m.rule)
+	if m.env != "" {
+		profile.PortEnv = m.env
+		ev.found("portEnv", m.env, m.file, m.line, m.rule)
+	}
+}
+
+// findPort scans the files described by s for a listen port.
+func findPort(root fs.FS, s portScan) (portMatch, bool) {
+	type source struct{ name, content string }
+	var sources []source
+	for _, name := range scanFiles(root, s.files) {
+		data, err := readLimit(root, name, sourceMaxSize)
+		if err != nil {
+			continue
+		}
+		sources = append(sources, source{name, string(data)})
+	}
+
+	for _, r := range s.rules {
+		for _, src := range sources {
+			loc := r.re.FindStringSubmatchIndex(src.content)
+			if loc == nil {
+				continue
+			}
+			m := portMatch{
+				file: src.name,
+				line: strings.Count(src.content[:loc[0]], "\n") + 1,
+				rule: r.rule,
+			}
+			m.port, _ = strconv.Atoi(submatch(r.re, src.content, loc, "port"))
+			m.env = submatch(r.re, src.content, loc, "env")
+			if m.port == 0 && m.env != "" && s.fallback != nil {
+				if f := s.fallback.FindStringSubmatch(src.content); f != nil {
+					m.port, _ = strconv.Atoi(f[1])
+				}
+			}
+			if m.port == 0 && m.env == "" {
+				continue
+			}
+			if m.env != "" {
+				m.rule += " from $" + m.env
+			}
+			return m, true
+		}
+	}
+	return portMatch{}, false
+}
+
+// Listen-port scans for each ecosystem.
+var (
+	goPortScan = portScan{
+		files: []string{"**/*.go"},
+		rules: []portRule{
+			{regexp.MustCompile(`(?:ListenAndServe(?:TLS)?|\.Run|\.Start(?:TLS)?|\.Listen(?:TLS)?|net\.Listen)\(\s*(?:"tcp4?6?",\s*)?"[\w.\-\[\]]*:(?P<port>\d{2,5})"`), "listen address in source"},
+			{regexp.MustCompile(`Addr:\s*"[\w.\-\[\]]*:(?P<port>\d{2,5})"`), "server address in source"},
+			{regexp.MustCompile(`flag\.(?:Int|Uint|String)(?:\(|Var\([^,]+,)\s*"(?:port|addr|address|listen|grpc-port|grpc-addr|http-addr|http-port)"\s*,\s*(?:"[\w.\-\[\]]*:(?P<port>\d{2,5})"|(?P<port>\d{2,5}))\s*,`), "listen flag default"},
+			{regexp.MustCompile(`os\.(?:Getenv|LookupEnv)\("(?P<env>[A-Z0-9_]*PORT)"\)`), "listen port read"},
+		},
+		fallback: regexp.MustCompile(`(?i)\bport\w*\s*:?=\s*":?(\d{2,5})"`),
+	}
+
+	nodePortScan = portScan{
+		files: []string{"**/*.js", "**/*.mjs", "**/*.cjs", "**/*.ts"},
+		rules: []portRule{
+			{regexp.MustCompile(`process\.env\.(?P<env>[A-Z0-9_]*PORT)\s*(?:\|\||\?\?)\s*["']?(?P<port>\d{2,5})`), "listen port read"},
+			{regexp.MustCompile(`\.listen\(\s*(?P<port>\d{2,5})\b`), "listen call in source"},
+			{regexp.MustCompile(`process\.env\.(?P<env>[A-Z0-9_]*PORT)\b`), "listen port read"},
+		},
+	}
+
+	pythonPortScan = portScan{
+		files: []string{"gunicorn.conf.py", "**/*.py"},
+		rules: []portRule{
+			{regexp.MustCompile(`os\.(?:environ\.get|getenv)\(\s*["'](?P<env>[A-Z0-9_]*PORT)["']\s*,\s*["']?(?P<port>\d{2,5})`), "listen port read"},
+			{regexp.MustCompile(`(?m)^\s*bind\s*=\s*\[?\s*["'][^"']*:(?P<port>\d{2,5})["']`), "gunicorn bind address"},
+			{regexp.MustCompile(`\.run\([^)]*\bport\s*=\s*(?P<port>\d{2,5})`), "run call in source"},
+			{regexp.MustCompile(`os\.environ\[\s*["'](?P<env>[A-Z0-9_]*PORT)["']\s*\]`), "listen port read"},
+		},
+	}
+
+	rubyPortScan = portScan{
+		files: []string{"config/puma.rb"},
+		rules: []portRule{
+			{regexp.MustCompile(`(?m)^\s*port\s*\(?\s*ENV\.fetch\(\s*["'](?P<env>\w+)["']\s*(?:,\s*|\)\s*\{\s*)(?P<port>\d{2,5})`), "Puma port"},
+			{regexp.MustCompile(`(?m)^\s*port\s*\(?\s*(?P<port>\d{2,5})`), "Puma port"},
+			{regexp.MustCompile(`(?m)^\s*bind\s*\(?\s*["']tcp://[^"']*:(?P<port>\d{2,5})["']`), "Puma bind address"},
+			{regexp.MustCompile(`(?m)^\s*port\s*\(?\s*ENV\[\s*["'](?P<env>\w+)["']\s*\]`), "Puma port"},
+		},
+	}
+
+	elixirPortScan = portScan{
+		files: []string{"config/runtime.exs", "config/prod.exs", "config/config.exs"},
+		rules: []portRule{
+			{regexp.MustCompile(`System\.get_env\("(?P<env>[A-Z0-9_]*PORT)"(?:\s*,\s*"(?P<port>\d{2,5})")?\)(?:\s*\|\|\s*"?(?P<port>\d{2,5}))?`), "endpoint port"},
+			{regexp.MustCompile(`http:\s*\[[^\]]*\bport:\s*(?P<port>\d{2,5})`), "endpoint port"},
+		},
+	}
+
+	rustPortScan = portScan{
+		files: []string{"**/*.rs"},
+		rules: []portRule{
+			{regexp.MustCompile(`\.bind\(\s*\(\s*"[^"]*"\s*,\s*(?P<port>\d{2,5})\s*\)\s*\)`), "bind address in source"},
+			{regexp.MustCompile(`bind\(\s*"[\w.\-\[\]]*:(?P<port>\d{2,5})"`), "bind address in source"},
+			{regexp.MustCompile(`SocketAddr::from\(\(\s*\[[\d,\s]+\]\s*,\s*(?P<port>\d{2,5})\s*\)\)`), "socket address in source"},
+			{regexp.MustCompile(`env::var\("(?P<env>[A-Z0-9_]*PORT)"\)`), "listen port read"},
+		},
+		fallback: regexp.MustCompile(`unwrap_or(?:_else)?\(\s*(?:\|\|\s*)?"?(\d{2,5})`),
+	}
+)
+
+var (
+	springPortRe  = regexp.MustCompile(`(?m)^\s*server\.port\s*[=:]\s*(\S+)`)
+	placeholderRe = regexp.MustCompile(`^\$\{(\w+)(?::(\d+))?\}$`)
+)
+
+// findSpringPort reads server.port from Spring Boot application config.
+// The value may be a placeholder such as ${PORT:8080}.
+func findSpringPort(root fs.FS) (portMatch, bool) {
+	const dir = "src/main/resources/"
+	if content := readFileString(root, dir+"application.properties"); content != "" {
+		if m := springPortRe.FindStringSubmatchIndex(content); m != nil {
+			value := content[m[2]:m[3]]
+			if pm, ok := parseSpringPort(value); ok {
+				pm.file = dir + "application.properties"
+				pm.line = strings.Count(content[:m[0]], "\n") + 1
+				return pm, true
+			}
+		}
+	}
+	for _, name := range []string{"application.yml", "application.yaml"} {
+		content := readFileString(root, dir+name)
+		if content == "" {
+			continue
+		}
+		var doc struct {
+			Server struct {
+				Port string `yaml:"port"`
+			} `yaml:"server"`
+		}
+		if yaml.Unmarshal([]byte(content), &doc) != nil || doc.Server.Port == "" {
+			continue
+		}
+		if pm, ok := parseSpringPort(doc.Server.Port); ok {
+			pm.file = dir + name
+			pm.line = lineOf(content, "port:")
+			return pm, true
+		}
+	}
+	return portMatch{}, false
+}
+
+// parseSpringPort parses a server.port value: a number or a placeholder
+// such as ${PORT:8080}.
+func parseSpringPort(value string) (portMatch, bool) {
+	pm := portMatch{rule: "server.port"}
+	if m := placeholderRe.FindStringSubmatch(value); m != nil {
+		pm.env = m[1]
+		pm.port, _ = strconv.Atoi(m[2])
+		pm.rule += " from $" + pm.env
+		return pm, true
+	}
+	port, err := strconv.Atoi(value)
+	if err != nil || port <= 0 {
+		return pm, false
+	}
+	pm.port = port
+	return pm, true
+}
+
+var (
+	portFlagRe   = regexp.MustCompile(`(?:^|\s)(?:--port|-p)(?:=|\s+)(?:(?P<port>\d{2,5})\b|\$\{?(?P<env>[A-Z0-9_]*PORT)(?::-(?P<port>\d{2,5}))?\}?)`)
+	bindFlagRe   = regexp.MustCompile(`(?:^|\s)(?:--bind|-b)(?:=|\s+)["']?(?:\w+://)?[\w.\-\[\]]*:(?:(?P<port>\d{2,5})\b|\$\{?(?P<env>[A-Z0-9_]*PORT)(?::-(?P<port>\d{2,5}))?\}?)`)
+	portAssignRe = regexp.MustCompile(`(?:^|\s)[A-Z0-9_]*PORT=(?P<port>\d{2,5})\s`)
+)
+
+// commandPort extracts the listen port from a start command's --port, -p,
+// --bind or -b flags, or a PORT=N assignment in front of it.
+func commandPort(cmd string) (portMatch, bool) {
+	for _, re := range []*regexp.Regexp{portFlagRe, bindFlagRe, portAssignRe} {
+		loc := re.FindStringSubmatchIndex(cmd)
+		if loc == nil {
+			continue
+		}
+		pm := portMatch{rule: "port flag in start command"}
+		if re == portAssignRe {
+			pm.rule = "port set in start command"
+		}
+		pm.port, _ = strconv.Atoi(submatch(re, cmd, loc, "port"))
+		if pm.env = submatch(re, cmd, loc, "env"); pm.env != "" {
+			pm.rule += " from $" + pm.env
+		}
+		return pm, true
+	}
+	return portMatch{}, false
+}
@@ -0,0 +1,56 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/internal/repoindex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandPort(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		port int
+		env  string
+		ok   bool
+	}{
+		{"next start -p 4000", 4000, "", true},
+		{"uvicorn main:app --host 0.0.0.0 --port 8001", 8001, "", true},
+		{"uvicorn main:app --port=$PORT", 0, "PORT", true},
+		{"gunicorn app:app -b 0.0.0.0:${PORT:-5001}", 5001, "PORT", true},
+		{"bundle exec puma --bind tcp://0.0.0.0:9292", 9292, "", true},
+		{"PORT=3100 node server.js", 3100, "", true},
+		{"node server.js", 0, "", false},
+		{"npm run build -- --prod", 0, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			m, ok := commandPort(tt.cmd)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.port, m.port)
+			assert.Equal(t, tt.env, m.env)
+		})
+	}
+}
+
+func TestFindPort_Index(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":          &fstest.MapFile{Data: []byte("gen/\n")},
+		"gen/server.go":       &fstest.MapFile{Data: []byte(`http.ListenAndServe(":7777", nil)`)},
+		"cmd/api/main.go":     &fstest.MapFile{Data: []byte(`srv := &http.Server{Addr: ":9090"}`)},
+		"internal/x/x.go":     &fstest.MapFile{Data: []byte("package x\n")},
+		"testdata/fixture.go": &fstest.MapFile{Data: []byte(`http.ListenAndServe(":1111", nil)`)},
+	}
+	idx, err := repoindex.Build(context.Background(), fsys, repoindex.Options{})
+	require.NoError(t, err)
+
+	m, ok := findPort(idx, goPortScan)
+	require.True(t, ok)
+	assert.Equal(t, 9090, m.port)
+	assert.Equal(t, "cmd/api/main.go", m.file)
+	assert.Equal(t, 1, m.line)
+	assert.Equal(t, "server address in source", m.rule)
+}
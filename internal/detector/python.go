@@ -2,6 +2,7 @@ package detector
 
 import (
 	"context"
+	"fmt"
 	"io/fs"
 	"strings"
 
@@ -27,10 +28,17 @@ func (d *PythonDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfi
 
 	profile := &flkr.AppProfile{
 		Language:   flkr.LangPython,
-		Confidence: 0.7,
 		DetectedBy: d.Name(),
 		Port:       8000,
 	}
+	ev := newRecorder(profile, d.Name())
+	for _, f := range []string{"pyproject.toml", "requirements.txt", "Pipfile", "setup.py"} {
+		if fileExists(root, f) {
+			ev.found("language", profile.Language, f, 0, f+" present")
+			break
+		}
+	}
+	ev.assumed("port", profile.Port, "Python web apps listen on 8000 by default")
 
 	// Detect package manager.
 	switch {
@@ -38,85 +46,112 @@ func (d *PythonDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfi
 		profile.PackageManager = flkr.PkgUV
 		profile.HasLockfile = true
 		profile.LockfileType = "uv"
+		ev.found("packageManager", profile.PackageManager, "uv.lock", 0, "uv lockfile present")
 	case fileExists(root, "poetry.lock"):
 		profile.PackageManager = flkr.PkgPoetry
 		profile.HasLockfile = true
 		profile.LockfileType = "poetry"
+		ev.found("packageManager", profile.PackageManager, "poetry.lock", 0, "poetry lockfile present")
 	case hasPipfile:
 		profile.PackageManager = flkr.PkgPipenv
+		ev.found("packageManager", profile.PackageManager, "Pipfile", 0, "Pipfile present")
 		if fileExists(root, "Pipfile.lock") {
 			profile.HasLockfile = true
 			profile.LockfileType = "pipenv"
 		}
 	default:
 		profile.PackageManager = flkr.PkgPip
+		ev.assumed("packageManager", profile.PackageManager, "no lockfile; pip is the default")
 	}
 
 	// Parse pyproject.toml for framework detection.
 	if hasPyproject {
 		pyproj, err := parser.ParsePyprojectTOML(root, "pyproject.toml")
 		if err == nil {
-			d.detectFramework(pyproj, profile)
+			raw := readFileString(root, "pyproject.toml")
+			d.detectFramework(pyproj, profile, ev, raw)
 			if pyproj.Project.Version != "" {
 				profile.AppVersion = pyproj.Project.Version
+				ev.found("appVersion", profile.AppVersion, "pyproject.toml", lineOf(raw, "version"), "project.version")
 			}
 			if pyproj.Project.RequiresPython != "" {
 				profile.Version = cleanVersion(pyproj.Project.RequiresPython)
+				profile.VersionConstraint = strings.TrimSpace(pyproj.Project.RequiresPython)
+				ev.found("version", profile.Version, "pyproject.toml", lineOf(raw, "requires-python"), "project.requires-python "+pyproj.Project.RequiresPython)
 			}
 		}
 	}
 
 	// Fallback: check requirements.txt for framework hints.
 	if profile.Framework == "" && hasRequirements {
-		d.detectFrameworkFromRequirements(root, profile)
+		d.detectFrameworkFromRequirements(root, profile, ev)
+	}
+
+	if profile.Framework == flkr.FrameworkFlask {
+		profile.Port = 5000
+		ev.assumed("port", profile.Port, "flask run listens on 5000 by default")
+	}
+	if m, ok := findPort(root, pythonPortScan); ok {
+		m.apply(profile, ev)
 	}
 
 	// Set start commands based on framework.
 	switch profile.Framework {
 	case flkr.FrameworkDjango:
-		profile.StartCommand = "python manage.py runserver 0.0.0.0:8000"
+		profile.StartCommand = fmt.Sprintf("python manage.py runserver 0.0.0.0:%d", profile.Port)
 	case flkr.FrameworkFlask:
 		profile.StartCommand = "flask run --host=0.0.0.0"
-		profile.Port = 5000
+		if profile.Port != 5000 {
+			profile.StartCommand += fmt.Sprintf(" --port %d", profile.Port)
+		}
 	case flkr.FrameworkFastAPI:
-		profile.StartCommand = "uvicorn main:app --host 0.0.0.0 --port 8000"
+		profile.StartCommand = fmt.Sprintf("uvicorn main:app --host 0.0.0.0 --port %d", profile.Port)
+	}
+	if profile.StartCommand != "" {
+		ev.assumed("startCommand", profile.StartCommand, "conventional "+string(profile.Framework)+" start command")
 	}
 
+	pinVersion(root, profile, ev)
+
+	addEnvVars(profile, ev, findEnvVars(root, pythonEnvScan))
+
 	return profile, true, nil
 }
 
-func (d *PythonDetector) detectFramework(pyproj *parser.PyprojectTOML, profile *flkr.AppProfile) {
+func (d *PythonDetector) detectFramework(pyproj *parser.PyprojectTOML, profile *flkr.AppProfile, ev recorder, raw string) {
+	var dep string
 	switch {
 	case pyproj.HasDep("django"):
+		dep = "django"
 		profile.Framework = flkr.FrameworkDjango
-		profile.Confidence = 0.9
 	case pyproj.HasDep("flask"):
+		dep = "flask"
 		profile.Framework = flkr.FrameworkFlask
-		profile.Confidence = 0.85
 	case pyproj.HasDep("fastapi"):
+		dep = "fastapi"
 		profile.Framework = flkr.FrameworkFastAPI
-		profile.Confidence = 0.9
+	default:
+		return
 	}
+	ev.found("framework", profile.Framework, "pyproject.toml", lineOf(raw, dep), "depends on "+dep)
 }
 
-func (d *PythonDetector) detectFrameworkFromRequirements(root fs.FS, profile *flkr.AppProfile) {
+func (d *PythonDetector) detectFrameworkFromRequirements(root fs.FS, profile *flkr.AppProfile, ev recorder) {
 	content := readFileString(root, "requirements.txt")
 	lines := strings.Split(strings.ToLower(content), "\n")
-	for _, line := range lines {
+	for i, line := range lines {
 		line = strings.TrimSpace(line)
 		switch {
 		case strings.HasPrefix(line, "django"):
 			profile.Framework = flkr.FrameworkDjango
-			profile.Confidence = 0.85
-			return
 		case strings.HasPrefix(line, "flask"):
 			profile.Framework = flkr.FrameworkFlask
-			profile.Confidence = 0.8
-			return
 		case strings.HasPrefix(line, "fastapi"):
 			profile.Framework = flkr.FrameworkFastAPI
-			profile.Confidence = 0.85
-			return
+		default:
+			continue
 		}
+		ev.found("framework", profile.Framework, "requirements.txt", i+1, "requires "+line)
+		return
 	}
 }
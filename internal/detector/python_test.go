@@ -55,3 +55,26 @@ func TestPythonDetector_NoMatch(t *testing.T) {
 	require.NoError(t, err)
 	assert.False(t, matched)
 }
+
+func TestPythonDetector_UvicornPort(t *testing.T) {
+	fsys := fstest.MapFS{
+		"requirements.txt": &fstest.MapFile{Data: []byte("fastapi\nuvicorn\n")},
+		"app/main.py": &fstest.MapFile{Data: []byte(`import os
+import uvicorn
+
+if __name__ == "__main__":
+    uvicorn.run(app, host="0.0.0.0", port=int(os.environ.get("PORT", "8001")))
+`)},
+	}
+
+	profile, matched, err := (&PythonDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, 8001, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	assert.Equal(t, "uvicorn main:app --host 0.0.0.0 --port 8001", profile.StartCommand)
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "app/main.py", ev[0].File)
+	assert.Equal(t, 5, ev[0].Line)
+}
@@ -2,90 +2,402 @@ package detector
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"io/fs"
 	"os"
+	"runtime"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/internal/repoindex"
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
 // Registry manages all available detectors and orchestrates detection.
 type Registry struct {
-	detectors []Detector
+	detectors   []Detector
+	log         io.Writer
+	logMu       sync.Mutex
+	parallelism int
+	timeout     time.Duration
+	languages   map[string]flkr.Language
 }
 
-// NewRegistry creates a registry with all built-in detectors.
+// NewRegistry creates a registry with all built-in detectors, adjusted by
+// any detectors registered through the public flkr plugin API.
 func NewRegistry() *Registry {
 	return &Registry{
-		detectors: []Detector{
-			&NodeDetector{},
-			&PythonDetector{},
-			&GoDetector{},
-			&RustDetector{},
-			&RubyDetector{},
-			&ElixirDetector{},
-			&PHPDetector{},
-			&JavaDetector{},
-		},
-	}
-}
-
-// DetectAll runs every detector and returns all matching profiles, sorted
-// by confidence (highest first).
-func (r *Registry) DetectAll(ctx context.Context, root fs.FS) ([]*flkr.AppProfile, error) {
+		detectors:   flkr.ResolveDetectors(builtinDetectors()),
+		parallelism: runtime.GOMAXPROCS(0),
+		timeout:     DefaultDetectorTimeout,
+	}
+}
+
+// builtinDetectors returns a fresh instance of every built-in detector.
+func builtinDetectors() []Detector {
+	return []Detector{
+		&NodeDetector{},
+		&PythonDetector{},
+		&GoDetector{},
+		&RustDetector{},
+		&RubyDetector{},
+		&ElixirDetector{},
+		&PHPDetector{},
+		&JavaDetector{},
+	}
+}
+
+// SetLogOutput enables verbose logging of each detector run to w.
+// A nil writer disables logging.
+func (r *Registry) SetLogOutput(w io.Writer) {
+	r.log = w
+}
+
+// SetParallelism limits how many detectors run at once. Values below one
+// run detectors one at a time.
+func (r *Registry) SetParallelism(n int) {
+	r.parallelism = n
+}
+
+// SetDetectorTimeout bounds how long each detector may run. Zero disables
+// the limit.
+func (r *Registry) SetDetectorTimeout(d time.Duration) {
+	r.timeout = d
+}
+
+// SetLanguages selects the primary language of apps by their path relative
+// to the repository root ("." for the root), overriding both confidence
+// and any language pinned in flkr.toml.
+func (r *Registry) SetLanguages(languages map[string]flkr.Language) {
+	r.languages = languages
+}
+
+func (r *Registry) logf(format string, args ...any) {
+	if r.log != nil {
+		r.logMu.Lock()
+		defer r.logMu.Unlock()
+		fmt.Fprintf(r.log, format+"\n", args...)
+	}
+}
+
+// DetectAll runs every detector concurrently and returns all matching
+// profiles, sorted by confidence (highest first). Profiles that carry
+// evidence are scored from it with AppProfile.Score. A detector that fails,
+// panics or times out does not abort the run; it is reported in the
+// returned warnings instead. An error is only returned when ctx is done.
+func (r *Registry) DetectAll(ctx context.Context, root fs.FS) ([]*flkr.AppProfile, []string, error) {
+	root, err := r.indexed(ctx, root)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Sort detectors by priority.
 	sorted := make([]Detector, len(r.detectors))
 	copy(sorted, r.detectors)
-	sort.Slice(sorted, func(i, j int) bool {
+	sort.SliceStable(sorted, func(i, j int) bool {
 		return sorted[i].Priority() < sorted[j].Priority()
 	})
 
+	results := r.runAll(ctx, sorted, root)
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
 	var profiles []*flkr.AppProfile
-	for _, d := range sorted {
-		profile, matched, err := d.Detect(ctx, root)
-		if err != nil {
-			return nil, err
-		}
-		if matched && profile != nil {
-			profiles = append(profiles, profile)
+	var warnings []string
+	for i, res := range results {
+		d := sorted[i]
+		switch {
+		case res.err != nil:
+			r.logf("detector %s: %v", d.Name(), res.err)
+			warnings = append(warnings, fmt.Sprintf("detector %s: %v", d.Name(), res.err))
+		case res.matched && res.profile != nil:
+			if len(res.profile.Evidence) > 0 {
+				res.profile.Confidence = res.profile.Score()
+			}
+			r.logf("detector %s: matched %s (confidence %.2f)", d.Name(), res.profile.Language, res.profile.Confidence)
+			profiles = append(profiles, res.profile)
+		default:
+			r.logf("detector %s: no match", d.Name())
 		}
 	}
 
-	// Sort by confidence descending.
-	sort.Slice(profiles, func(i, j int) bool {
+	// Sort by confidence descending, keeping priority order for ties.
+	sort.SliceStable(profiles, func(i, j int) bool {
 		return profiles[i].Confidence > profiles[j].Confidence
 	})
 
-	return profiles, nil
+	return profiles, warnings, nil
+}
+
+// indexed returns root as a flkr.Index, building one in a single walk
+// unless root already is one. Detectors then share cached reads and never
+// see ignored files or the contents of dependency directories.
+func (r *Registry) indexed(ctx context.Context, root fs.FS) (fs.FS, error) {
+	if _, ok := root.(flkr.Index); ok {
+		return root, nil
+	}
+	idx, err := repoindex.Build(ctx, root, repoindex.Options{})
+	if err != nil {
+		return nil, err
+	}
+	r.logf("indexed %d files", len(idx.Files()))
+	return idx, nil
+}
+
+// runAll runs detectors with bounded parallelism and returns their results
+// in the same order.
+func (r *Registry) runAll(ctx context.Context, detectors []Detector, root fs.FS) []detectorResult {
+	results := make([]detectorResult, len(detectors))
+	sem := make(chan struct{}, max(r.parallelism, 1))
+
+	var wg sync.WaitGroup
+	for i, d := range detectors {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = detectorResult{err: ctx.Err()}
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = runDetector(ctx, d, root, r.timeout)
+		}()
+	}
+	wg.Wait()
+	return results
 }
 
 // DetectBest runs all detectors and returns the highest-confidence match,
-// enriched with cross-cutting data.
+// enriched with the output of language-less detectors and cross-cutting data.
+// A Dockerfile credits the candidate matching its base image and fills the
+// fields no other source set. Platform configs such as fly.toml override
+// what was inferred from the code.
+// A flkr.toml at the root is applied last; a language chosen with
+// SetLanguages or pinned there selects the matching candidate instead of
+// the highest-confidence one. When other candidates score within
+// flkr.AmbiguityMargin of the best, they are listed in its Alternatives.
+// Finally the version constraint is resolved to a nixpkgs toolchain with
+// ResolveToolchain. Local Go modules outside root are listed in Problems.
 func (r *Registry) DetectBest(ctx context.Context, root fs.FS) (*flkr.AppProfile, error) {
-	profiles, err := r.DetectAll(ctx, root)
+	profile, err := r.detectBest(ctx, root, r.languages["."], nil)
+	if err != nil || profile == nil {
+		return nil, err
+	}
+	placeLocalModules(profile, ".")
+	return profile, nil
+}
+
+// detectBest implements DetectBest for an app that wants the given
+// language. depMap extends the system dependency mapping, on top of any
+// mapping in the app's own flkr.toml.
+func (r *Registry) detectBest(ctx context.Context, root fs.FS, want flkr.Language, depMap map[string]map[string]parser.SystemDepRule) (*flkr.AppProfile, error) {
+	root, err := r.indexed(ctx, root)
 	if err != nil {
 		return nil, err
 	}
-	if len(profiles) == 0 {
-		return nil, nil
+
+	cfg, raw, err := loadConfig(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+	pinned := want == "" && cfg != nil && cfg.Language != ""
+	if pinned {
+		want = flkr.Language(cfg.Language)
 	}
 
-	best := profiles[0]
+	profiles, warnings, err := r.DetectAll(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	// A Dockerfile breaks ties between candidates and later fills gaps.
+	deps := systemDepsFor(depMap, cfg.SystemDepRules())
+	matchers, err := compileDepMatchers(deps)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+	docker, err := r.runPost(ctx, &DockerfileDetector{deps: deps}, root, &warnings)
+	if err != nil {
+		return nil, err
+	}
+	var runtime flkr.Language
+	if docker != nil {
+		breakTies(profiles, docker)
+		runtime = docker.Language
+	}
+
+	best, enrichments := selectProfile(profiles, want, runtime)
+	if best == nil && want != "" && !pinned {
+		warnings = append(warnings, fmt.Sprintf("language %s was chosen but not detected", want))
+		best, enrichments = selectProfile(profiles, "", runtime)
+	}
+	if best == nil {
+		if !pinned {
+			if len(warnings) > 0 {
+				return nil, fmt.Errorf("no application stack detected: %s", strings.Join(warnings, "; "))
+			}
+			return nil, nil
+		}
+		best = &flkr.AppProfile{DetectedBy: "config"}
+	}
+	if best.Ambiguous() {
+		candidates := make([]string, 0, len(best.Alternatives)+1)
+		for _, c := range best.Candidates() {
+			candidates = append(candidates, c.String())
+		}
+		last := len(candidates) - 1
+		warnings = append(warnings, fmt.Sprintf("detection is ambiguous between %s and %s; choose a language or pin it in %s",
+			strings.Join(candidates[:last], ", "), candidates[last], ConfigFile))
+	}
+	r.logf("selected %s profile from detector %s", best.Language, best.DetectedBy)
+
+	for _, e := range enrichments {
+		best.Merge(e)
+	}
+	warnings = append(warnings, r.addBuildStages(ctx, root, best)...)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
 	// Enrich with cross-cutting data.
-	cc := &CrosscuttingDetector{}
-	enrichment, matched, err := cc.Detect(ctx, root)
+	cc, err := r.runPost(ctx, &CrosscuttingDetector{}, root, &warnings)
 	if err != nil {
 		return nil, err
 	}
-	if matched {
-		best.Merge(enrichment)
+	if cc != nil {
+		r.logf("detector crosscutting: enriched profile")
+		best.Merge(cc)
+	}
+
+	// Platform configs state what runs in production, so they override
+	// what was inferred from the code.
+	platform, err := r.runPost(ctx, &PlatformDetector{deps: deps}, root, &warnings)
+	if err != nil {
+		return nil, err
+	}
+	if platform != nil {
+		r.logf("detector platform: enriched profile")
+		// A platform that only routes to a port keeps the variable the
+		// app reads it from.
+		if platform.Port != 0 && platform.PortEnv == "" && best.PortEnv != "" {
+			platform.PortEnv = best.PortEnv
+			platform.Evidence = append(platform.Evidence, best.EvidenceFor("portEnv")...)
+		}
+		best.Merge(platform)
+	}
+	if docker != nil {
+		r.logf("detector dockerfile: filling gaps")
+		fillGaps(best, docker)
+	}
+	addSystemDeps(root, best, matchers)
+	addCgo(root, best, deps)
+	best.Warnings = mergeWarnings(best.Warnings, warnings)
+
+	if cfg != nil {
+		r.logf("applying %s", ConfigFile)
+		applyOverrides(best, cfg.ProfileOverrides, raw, "")
 	}
+	connectServices(best)
+	ResolveToolchain(best)
 
 	return best, nil
 }
 
+// runPost runs a detector used as a post-processing step. A failure is
+// recorded in warnings; the profile is nil unless the detector matched.
+func (r *Registry) runPost(ctx context.Context, d Detector, root fs.FS, warnings *[]string) (*flkr.AppProfile, error) {
+	res := runDetector(ctx, d, root, r.timeout)
+	switch {
+	case ctx.Err() != nil:
+		return nil, ctx.Err()
+	case res.err != nil:
+		r.logf("detector %s: %v", d.Name(), res.err)
+		*warnings = append(*warnings, fmt.Sprintf("detector %s: %v", d.Name(), res.err))
+	case res.matched:
+		return res.profile, nil
+	}
+	return nil, nil
+}
+
+// selectProfile picks the primary profile among detector results sorted by
+// confidence and returns it with the language-less enrichments. With want
+// set, the best candidate of that language is selected. Otherwise a Node
+// project that only builds frontend assets gives way to a backend
+// candidate, and candidates within flkr.AmbiguityMargin of the selected
+// one are recorded as its Alternatives. When the selected candidate runs
+// on runtime, the language of the Dockerfile, the others aren't
+// alternatives: the Dockerfile settles the choice.
+func selectProfile(profiles []*flkr.AppProfile, want, runtime flkr.Language) (*flkr.AppProfile, []*flkr.AppProfile) {
+	var candidates, enrichments []*flkr.AppProfile
+	for _, p := range profiles {
+		if p.Language == "" {
+			enrichments = append(enrichments, p)
+		} else {
+			candidates = append(candidates, p)
+		}
+	}
+
+	if want != "" {
+		for _, p := range candidates {
+			if p.Language == want {
+				return p, enrichments
+			}
+		}
+		return nil, enrichments
+	}
+
+	// A frontend alongside a backend is built as a stage of it.
+	pool := slices.DeleteFunc(slices.Clone(candidates), isFrontendBuild)
+	if len(pool) == 0 {
+		pool = candidates
+	}
+	if len(pool) == 0 {
+		return nil, enrichments
+	}
+
+	best := pool[0]
+	best.Alternatives = nil
+	if best.Language == runtime {
+		return best, enrichments
+	}
+	for _, p := range pool[1:] {
+		if p.Language != best.Language && best.Confidence-p.Confidence < flkr.AmbiguityMargin {
+			best.Alternatives = append(best.Alternatives, flkr.Candidate{
+				Language:   p.Language,
+				Framework:  p.Framework,
+				Confidence: p.Confidence,
+				DetectedBy: p.DetectedBy,
+			})
+		}
+	}
+	return best, enrichments
+}
+
+// mergeWarnings appends warnings not already present in list.
+func mergeWarnings(list, warnings []string) []string {
+	for _, w := range warnings {
+		if !slices.Contains(list, w) {
+			list = append(list, w)
+		}
+	}
+	return list
+}
+
 // DetectFromPath is a convenience that opens an OS directory and runs DetectBest.
 func (r *Registry) DetectFromPath(ctx context.Context, path string) (*flkr.AppProfile, error) {
 	return r.DetectBest(ctx, os.DirFS(path))
 }
+
+// DetectWorkspaceFromPath is a convenience that opens an OS directory and
+// runs DetectWorkspace.
+func (r *Registry) DetectWorkspaceFromPath(ctx context.Context, path string) ([]*flkr.AppProfile, error) {
+	return r.DetectWorkspace(ctx, os.DirFS(path))
+}
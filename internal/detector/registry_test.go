@@ -2,8 +2,11 @@ package detector
 
 import (
 	"context"
+	"errors"
+	"io/fs"
 	"testing"
 	"testing/fstest"
+	"time"
 
 	"github.com/narvanalabs/flkr/pkg/flkr"
 	"github.com/stretchr/testify/assert"
@@ -18,7 +21,7 @@ func TestRegistry_DetectAll_Node(t *testing.T) {
 	}
 
 	reg := NewRegistry()
-	profiles, err := reg.DetectAll(context.Background(), fsys)
+	profiles, _, err := reg.DetectAll(context.Background(), fsys)
 	require.NoError(t, err)
 	require.Len(t, profiles, 1)
 	assert.Equal(t, flkr.LangNode, profiles[0].Language)
@@ -35,7 +38,7 @@ func TestRegistry_DetectAll_MultiLanguage(t *testing.T) {
 	}
 
 	reg := NewRegistry()
-	profiles, err := reg.DetectAll(context.Background(), fsys)
+	profiles, _, err := reg.DetectAll(context.Background(), fsys)
 	require.NoError(t, err)
 	assert.Len(t, profiles, 2)
 }
@@ -61,12 +64,247 @@ func TestRegistry_DetectBest_WithCrosscutting(t *testing.T) {
 	assert.Contains(t, profile.EnvVars, "DATABASE_URL")
 	assert.Contains(t, profile.EnvVars, "SECRET_KEY")
 	assert.Equal(t, "npm start", profile.StartCommand)
+
+	// The Procfile replaced the start command, so only its evidence remains.
+	ev := profile.EvidenceFor("startCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "crosscutting", ev[0].Detector)
+	assert.Equal(t, 2, len(profile.EvidenceFor("envVars")))
 }
 
 func TestRegistry_DetectAll_Empty(t *testing.T) {
 	fsys := fstest.MapFS{}
 	reg := NewRegistry()
-	profiles, err := reg.DetectAll(context.Background(), fsys)
+	profiles, _, err := reg.DetectAll(context.Background(), fsys)
 	require.NoError(t, err)
 	assert.Empty(t, profiles)
 }
+
+type acmeDetector struct{}
+
+func (d *acmeDetector) Name() string  { return "acme" }
+func (d *acmeDetector) Priority() int { return 90 }
+func (d *acmeDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error) {
+	if !fileExists(root, "acme.yaml") {
+		return nil, false, nil
+	}
+	return &flkr.AppProfile{Framework: "acme", StartCommand: "./acme-server"}, true, nil
+}
+
+func TestRegistry_RegisteredEnrichment(t *testing.T) {
+	t.Cleanup(flkr.ResetDetectors)
+	flkr.RegisterDetector(&acmeDetector{})
+	flkr.DisableDetector("node")
+
+	fsys := fstest.MapFS{
+		"go.mod":       &fstest.MapFile{Data: []byte("module myapp\n\ngo 1.22.0\n")},
+		"package.json": &fstest.MapFile{Data: []byte(`{"name": "app"}`)},
+		"acme.yaml":    &fstest.MapFile{Data: []byte("service: api\n")},
+	}
+
+	reg := NewRegistry()
+	profiles, _, err := reg.DetectAll(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Len(t, profiles, 2)
+
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.Equal(t, flkr.Framework("acme"), profile.Framework)
+	assert.Equal(t, "./acme-server", profile.StartCommand)
+}
+
+type stubDetector struct {
+	name   string
+	detect func(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error)
+}
+
+func (d *stubDetector) Name() string  { return d.name }
+func (d *stubDetector) Priority() int { return 95 }
+func (d *stubDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile, bool, error) {
+	return d.detect(ctx, root)
+}
+
+func TestRegistry_DetectAll_FailingDetectors(t *testing.T) {
+	t.Cleanup(flkr.ResetDetectors)
+	flkr.RegisterDetector(&stubDetector{name: "broken", detect: func(context.Context, fs.FS) (*flkr.AppProfile, bool, error) {
+		return nil, false, errors.New("malformed manifest")
+	}})
+	flkr.RegisterDetector(&stubDetector{name: "panicky", detect: func(context.Context, fs.FS) (*flkr.AppProfile, bool, error) {
+		panic("boom")
+	}})
+	flkr.RegisterDetector(&stubDetector{name: "stuck", detect: func(ctx context.Context, _ fs.FS) (*flkr.AppProfile, bool, error) {
+		<-ctx.Done()
+		return nil, false, ctx.Err()
+	}})
+
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module myapp\n\ngo 1.24.0\n")},
+	}
+
+	reg := NewRegistry()
+	reg.SetDetectorTimeout(50 * time.Millisecond)
+	profiles, warnings, err := reg.DetectAll(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, flkr.LangGo, profiles[0].Language)
+	assert.Equal(t, []string{
+		"detector broken: malformed manifest",
+		"detector panicky: panic: boom",
+		"detector stuck: timed out after 50ms",
+	}, warnings)
+
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Len(t, profile.Warnings, 3)
+}
+
+func TestRegistry_DetectAll_AbandonsUncooperativeDetector(t *testing.T) {
+	t.Cleanup(flkr.ResetDetectors)
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	flkr.RegisterDetector(&stubDetector{name: "deaf", detect: func(context.Context, fs.FS) (*flkr.AppProfile, bool, error) {
+		<-release
+		return nil, false, nil
+	}})
+
+	reg := NewRegistry()
+	reg.SetDetectorTimeout(20 * time.Millisecond)
+	_, warnings, err := reg.DetectAll(context.Background(), fstest.MapFS{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"detector deaf: timed out after 20ms"}, warnings)
+}
+
+func TestRegistry_DetectAll_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module myapp\n")},
+	}
+	reg := NewRegistry()
+	_, _, err := reg.DetectAll(ctx, fsys)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	_, err = reg.DetectBest(ctx, fsys)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRegistry_DetectBest_AllDetectorsFailed(t *testing.T) {
+	t.Cleanup(flkr.ResetDetectors)
+	flkr.RegisterDetector(&stubDetector{name: "broken", detect: func(context.Context, fs.FS) (*flkr.AppProfile, bool, error) {
+		return nil, false, errors.New("unreadable")
+	}})
+
+	reg := NewRegistry()
+	_, err := reg.DetectBest(context.Background(), fstest.MapFS{})
+	assert.ErrorContains(t, err, "detector broken: unreadable")
+}
+
+func TestRegistry_DetectAll_Sequential(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"dependencies": {"vite": "5.0.0"}}`)},
+		"go.mod":       &fstest.MapFile{Data: []byte("module myapp\n\ngo 1.22.0\n")},
+	}
+
+	parallel, _, err := NewRegistry().DetectAll(context.Background(), fsys)
+	require.NoError(t, err)
+
+	reg := NewRegistry()
+	reg.SetParallelism(1)
+	sequential, _, err := reg.DetectAll(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, parallel, sequential)
+}
+
+func TestWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	fsys := withContext(ctx, fstest.MapFS{
+		"a/b.txt": &fstest.MapFile{Data: []byte("hi")},
+	})
+
+	sub, err := fs.Sub(fsys, "a")
+	require.NoError(t, err)
+	assert.True(t, fileExists(sub, "b.txt"))
+
+	cancel()
+	_, err = fs.ReadFile(sub, "b.txt")
+	assert.ErrorIs(t, err, context.Canceled)
+	err = fs.WalkDir(fsys, ".", func(_ string, _ fs.DirEntry, err error) error { return err })
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestRegistry_DetectBest_HonorsGitignore(t *testing.T) {
+	fsys := fstest.MapFS{
+		".gitignore":          &fstest.MapFile{Data: []byte("/cmd/scratch/\n")},
+		"go.mod":              &fstest.MapFile{Data: []byte("module example.com/myapp\n")},
+		"cmd/scratch/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"cmd/server/main.go":  &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "./server", profile.StartCommand)
+}
+
+func TestRegistry_DetectBest_Ambiguous(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":            &fstest.MapFile{Data: []byte("module myapp\n")},
+		"package.json":      &fstest.MapFile{Data: []byte(`{"scripts": {"start": "node server.js"}}`)},
+		"package-lock.json": &fstest.MapFile{Data: []byte("{}")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.True(t, profile.Ambiguous())
+	require.Len(t, profile.Alternatives, 1)
+	assert.Equal(t, flkr.LangGo, profile.Alternatives[0].Language)
+	assert.Contains(t, profile.Warnings[0], "ambiguous between node (65%) and go (65%)")
+
+	reg.SetLanguages(map[string]flkr.Language{".": flkr.LangGo})
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangGo, profile.Language)
+	assert.False(t, profile.Ambiguous())
+	assert.Empty(t, profile.Warnings)
+}
+
+func TestRegistry_DetectBest_StrayPackageJSON(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pom.xml": &fstest.MapFile{
+			Data: []byte(`<project><parent><groupId>org.springframework.boot</groupId></parent></project>`),
+		},
+		"package.json": &fstest.MapFile{Data: []byte(`{"devDependencies": {"prettier": "3.0.0"}}`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangJava, profile.Language)
+	assert.False(t, profile.Ambiguous())
+}
+
+func TestRegistry_DetectWorkspace_ChosenLanguage(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json":          &fstest.MapFile{Data: []byte(`{"workspaces": ["apps/*"]}`)},
+		"apps/api/package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"start": "node server.js"}}`)},
+		"apps/api/go.mod":       &fstest.MapFile{Data: []byte("module api\n")},
+		"apps/api/main.go":      &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	reg := NewRegistry()
+	reg.SetLanguages(map[string]flkr.Language{"apps/api": flkr.LangGo})
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, flkr.LangGo, profiles[0].Language)
+}
@@ -3,11 +3,17 @@ package detector
 import (
 	"context"
 	"io/fs"
+	"regexp"
 	"strings"
 
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
+var (
+	gemfileRubyRe = regexp.MustCompile(`(?m)^[ \t]*ruby[ \t]*\(?[ \t]*((?:["'][^"'\n]+["'][ \t]*,?[ \t]*)+)`)
+	quotedRe      = regexp.MustCompile(`["']([^"']+)["']`)
+)
+
 // RubyDetector detects Ruby applications.
 type RubyDetector struct{}
 
@@ -22,35 +28,59 @@ func (d *RubyDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 	profile := &flkr.AppProfile{
 		Language:       flkr.LangRuby,
 		PackageManager: flkr.PkgBundler,
-		Confidence:     0.7,
 		DetectedBy:     d.Name(),
 		Port:           3000,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "Gemfile", 0, "Gemfile present")
+	ev.found("packageManager", profile.PackageManager, "Gemfile", 0, "Gemfile present")
+	ev.assumed("port", profile.Port, "Ruby web servers listen on 3000 by default")
 
 	if fileExists(root, "Gemfile.lock") {
 		profile.HasLockfile = true
 		profile.LockfileType = "bundler"
+		ev.found("lockfileType", profile.LockfileType, "Gemfile.lock", 0, "Gemfile.lock present")
 	}
 
-	// Read .ruby-version if it exists.
-	if ver := readFileString(root, ".ruby-version"); ver != "" {
-		profile.Version = strings.TrimSpace(ver)
+	// The Gemfile may require a Ruby version; version manager pins such
+	// as .ruby-version take precedence.
+	gemfile := readFileString(root, "Gemfile")
+	if m := gemfileRubyRe.FindStringSubmatchIndex(gemfile); m != nil {
+		var reqs []string
+		for _, q := range quotedRe.FindAllStringSubmatch(gemfile[m[2]:m[3]], -1) {
+			reqs = append(reqs, q[1])
+		}
+		profile.VersionConstraint = strings.Join(reqs, " ")
+		profile.Version = cleanVersion(profile.VersionConstraint)
+		ev.found("version", profile.Version, "Gemfile", strings.Count(gemfile[:m[0]], "\n")+1, "Gemfile ruby requirement "+profile.VersionConstraint)
 	}
+	pinVersion(root, profile, ev)
 
 	// Detect Rails.
-	gemfile := readFileString(root, "Gemfile")
 	if strings.Contains(gemfile, "'rails'") || strings.Contains(gemfile, "\"rails\"") {
 		profile.Framework = flkr.FrameworkRails
-		profile.Confidence = 0.9
 		profile.BuildCommand = "bundle exec rake assets:precompile"
 		profile.StartCommand = "bundle exec rails server -b 0.0.0.0"
+		line := lineOf(gemfile, "'rails'")
+		if line == 0 {
+			line = lineOf(gemfile, "\"rails\"")
+		}
+		ev.found("framework", profile.Framework, "Gemfile", line, "requires the rails gem")
+		ev.assumed("buildCommand", profile.BuildCommand, "Rails asset precompilation")
+		ev.assumed("startCommand", profile.StartCommand, "conventional Rails start command")
 	}
 
 	// Also check for config/routes.rb as a Rails indicator.
 	if fileExists(root, "config/routes.rb") {
 		profile.Framework = flkr.FrameworkRails
-		profile.Confidence = 0.9
+		ev.found("framework", profile.Framework, "config/routes.rb", 0, "Rails routes file present")
 	}
 
+	if m, ok := findPort(root, rubyPortScan); ok {
+		m.apply(profile, ev)
+	}
+
+	addEnvVars(profile, ev, findEnvVars(root, rubyEnvScan))
+
 	return profile, true, nil
 }
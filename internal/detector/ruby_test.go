@@ -31,3 +31,23 @@ gem "rails", "~> 7.1"
 	assert.Equal(t, "3.2.2", profile.Version)
 	assert.True(t, profile.HasLockfile)
 }
+
+func TestRubyDetector_PumaPort(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Gemfile": &fstest.MapFile{Data: []byte(`gem "puma"` + "\n")},
+		"config/puma.rb": &fstest.MapFile{Data: []byte(`threads 5, 5
+port ENV.fetch("PORT") { 3001 }
+`)},
+	}
+
+	profile, matched, err := (&RubyDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, 3001, profile.Port)
+	assert.Equal(t, "PORT", profile.PortEnv)
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.False(t, ev[0].Default)
+	assert.Equal(t, "config/puma.rb", ev[0].File)
+	assert.Equal(t, 2, ev[0].Line)
+}
@@ -0,0 +1,153 @@
+package detector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"time"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// DefaultDetectorTimeout bounds how long a single detector may run before
+// its result is abandoned.
+const DefaultDetectorTimeout = 10 * time.Second
+
+// detectorResult is the outcome of running one detector.
+type detectorResult struct {
+	profile *flkr.AppProfile
+	matched bool
+	err     error
+}
+
+// runDetector runs d against root with the given timeout. The detector sees
+// a filesystem that fails once its context is done, so file walks stop
+// promptly; a detector that ignores cancellation is abandoned when the
+// timeout expires. Panics are reported as errors.
+func runDetector(ctx context.Context, d Detector, root fs.FS, timeout time.Duration) detectorResult {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	done := make(chan detectorResult, 1)
+	go func() {
+		defer func() {
+			if v := recover(); v != nil {
+				done <- detectorResult{err: fmt.Errorf("panic: %v", v)}
+			}
+		}()
+		profile, matched, err := d.Detect(ctx, withContext(ctx, root))
+		done <- detectorResult{profile: profile, matched: matched, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		// File access fails quietly once the context is done, so a result
+		// produced after that point may be incomplete.
+		if ctx.Err() == nil {
+			return res
+		}
+	case <-ctx.Done():
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return detectorResult{err: fmt.Errorf("timed out after %s", timeout)}
+	}
+	return detectorResult{err: ctx.Err()}
+}
+
+// ctxFS wraps a filesystem so every operation fails with the context's
+// error once it is done.
+type ctxFS struct {
+	ctx  context.Context
+	fsys fs.FS
+}
+
+// withContext returns root wrapped so that file access honors ctx. A root
+// that is a flkr.Index stays one.
+func withContext(ctx context.Context, root fs.FS) fs.FS {
+	switch c := root.(type) {
+	case ctxFS:
+		root = c.fsys
+	case ctxIndex:
+		root = c.fsys
+	}
+	if idx, ok := root.(flkr.Index); ok {
+		return ctxIndex{ctxFS: ctxFS{ctx: ctx, fsys: idx}, idx: idx}
+	}
+	return ctxFS{ctx: ctx, fsys: root}
+}
+
+func (c ctxFS) Open(name string) (fs.File, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return c.fsys.Open(name)
+}
+
+func (c ctxFS) ReadFile(name string) ([]byte, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return fs.ReadFile(c.fsys, name)
+}
+
+func (c ctxFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: err}
+	}
+	return fs.ReadDir(c.fsys, name)
+}
+
+func (c ctxFS) Stat(name string) (fs.FileInfo, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "stat", Path: name, Err: err}
+	}
+	return fs.Stat(c.fsys, name)
+}
+
+func (c ctxFS) Sub(dir string) (fs.FS, error) {
+	sub, err := fs.Sub(c.fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	return withContext(c.ctx, sub), nil
+}
+
+// ctxIndex is a ctxFS over a flkr.Index.
+type ctxIndex struct {
+	ctxFS
+	idx flkr.Index
+}
+
+var _ flkr.Index = ctxIndex{}
+
+func (c ctxIndex) Glob(pattern string) ([]string, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.idx.Glob(pattern)
+}
+
+func (c ctxIndex) Files() []string {
+	if c.ctx.Err() != nil {
+		return nil
+	}
+	return c.idx.Files()
+}
+
+func (c ctxIndex) Match(pattern string) []string {
+	if c.ctx.Err() != nil {
+		return nil
+	}
+	return c.idx.Match(pattern)
+}
+
+func (c ctxIndex) ReadFileLimit(name string, limit int64) ([]byte, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: err}
+	}
+	return c.idx.ReadFileLimit(name, limit)
+}
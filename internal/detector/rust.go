@@ -22,33 +22,45 @@ func (d *RustDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 	profile := &flkr.AppProfile{
 		Language:       flkr.LangRust,
 		PackageManager: flkr.PkgCargo,
-		Confidence:     0.8,
 		DetectedBy:     d.Name(),
 		BuildCommand:   "cargo build --release",
 		StartCommand:   "./target/release/app",
 		Port:           8080,
 	}
+	ev := newRecorder(profile, d.Name())
+	ev.found("language", profile.Language, "Cargo.toml", 0, "Cargo.toml present")
+	ev.found("packageManager", profile.PackageManager, "Cargo.toml", 0, "Cargo.toml present")
+	ev.assumed("buildCommand", profile.BuildCommand, "release build with cargo")
+	ev.assumed("port", profile.Port, "Rust services listen on 8080 by default")
 
 	if fileExists(root, "Cargo.lock") {
 		profile.HasLockfile = true
 		profile.LockfileType = "cargo"
+		ev.found("lockfileType", profile.LockfileType, "Cargo.lock", 0, "Cargo.lock present")
 	}
 
-	// Parse Cargo.toml for edition and deps.
+	// Parse Cargo.toml for the minimum Rust version and deps.
 	cargo, err := parser.ParseCargoTOML(root, "Cargo.toml")
 	if err == nil {
+		raw := readFileString(root, "Cargo.toml")
 		if cargo.Package.Version != "" {
 			profile.AppVersion = cargo.Package.Version
+			ev.found("appVersion", profile.AppVersion, "Cargo.toml", lineOf(raw, "version"), "package.version")
 		}
-		if cargo.Package.Edition != "" {
-			profile.Version = cargo.Package.Edition
+		if v, ok := cargo.Package.RustVersion.(string); ok && v != "" {
+			profile.Version = v
+			profile.VersionConstraint = ">=" + v
+			ev.found("version", profile.Version, "Cargo.toml", lineOf(raw, "rust-version"), "package.rust-version (minimum supported Rust)")
 		}
 		if cargo.Package.Name != "" {
 			profile.StartCommand = "./target/release/" + cargo.Package.Name
+			ev.found("startCommand", profile.StartCommand, "Cargo.toml", lineOf(raw, "name"), "binary named after package.name")
+		} else {
+			ev.assumed("startCommand", profile.StartCommand, "no package name; assuming binary \"app\"")
 		}
 		if cargo.HasDep("actix-web") {
 			profile.Framework = flkr.FrameworkActix
-			profile.Confidence = 0.9
+			ev.found("framework", profile.Framework, "Cargo.toml", lineOf(raw, "actix-web"), "depends on actix-web")
 		}
 	}
 
@@ -56,7 +68,14 @@ func (d *RustDetector) Detect(ctx context.Context, root fs.FS) (*flkr.AppProfile
 	tc, err := parser.ParseRustToolchainTOML(root, "rust-toolchain.toml")
 	if err == nil && tc.Toolchain.Channel != "" {
 		profile.Version = tc.Toolchain.Channel
+		ev.found("version", profile.Version, "rust-toolchain.toml", lineOf(readFileString(root, "rust-toolchain.toml"), "channel"), "toolchain.channel")
 	}
 
+	if m, ok := findPort(root, rustPortScan); ok {
+		m.apply(profile, ev)
+	}
+
+	addEnvVars(profile, ev, findEnvVars(root, rustEnvScan))
+
 	return profile, true, nil
 }
@@ -32,5 +32,53 @@ actix-web = "4"
 	assert.Equal(t, flkr.PkgCargo, profile.PackageManager)
 	assert.Equal(t, flkr.FrameworkActix, profile.Framework)
 	assert.Equal(t, "./target/release/my-app", profile.StartCommand)
+	assert.Empty(t, profile.Version, "the edition is not a compiler version")
 	assert.True(t, profile.HasLockfile)
 }
+
+func TestRustDetector_RustVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Cargo.toml": &fstest.MapFile{Data: []byte(`[package]
+name = "api"
+edition = "2021"
+rust-version = "1.74"
+`)},
+	}
+
+	d := &RustDetector{}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "1.74", profile.Version)
+	assert.Equal(t, ">=1.74", profile.VersionConstraint)
+	assert.Equal(t, 4, profile.EvidenceFor("version")[0].Line)
+
+	// A workspace-inherited rust-version does not break parsing.
+	fsys["Cargo.toml"] = &fstest.MapFile{Data: []byte("[package]\nname = \"api\"\nrust-version.workspace = true\n")}
+	profile, matched, err = d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Empty(t, profile.Version)
+	assert.Equal(t, "./target/release/api", profile.StartCommand)
+}
+
+func TestRustDetector_BindPort(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Cargo.toml": &fstest.MapFile{Data: []byte("[package]\nname = \"api\"\n")},
+		"src/main.rs": &fstest.MapFile{Data: []byte(`#[actix_web::main]
+async fn main() -> std::io::Result<()> {
+    HttpServer::new(|| App::new()).bind(("0.0.0.0", 8088))?.run().await
+}
+`)},
+	}
+
+	profile, matched, err := (&RustDetector{}).Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	require.True(t, matched)
+	assert.Equal(t, 8088, profile.Port)
+	assert.Empty(t, profile.PortEnv)
+	ev := profile.EvidenceFor("port")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "src/main.rs", ev[0].File)
+	assert.Equal(t, 3, ev[0].Line)
+}
@@ -0,0 +1,390 @@
+package detector
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// composeFiles are the compose file names, in the order Docker Compose
+// looks them up.
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// credential names the compose settings a service reads a credential
+// from, and the value the service uses without them.
+type credential struct {
+	settings []string
+	fallback string
+}
+
+// serviceKind describes a backing service that can run locally from
+// nixpkgs.
+type serviceKind struct {
+	// images are the image names providing the service, without
+	// registry or namespace.
+	images []string
+
+	port int
+
+	// pkg returns the nixpkgs attribute for the version in the image
+	// tag, which may be empty.
+	pkg func(version string) string
+
+	user, password, database credential
+
+	// connect lists the env vars an app may read to reach the service,
+	// with values in which {user}, {password}, {auth} (user and password
+	// as URL userinfo), {database} and {port} are substituted. The first
+	// is set when the app reads none of them.
+	connect []flkr.Setting
+}
+
+// constPkg returns a pkg func for a service packaged under a single
+// attribute.
+func constPkg(attr string) func(string) string {
+	return func(string) string { return attr }
+}
+
+// sqlConnect builds the connection variables of a SQL database reached
+// with the given URL scheme, under the prefixes its clients use.
+func sqlConnect(scheme string, prefixes ...string) []flkr.Setting {
+	url := scheme + "://{auth}@localhost:{port}/{database}"
+	vars := []flkr.Setting{{Name: "DATABASE_URL", Value: url}, {Name: "DB_URL", Value: url}}
+	for _, p := range prefixes {
+		vars = append(vars, flkr.Setting{Name: p + "_URL", Value: url})
+	}
+	for _, p := range append(prefixes, "DB") {
+		vars = append(vars,
+			flkr.Setting{Name: p + "_HOST", Value: "localhost"},
+			flkr.Setting{Name: p + "_PORT", Value: "{port}"},
+			flkr.Setting{Name: p + "_USER", Value: "{user}"},
+			flkr.Setting{Name: p + "_PASSWORD", Value: "{password}"},
+		)
+	}
+	return append(vars,
+		flkr.Setting{Name: "DB_USERNAME", Value: "{user}"},
+		flkr.Setting{Name: "DB_NAME", Value: "{database}"},
+		flkr.Setting{Name: "DB_DATABASE", Value: "{database}"},
+	)
+}
+
+var serviceKinds = map[string]serviceKind{
+	"postgres": {
+		images: []string{"postgres", "postgis", "postgresql"},
+		port:   5432,
+		pkg: func(v string) string {
+			major, _, _ := strings.Cut(v, ".")
+			if n, err := strconv.Atoi(major); err == nil && n >= 13 && n <= 17 {
+				return "postgresql_" + major
+			}
+			return "postgresql"
+		},
+		user:     credential{[]string{"POSTGRES_USER"}, "postgres"},
+		password: credential{[]string{"POSTGRES_PASSWORD"}, ""},
+		database: credential{[]string{"POSTGRES_DB"}, "{user}"},
+		connect: append(sqlConnect("postgres", "POSTGRES"),
+			flkr.Setting{Name: "POSTGRES_DB", Value: "{database}"},
+			flkr.Setting{Name: "PGHOST", Value: "localhost"},
+			flkr.Setting{Name: "PGPORT", Value: "{port}"},
+			flkr.Setting{Name: "PGUSER", Value: "{user}"},
+			flkr.Setting{Name: "PGPASSWORD", Value: "{password}"},
+			flkr.Setting{Name: "PGDATABASE", Value: "{database}"},
+		),
+	},
+	"mysql": {
+		images: []string{"mysql"},
+		port:   3306,
+		pkg: func(v string) string {
+			if strings.HasPrefix(v, "8.0") || strings.HasPrefix(v, "5.") {
+				return "mysql80"
+			}
+			return "mysql84"
+		},
+		user:     credential{[]string{"MYSQL_USER"}, "root"},
+		password: credential{[]string{"MYSQL_PASSWORD", "MYSQL_ROOT_PASSWORD"}, ""},
+		database: credential{[]string{"MYSQL_DATABASE"}, ""},
+		connect:  append(sqlConnect("mysql", "MYSQL"), flkr.Setting{Name: "MYSQL_DATABASE", Value: "{database}"}),
+	},
+	"mariadb": {
+		images:   []string{"mariadb"},
+		port:     3306,
+		pkg:      constPkg("mariadb"),
+		user:     credential{[]string{"MARIADB_USER", "MYSQL_USER"}, "root"},
+		password: credential{[]string{"MARIADB_PASSWORD", "MYSQL_PASSWORD", "MARIADB_ROOT_PASSWORD", "MYSQL_ROOT_PASSWORD"}, ""},
+		database: credential{[]string{"MARIADB_DATABASE", "MYSQL_DATABASE"}, ""},
+		connect:  append(sqlConnect("mysql", "MYSQL"), flkr.Setting{Name: "MYSQL_DATABASE", Value: "{database}"}),
+	},
+	"redis": {
+		images: []string{"redis"},
+		port:   6379,
+		pkg:    constPkg("redis"),
+		connect: []flkr.Setting{
+			{Name: "REDIS_URL", Value: "redis://localhost:{port}"},
+			{Name: "CACHE_URL", Value: "redis://localhost:{port}"},
+			{Name: "CELERY_BROKER_URL", Value: "redis://localhost:{port}/0"},
+			{Name: "REDIS_HOST", Value: "localhost"},
+			{Name: "REDIS_PORT", Value: "{port}"},
+		},
+	},
+	"rabbitmq": {
+		images:   []string{"rabbitmq"},
+		port:     5672,
+		pkg:      constPkg("rabbitmq-server"),
+		user:     credential{[]string{"RABBITMQ_DEFAULT_USER"}, "guest"},
+		password: credential{[]string{"RABBITMQ_DEFAULT_PASS"}, "guest"},
+		database: credential{[]string{"RABBITMQ_DEFAULT_VHOST"}, ""},
+		connect: []flkr.Setting{
+			{Name: "AMQP_URL", Value: "amqp://{auth}@localhost:{port}/{database}"},
+			{Name: "RABBITMQ_URL", Value: "amqp://{auth}@localhost:{port}/{database}"},
+			{Name: "CLOUDAMQP_URL", Value: "amqp://{auth}@localhost:{port}/{database}"},
+			{Name: "BROKER_URL", Value: "amqp://{auth}@localhost:{port}/{database}"},
+			{Name: "CELERY_BROKER_URL", Value: "amqp://{auth}@localhost:{port}/{database}"},
+			{Name: "RABBITMQ_HOST", Value: "localhost"},
+			{Name: "RABBITMQ_PORT", Value: "{port}"},
+			{Name: "RABBITMQ_USER", Value: "{user}"},
+			{Name: "RABBITMQ_PASSWORD", Value: "{password}"},
+		},
+	},
+	"minio": {
+		images:   []string{"minio"},
+		port:     9000,
+		pkg:      constPkg("minio"),
+		user:     credential{[]string{"MINIO_ROOT_USER"}, "minioadmin"},
+		password: credential{[]string{"MINIO_ROOT_PASSWORD"}, "minioadmin"},
+		connect: []flkr.Setting{
+			{Name: "S3_ENDPOINT", Value: "http://localhost:{port}"},
+			{Name: "S3_ENDPOINT_URL", Value: "http://localhost:{port}"},
+			{Name: "AWS_ENDPOINT_URL", Value: "http://localhost:{port}"},
+			{Name: "AWS_ENDPOINT_URL_S3", Value: "http://localhost:{port}"},
+			{Name: "MINIO_ENDPOINT", Value: "http://localhost:{port}"},
+			{Name: "AWS_ACCESS_KEY_ID", Value: "{user}"},
+			{Name: "S3_ACCESS_KEY", Value: "{user}"},
+			{Name: "MINIO_ACCESS_KEY", Value: "{user}"},
+			{Name: "AWS_SECRET_ACCESS_KEY", Value: "{password}"},
+			{Name: "S3_SECRET_KEY", Value: "{password}"},
+			{Name: "MINIO_SECRET_KEY", Value: "{password}"},
+		},
+	},
+	"memcached": {
+		images: []string{"memcached"},
+		port:   11211,
+		pkg:    constPkg("memcached"),
+		connect: []flkr.Setting{
+			{Name: "MEMCACHED_SERVERS", Value: "localhost:{port}"},
+			{Name: "MEMCACHE_SERVERS", Value: "localhost:{port}"},
+			{Name: "MEMCACHIER_SERVERS", Value: "localhost:{port}"},
+			{Name: "MEMCACHED_HOST", Value: "localhost"},
+			{Name: "MEMCACHED_PORT", Value: "{port}"},
+		},
+	},
+}
+
+// kindOf returns the kind of service an image provides.
+func kindOf(image string) (string, bool) {
+	name, _ := splitImage(image)
+	for kind, k := range serviceKinds {
+		if slices.Contains(k.images, name) {
+			return kind, true
+		}
+	}
+	return "", false
+}
+
+// findServices records the backing services of the compose file in root
+// on profile. dir is the app's directory relative to root; the compose
+// service built from it is the app, and its environment entries pointing
+// at a backing service are kept with the host rewritten to localhost.
+func findServices(root fs.FS, dir string, profile *flkr.AppProfile, ev recorder) bool {
+	var file string
+	for _, name := range composeFiles {
+		if fileExists(root, name) {
+			file = name
+			break
+		}
+	}
+	if file == "" {
+		return false
+	}
+	cf, err := parser.ParseCompose(root, file)
+	if err != nil {
+		return false
+	}
+	var app *parser.ComposeService
+	var services []flkr.Service
+	for i, cs := range cf.Services {
+		if cs.Build != "" && path.Clean(cs.Build) == path.Clean(dir) {
+			app = &cf.Services[i]
+			continue
+		}
+		kind, ok := kindOf(cs.Image)
+		if !ok {
+			continue
+		}
+		k := serviceKinds[kind]
+		_, tag := splitImage(cs.Image)
+		s := flkr.Service{
+			Name:  cs.Name,
+			Kind:  kind,
+			Image: cs.Image,
+			Port:  publishedPort(cs.Ports, k.port),
+		}
+		if m := leadingVersionRe.FindStringSubmatch(tag); m != nil {
+			s.Version = m[1]
+		}
+		s.Package = k.pkg(s.Version)
+		for _, v := range cs.Environment {
+			if v.Value != "" {
+				s.Settings = append(s.Settings, flkr.Setting{Name: v.Name, Value: v.Value})
+			}
+		}
+		s.User, s.Password, s.Database = credentials(s)
+		services = append(services, s)
+		ev.found("services", s.Name, file, cs.Line, kind+" image "+cs.Image+" in "+file)
+	}
+	if len(services) == 0 {
+		return false
+	}
+	profile.Services = append(profile.Services, services...)
+
+	if app != nil {
+		for _, v := range app.Environment {
+			value, s, ok := localServiceValue(v.Value, services)
+			if !ok || slices.ContainsFunc(profile.ServiceEnv, func(s flkr.Setting) bool { return s.Name == v.Name }) {
+				continue
+			}
+			profile.ServiceEnv = append(profile.ServiceEnv, flkr.Setting{Name: v.Name, Value: value})
+			ev.found("serviceEnv", v.Name, file, v.Line, "set for the "+app.Name+" service in "+file+", pointed at local "+s.Kind+" service "+s.Name)
+		}
+	}
+	return true
+}
+
+// publishedPort returns the host port a compose service publishes for
+// target, or target itself.
+func publishedPort(ports []string, target int) int {
+	for _, p := range ports {
+		p, _, _ = strings.Cut(p, "/")
+		parts := strings.Split(p, ":")
+		if len(parts) < 2 || parts[len(parts)-1] != strconv.Itoa(target) {
+			continue
+		}
+		if n, err := strconv.Atoi(parts[len(parts)-2]); err == nil {
+			return n
+		}
+	}
+	return target
+}
+
+// localServiceValue rewrites a value that reaches a backing service by
+// its compose host name, such as postgres://app@db:5432/app or plain
+// "redis", to reach the locally run service instead. It returns the
+// service reached.
+func localServiceValue(value string, services []flkr.Service) (string, flkr.Service, bool) {
+	if strings.Contains(value, "${") {
+		return "", flkr.Service{}, false
+	}
+	for _, s := range services {
+		if value == s.Name {
+			return "localhost", s, true
+		}
+		re := regexp.MustCompile(`(^|[/@])` + regexp.QuoteMeta(s.Name) + `(:\d+)?([/?]|$)`)
+		if !re.MatchString(value) {
+			continue
+		}
+		return re.ReplaceAllStringFunc(value, func(m string) string {
+			sm := re.FindStringSubmatch(m)
+			host := "localhost"
+			if sm[2] != "" {
+				host += ":" + strconv.Itoa(s.Port)
+			}
+			return sm[1] + host + sm[3]
+		}), s, true
+	}
+	return "", flkr.Service{}, false
+}
+
+// connectServices sets the env vars the app reads to reach its services,
+// with values matching how the services run locally. A service the app
+// reads none of the known variables for gets its conventional one, such
+// as DATABASE_URL or REDIS_URL.
+func connectServices(profile *flkr.AppProfile) {
+	ev := newRecorder(profile, "crosscutting")
+	set := func(name string) bool {
+		return slices.ContainsFunc(profile.ServiceEnv, func(s flkr.Setting) bool { return s.Name == name })
+	}
+	for _, s := range profile.Services {
+		k := serviceKinds[s.Kind]
+		user, password, database := credentials(s)
+		r := strings.NewReplacer(
+			"{user}", user,
+			"{password}", password,
+			"{auth}", strings.TrimSuffix(user+":"+password, ":"),
+			"{database}", database,
+			"{port}", strconv.Itoa(s.Port),
+		)
+		connected := false
+		for _, c := range k.connect {
+			if !slices.Contains(profile.EnvVars, c.Name) {
+				continue
+			}
+			connected = true
+			if set(c.Name) {
+				continue
+			}
+			profile.ServiceEnv = append(profile.ServiceEnv, flkr.Setting{Name: c.Name, Value: r.Replace(c.Value)})
+			file, line := "", 0
+			if e := profile.EvidenceFor("envVars"); len(e) > 0 {
+				if i := slices.IndexFunc(e, func(e flkr.Evidence) bool { return e.Value == c.Name }); i >= 0 {
+					file, line = e[i].File, e[i].Line
+				}
+			}
+			ev.found("serviceEnv", c.Name, file, line, "app reads "+c.Name+" to reach "+s.Kind+" service "+s.Name)
+		}
+		if !connected && len(k.connect) > 0 && !set(k.connect[0].Name) &&
+			!slices.ContainsFunc(profile.Evidence, func(e flkr.Evidence) bool {
+				return e.Field == "serviceEnv" && strings.HasSuffix(e.Rule, " service "+s.Name)
+			}) {
+			c := k.connect[0]
+			profile.ServiceEnv = append(profile.ServiceEnv, flkr.Setting{Name: c.Name, Value: r.Replace(c.Value)})
+			ev.assumed("serviceEnv", c.Name, "conventional variable for "+s.Kind+" service "+s.Name)
+		}
+	}
+}
+
+// credentials returns the user, password and database of s, from its
+// settings or the defaults of its kind.
+func credentials(s flkr.Service) (user, password, database string) {
+	k := serviceKinds[s.Kind]
+	user = setting(s, k.user, "")
+	return user, setting(s, k.password, user), setting(s, k.database, user)
+}
+
+// setting returns the value of a credential of s, with {user} in its
+// fallback replaced by user.
+func setting(s flkr.Service, c credential, user string) string {
+	for _, name := range c.settings {
+		for _, v := range s.Settings {
+			if v.Name == name {
+				return v.Value
+			}
+		}
+	}
+	return strings.ReplaceAll(c.fallback, "{user}", user)
+}
+
+// inheritServices records the services of a compose file at the
+// workspace root on a member that has none of its own.
+func inheritServices(root fs.FS, dir string, profile *flkr.AppProfile) {
+	if len(profile.Services) > 0 || dir == "." {
+		return
+	}
+	if findServices(root, dir, profile, newRecorder(profile, "crosscutting")) {
+		connectServices(profile)
+	}
+}
@@ -0,0 +1,132 @@
+package detector
+
+import (
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// Limits on how much application source is scanned for listen ports and
+// env var reads.
+const (
+	sourceMaxFiles = 500
+	sourceMaxSize  = 256 << 10
+)
+
+// scanFiles resolves paths and glob patterns to existing files, skipping
+// tests. A "**/" segment matches any number of directories. Patterns are
+// matched against the repository index when available and by walking root
+// otherwise.
+func scanFiles(root fs.FS, patterns []string) []string {
+	var files []string
+	add := func(name string) {
+		if len(files) < sourceMaxFiles && !ignoredSource(name) && !slices.Contains(files, name) {
+			files = append(files, name)
+		}
+	}
+	for _, p := range patterns {
+		if !strings.Contains(p, "*") {
+			if info, err := fs.Stat(root, p); err == nil && !info.IsDir() {
+				add(p)
+			}
+			continue
+		}
+		if idx, ok := root.(flkr.Index); ok {
+			for _, name := range idx.Match(p) {
+				add(name)
+			}
+			continue
+		}
+		dir, base, deep := strings.Cut(p, "**/")
+		if !deep {
+			matches, _ := fs.Glob(root, p)
+			for _, name := range matches {
+				add(name)
+			}
+			continue
+		}
+		dir = strings.TrimSuffix(dir, "/")
+		if dir == "" {
+			dir = "."
+		}
+		_ = fs.WalkDir(root, dir, func(name string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if name != dir && skipScanDir(d.Name()) {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			if ok, _ := path.Match(base, d.Name()); ok {
+				add(name)
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// group locates the first participating group with the given name in a
+// match from FindStringSubmatchIndex.
+func group(re *regexp.Regexp, loc []int, name string) (start, end int, ok bool) {
+	for i, n := range re.SubexpNames() {
+		if n == name && loc[2*i] >= 0 {
+			return loc[2*i], loc[2*i+1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// submatch returns the text of the named group in a match of s, or "" if
+// it did not participate.
+func submatch(re *regexp.Regexp, s string, loc []int, name string) string {
+	if start, end, ok := group(re, loc, name); ok {
+		return s[start:end]
+	}
+	return ""
+}
+
+// skipScanDir reports whether a directory holds dependencies, build
+// output or tests rather than application source.
+func skipScanDir(name string) bool {
+	switch name {
+	case "node_modules", "vendor", "target", "dist", "build", "__pycache__",
+		"testdata", "test", "tests", "__tests__", "spec":
+		return true
+	}
+	return strings.HasPrefix(name, ".")
+}
+
+// ignoredSource reports whether name is a test or lies in a directory
+// skipped by skipScanDir; listen addresses there say nothing about the
+// application.
+func ignoredSource(name string) bool {
+	base := path.Base(name)
+	if strings.HasSuffix(base, "_test.go") || strings.HasPrefix(base, "test_") ||
+		strings.Contains(base, ".test.") || strings.Contains(base, ".spec.") {
+		return true
+	}
+	dir := path.Dir(name)
+	return dir != "." && slices.ContainsFunc(strings.Split(dir, "/"), skipScanDir)
+}
+
+// readLimit reads a file unless it is larger than limit bytes.
+func readLimit(root fs.FS, name string, limit int64) ([]byte, error) {
+	if idx, ok := root.(flkr.Index); ok {
+		return idx.ReadFileLimit(name, limit)
+	}
+	content, complete := readHead(root, name, limit)
+	if !complete {
+		return nil, flkr.ErrFileTooLarge
+	}
+	if content == "" && !fileExists(root, name) {
+		return nil, fs.ErrNotExist
+	}
+	return []byte(content), nil
+}
@@ -0,0 +1,213 @@
+package detector
+
+import (
+	_ "embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+//go:embed systemdeps.toml
+var systemDepsData string
+
+// systemDepMap maps ecosystem packages to the nixpkgs attributes they need,
+// keyed by ecosystem and package name.
+type systemDepMap map[string]map[string]parser.SystemDepRule
+
+// builtinSystemDeps parses the embedded mapping once.
+var builtinSystemDeps = sync.OnceValue(func() systemDepMap {
+	var m systemDepMap
+	if _, err := toml.Decode(systemDepsData, &m); err != nil {
+		panic("detector: invalid systemdeps.toml: " + err.Error())
+	}
+	return m
+})
+
+// systemDepsFor returns the built-in mapping overlaid with extra entries,
+// such as those from flkr.toml. An extra entry replaces the built-in one
+// for the same package.
+func systemDepsFor(extra ...map[string]map[string]parser.SystemDepRule) systemDepMap {
+	m := systemDepMap{}
+	for _, src := range append([]map[string]map[string]parser.SystemDepRule{builtinSystemDeps()}, extra...) {
+		for eco, pkgs := range src {
+			if m[eco] == nil {
+				m[eco] = map[string]parser.SystemDepRule{}
+			}
+			for name, rule := range pkgs {
+				m[eco][name] = rule
+			}
+		}
+	}
+	return m
+}
+
+// packageSource is a manifest or lockfile in which a package appears.
+// pattern builds the regular expression matching an entry for a package
+// name escaped with regexp.QuoteMeta.
+type packageSource struct {
+	file    string
+	pattern func(name string) string
+}
+
+// ecosystem describes where the packages of a language are declared,
+// lockfiles first.
+type ecosystem struct {
+	name    string
+	sources []packageSource
+}
+
+var pySeparatorRe = regexp.MustCompile(`[-_]|\\\.`)
+
+// pyName matches an escaped PyPI name in any of its normalized spellings.
+func pyName(name string) string {
+	return `(?i:` + pySeparatorRe.ReplaceAllString(name, `[-_.]`) + `)`
+}
+
+var ecosystems = map[flkr.Language]ecosystem{
+	flkr.LangNode: {"npm", []packageSource{
+		{"package-lock.json", func(n string) string { return `node_modules/` + n + `"` }},
+		{"yarn.lock", func(n string) string { return `(?m)^"?` + n + `@` }},
+		{"pnpm-lock.yaml", func(n string) string { return `(?m)^\s+'?/?` + n + `@` }},
+		{"package.json", func(n string) string { return `"` + n + `"\s*:\s*"` }},
+	}},
+	flkr.LangPython: {"pypi", []packageSource{
+		{"poetry.lock", func(n string) string { return `(?m)^name\s*=\s*"` + pyName(n) + `"` }},
+		{"uv.lock", func(n string) string { return `(?m)^name\s*=\s*"` + pyName(n) + `"` }},
+		{"Pipfile.lock", func(n string) string { return `"` + pyName(n) + `"\s*:\s*\{` }},
+		{"requirements.txt", func(n string) string { return `(?m)^` + pyName(n) + `\s*(?:[\[=<>~!;@ ]|$)` }},
+		{"pyproject.toml", func(n string) string { return `(?m)(?:["']|^)` + pyName(n) + `\s*(?:[\[=<>~!;@ "']|$)` }},
+		{"Pipfile", func(n string) string { return `(?m)^` + pyName(n) + `\s*=` }},
+	}},
+	flkr.LangRuby: {"rubygems", []packageSource{
+		{"Gemfile.lock", func(n string) string { return `(?m)^    ` + n + ` \(` }},
+		{"Gemfile", func(n string) string { return `(?m)^\s*gem\s+["']` + n + `["']` }},
+	}},
+	flkr.LangRust: {"crates", []packageSource{
+		{"Cargo.lock", func(n string) string { return `(?m)^name = "` + n + `"` }},
+		{"Cargo.toml", func(n string) string { return `(?m)^(?:` + n + `\s*=|\[(?:[\w-]+\.)?dependencies\.` + n + `\])` }},
+	}},
+	flkr.LangElixir: {"hex", []packageSource{
+		{"mix.lock", func(n string) string { return `(?m)^\s*"` + n + `":` }},
+		{"mix.exs", func(n string) string { return `\{:` + n + `\s*,` }},
+	}},
+	flkr.LangPHP: {"composer", []packageSource{
+		{"composer.lock", func(n string) string { return `"name":\s*"` + n + `"` }},
+		{"composer.json", func(n string) string { return `"` + n + `"\s*:` }},
+	}},
+	flkr.LangGo: {"go", []packageSource{
+		{"go.mod", func(n string) string { return `(?m)^\s*(?:require\s+)?` + n + `\s+v` }},
+	}},
+}
+
+// depMatcher finds a mapped package in the sources of its ecosystem.
+type depMatcher struct {
+	name     string
+	rule     parser.SystemDepRule
+	patterns []*regexp.Regexp // one per source, in order
+}
+
+// depMatchers holds the mapped packages of each language, sorted by name.
+type depMatchers map[flkr.Language][]depMatcher
+
+// compileDepMatchers compiles the source patterns of every package in m,
+// failing on a package name they can't be built from.
+func compileDepMatchers(m systemDepMap) (depMatchers, error) {
+	matchers := depMatchers{}
+	for lang, eco := range ecosystems {
+		names := make([]string, 0, len(m[eco.name]))
+		for name := range m[eco.name] {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			dm := depMatcher{name: name, rule: m[eco.name][name]}
+			for _, src := range eco.sources {
+				re, err := regexp.Compile(src.pattern(regexp.QuoteMeta(name)))
+				if err != nil {
+					return nil, fmt.Errorf("systemDepsMap.%s.%q: %w", eco.name, name, err)
+				}
+				dm.patterns = append(dm.patterns, re)
+			}
+			matchers[lang] = append(matchers[lang], dm)
+		}
+	}
+	return matchers, nil
+}
+
+// inferSystemDeps looks up the packages of a lang app in root against
+// matchers and returns an enrichment carrying the system dependencies they
+// need. With buildOnly set, runtime libraries are needed at build time
+// too, as for a frontend stage whose output is all that ships. dir
+// prefixes the evidence file paths.
+func inferSystemDeps(root fs.FS, lang flkr.Language, matchers depMatchers, dir string, buildOnly bool) *flkr.AppProfile {
+	eco, ok := ecosystems[lang]
+	if !ok || len(matchers[lang]) == 0 {
+		return nil
+	}
+
+	profile := &flkr.AppProfile{}
+	ev := newRecorder(profile, "systemdeps")
+	for i, src := range eco.sources {
+		content := readFileString(root, src.file)
+		if content == "" {
+			continue
+		}
+		for _, dm := range matchers[lang] {
+			loc := dm.patterns[i].FindStringIndex(content)
+			if loc == nil {
+				continue
+			}
+			name, rule := dm.name, dm.rule
+			file := path.Join(dir, src.file)
+			line := strings.Count(content[:loc[0]], "\n") + 1
+			build, runtime := rule.Build, rule.Runtime
+			if buildOnly {
+				build, runtime = append(slices.Clone(build), runtime...), nil
+			}
+			for _, dep := range build {
+				if !slices.Contains(profile.BuildDeps, dep) {
+					profile.BuildDeps = append(profile.BuildDeps, dep)
+					ev.found("buildDeps", dep, file, line, "needed to build "+eco.name+" package "+name)
+				}
+			}
+			for _, dep := range runtime {
+				if !slices.Contains(profile.SystemDeps, dep) {
+					profile.SystemDeps = append(profile.SystemDeps, dep)
+					ev.found("systemDeps", dep, file, line, eco.name+" package "+name+" links against it")
+				}
+			}
+		}
+	}
+	if len(profile.SystemDeps) == 0 && len(profile.BuildDeps) == 0 {
+		return nil
+	}
+	return profile
+}
+
+// addSystemDeps merges the system dependencies inferred for best and its
+// build stages into best.
+func addSystemDeps(root fs.FS, best *flkr.AppProfile, matchers depMatchers) {
+	if p := inferSystemDeps(root, best.Language, matchers, "", false); p != nil {
+		best.Merge(p)
+	}
+	for _, s := range best.Stages {
+		sub := root
+		if s.Dir != "." {
+			var err error
+			if sub, err = fs.Sub(root, s.Dir); err != nil {
+				continue
+			}
+		}
+		if p := inferSystemDeps(sub, s.Language, matchers, s.Dir, true); p != nil {
+			best.Merge(p)
+		}
+	}
+}
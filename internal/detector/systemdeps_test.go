@@ -0,0 +1,213 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferSystemDeps(t *testing.T) {
+	tests := []struct {
+		name        string
+		lang        flkr.Language
+		files       fstest.MapFS
+		wantBuild   []string
+		wantRuntime []string
+		wantFile    string
+		wantLine    int
+	}{
+		{
+			name: "npm lockfile",
+			lang: flkr.LangNode,
+			files: fstest.MapFS{
+				"package-lock.json": &fstest.MapFile{Data: []byte(`{
+  "packages": {
+    "node_modules/express": {"version": "4.19.2"},
+    "node_modules/sharp": {"version": "0.33.2"}
+  }
+}`)},
+			},
+			wantBuild:   []string{"pkg-config"},
+			wantRuntime: []string{"vips"},
+			wantFile:    "package-lock.json",
+			wantLine:    4,
+		},
+		{
+			name: "pypi requirements",
+			lang: flkr.LangPython,
+			files: fstest.MapFS{
+				"requirements.txt": &fstest.MapFile{Data: []byte("Django==5.0\npsycopg2-binary==2.9.9\nPsycopg2==2.9.9\n")},
+			},
+			wantBuild:   []string{"pkg-config", "postgresql"},
+			wantRuntime: []string{"postgresql"},
+			wantFile:    "requirements.txt",
+			wantLine:    3,
+		},
+		{
+			name: "pypi poetry lock",
+			lang: flkr.LangPython,
+			files: fstest.MapFS{
+				"poetry.lock": &fstest.MapFile{Data: []byte("[[package]]\nname = \"python_magic\"\nversion = \"0.4.27\"\n")},
+			},
+			wantRuntime: []string{"file"},
+			wantFile:    "poetry.lock",
+			wantLine:    2,
+		},
+		{
+			name: "rubygems lockfile",
+			lang: flkr.LangRuby,
+			files: fstest.MapFS{
+				"Gemfile.lock": &fstest.MapFile{Data: []byte(`GEM
+  remote: https://rubygems.org/
+  specs:
+    nokogiri (1.16.2-x86_64-linux)
+      racc (~> 1.4)
+    pg (1.5.4)
+`)},
+			},
+			wantBuild:   []string{"pkg-config"},
+			wantRuntime: []string{"libxml2", "libxslt", "postgresql"},
+			wantFile:    "Gemfile.lock",
+			wantLine:    6,
+		},
+		{
+			name: "crates lockfile",
+			lang: flkr.LangRust,
+			files: fstest.MapFS{
+				"Cargo.lock": &fstest.MapFile{Data: []byte("[[package]]\nname = \"openssl-sys\"\nversion = \"0.9.99\"\n")},
+			},
+			wantBuild:   []string{"pkg-config"},
+			wantRuntime: []string{"openssl"},
+			wantFile:    "Cargo.lock",
+			wantLine:    2,
+		},
+		{
+			name: "go module",
+			lang: flkr.LangGo,
+			files: fstest.MapFS{
+				"go.mod": &fstest.MapFile{Data: []byte("module example.com/img\n\ngo 1.22\n\nrequire (\n\tgithub.com/h2non/bimg v1.1.9\n)\n")},
+			},
+			wantBuild:   []string{"pkg-config"},
+			wantRuntime: []string{"vips"},
+			wantFile:    "go.mod",
+			wantLine:    6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchers, err := compileDepMatchers(systemDepsFor())
+			require.NoError(t, err)
+			p := inferSystemDeps(tt.files, tt.lang, matchers, "", false)
+			require.NotNil(t, p)
+			assert.Equal(t, tt.wantBuild, p.BuildDeps)
+			assert.Equal(t, tt.wantRuntime, p.SystemDeps)
+
+			field := "systemDeps"
+			if tt.wantRuntime == nil {
+				field = "buildDeps"
+			}
+			ev := p.EvidenceFor(field)
+			require.NotEmpty(t, ev)
+			assert.Equal(t, tt.wantFile, ev[len(ev)-1].File)
+			assert.Equal(t, tt.wantLine, ev[len(ev)-1].Line)
+		})
+	}
+}
+
+func TestInferSystemDeps_NoMatch(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"dependencies": {"sharpie": "1.0.0", "express": "4.19.2"}}`)},
+	}
+	matchers, err := compileDepMatchers(systemDepsFor())
+	require.NoError(t, err)
+	assert.Nil(t, inferSystemDeps(fsys, flkr.LangNode, matchers, "", false))
+	assert.Nil(t, inferSystemDeps(fsys, flkr.LangJava, matchers, "", false))
+}
+
+func TestSystemDeps_DetectBest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/shop\n\ngo 1.22.0\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"web/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"build": "vite build"}, "devDependencies": {"vite": "5.0.0", "sharp": "0.33.2"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	require.Len(t, profile.Stages, 1)
+	assert.Empty(t, profile.SystemDeps)
+	assert.Equal(t, []string{"pkg-config", "vips"}, profile.BuildDeps)
+
+	ev := profile.EvidenceFor("buildDeps")
+	require.Len(t, ev, 2)
+	assert.Equal(t, "web/package.json", ev[0].File)
+	assert.Equal(t, "systemdeps", ev[0].Detector)
+}
+
+func TestSystemDeps_ConfigMapping(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node server.js"}, "dependencies": {"@acme/imaging": "2.0.0", "sharp": "0.33.2"}}`),
+		},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[systemDepsMap.npm."@acme/imaging"]
+runtime = ["imagemagick"]
+
+[systemDepsMap.npm.sharp]
+runtime = ["vips"]
+
+[buildDeps]
+remove = ["pkg-config"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, []string{"imagemagick", "vips"}, profile.SystemDeps)
+	assert.Empty(t, profile.BuildDeps)
+	assert.Empty(t, profile.EvidenceFor("buildDeps"))
+}
+
+func TestSystemDeps_ConfigMappingPyPINames(t *testing.T) {
+	fsys := fstest.MapFS{
+		"requirements.txt": &fstest.MapFile{Data: []byte("flask==3.0\nzope-interface==6.2\nfoobar==1.0\n")},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[systemDepsMap.pypi."zope.interface"]
+runtime = ["libxml2"]
+
+[systemDepsMap.pypi."foo(bar"]
+runtime = ["libxslt"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, []string{"libxml2"}, profile.SystemDeps, "only an escaped dot is a separator")
+}
+
+func TestSystemDeps_WorkspaceConfigMapping(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"workspaces": ["apps/*"]}`)},
+		"apps/api/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node server.js"}, "dependencies": {"@acme/imaging": "2.0.0"}}`),
+		},
+		"flkr.toml": &fstest.MapFile{Data: []byte(`[systemDepsMap.npm."@acme/imaging"]
+runtime = ["imagemagick"]
+`)},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, []string{"imagemagick"}, profiles[0].SystemDeps)
+}
@@ -0,0 +1,346 @@
+package detector
+
+import (
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// taskTargets lists, for each command a task runner can supply, the
+// conventional target names in order of preference.
+var taskTargets = []struct {
+	field string
+	names []string
+	cmd   func(*flkr.AppProfile) *string
+}{
+	{"buildCommand", []string{"build"}, func(p *flkr.AppProfile) *string { return &p.BuildCommand }},
+	{"startCommand", []string{"start", "serve", "run"}, func(p *flkr.AppProfile) *string { return &p.StartCommand }},
+	{"testCommand", []string{"test"}, func(p *flkr.AppProfile) *string { return &p.TestCommand }},
+}
+
+// taskStep is a step of a task runner target: a shell command, or a run
+// of another target.
+type taskStep struct {
+	cmd    string
+	target string
+}
+
+// taskTarget is a target of a Makefile, justfile or Taskfile with its
+// variables expanded. safe is false when a command could not be expanded
+// or depends on how the runner executes it, so that it must be run
+// through the runner rather than inlined.
+type taskTarget struct {
+	line  int
+	steps []taskStep
+	safe  bool
+}
+
+// taskRunner is a task runner config read into its targets.
+type taskRunner struct {
+	file    string
+	tool    string // nixpkgs attribute providing the runner
+	command string // runs a target, e.g. "make"
+	targets map[string]taskTarget
+
+	// missingOK is true for make, whose prerequisites may be files
+	// rather than targets.
+	missingOK bool
+}
+
+// taskRunners reads the task runner configs at the root, in order of
+// precedence: a justfile or Taskfile is usually more deliberate than a
+// Makefile alongside it.
+func taskRunners(root fs.FS) []taskRunner {
+	var runners []taskRunner
+	for _, name := range []string{"justfile", "Justfile", ".justfile"} {
+		if jf, err := parser.ParseJustfile(root, name); err == nil {
+			runners = append(runners, justRunner(name, jf))
+			break
+		}
+	}
+	for _, name := range []string{"Taskfile.yml", "Taskfile.yaml", "taskfile.yml", "taskfile.yaml"} {
+		if tf, err := parser.ParseTaskfile(root, name); err == nil {
+			runners = append(runners, taskfileRunner(name, tf))
+			break
+		}
+	}
+	for _, name := range []string{"GNUmakefile", "makefile", "Makefile"} {
+		if mf, err := parser.ParseMakefile(root, name); err == nil {
+			runners = append(runners, makeRunner(name, mf))
+			break
+		}
+	}
+	return runners
+}
+
+// addTaskTargets sets the build, start and test commands profile doesn't
+// have yet from the conventional targets of the task runners at the root.
+// A target whose recipe is simple enough is inlined, so that the runner
+// isn't needed; otherwise the command runs the target. It reports whether
+// any command was set.
+func addTaskTargets(root fs.FS, profile *flkr.AppProfile, ev recorder) bool {
+	runners := taskRunners(root)
+	matched := false
+	for _, tt := range taskTargets {
+		if *tt.cmd(profile) != "" {
+			continue
+		}
+		for _, r := range runners {
+			cmd, line, inlined, ok := r.resolve(tt.names, tt.field == "startCommand")
+			if !ok {
+				continue
+			}
+			rule := r.file + " target"
+			if inlined {
+				rule = "recipe of " + rule + ", inlined"
+			} else {
+				cmd = r.command + " " + cmd
+				deps := &profile.BuildDeps
+				field := "buildDeps"
+				if tt.field == "startCommand" {
+					deps, field = &profile.SystemDeps, "systemDeps"
+				}
+				if !slices.Contains(*deps, r.tool) {
+					*deps = append(*deps, r.tool)
+					ev.found(field, r.tool, r.file, line, "runs "+cmd)
+				}
+			}
+			*tt.cmd(profile) = cmd
+			ev.found(tt.field, cmd, r.file, line, rule)
+			matched = true
+			break
+		}
+	}
+	return matched
+}
+
+var (
+	// containerToolRe matches commands that build or deploy containers,
+	// which a Nix build neither can nor needs to run.
+	containerToolRe = regexp.MustCompile(`(?:^|[\s;&|(])(?:docker|docker-compose|podman|nerdctl|buildah|kubectl|helm|skaffold|fly|flyctl)(?:\s|$)`)
+
+	// devServerRe matches start commands that compile or reload the app
+	// as it runs, which need the toolchain at runtime.
+	devServerRe = regexp.MustCompile(`(?:^|[\s;&|])(?:go run|cargo run|cargo watch|air|nodemon|watchexec|reflex)(?:\s|$)`)
+)
+
+// resolve returns the first usable target among names: either its
+// commands joined into one, with inlined true, or its name. Targets that
+// build containers are not usable, nor, for a start command, ones that run
+// a dev server. A start command leaves out the targets run before it,
+// which build the app.
+func (r taskRunner) resolve(names []string, start bool) (cmd string, line int, inlined, ok bool) {
+	for _, name := range names {
+		t, found := r.targets[name]
+		if !found {
+			continue
+		}
+		cmds, safe := r.commands(name, map[string]bool{}, !start)
+		if len(cmds) == 0 {
+			continue
+		}
+		joined := strings.Join(cmds, " && ")
+		if containerToolRe.MatchString(joined) || start && devServerRe.MatchString(joined) {
+			continue
+		}
+		if safe && inlineSafe(cmds) {
+			return joined, t.line, true, true
+		}
+		return name, t.line, false, true
+	}
+	return "", 0, false, false
+}
+
+// commands returns the commands a target runs, with those of the targets
+// it runs in turn if deps is set.
+func (r taskRunner) commands(name string, seen map[string]bool, deps bool) ([]string, bool) {
+	t, ok := r.targets[name]
+	if !ok {
+		return nil, r.missingOK
+	}
+	if seen[name] {
+		return nil, true
+	}
+	seen[name] = true
+	var cmds []string
+	safe := t.safe
+	for _, s := range t.steps {
+		if s.target == "" {
+			cmds = append(cmds, s.cmd)
+			continue
+		}
+		if !deps {
+			continue
+		}
+		sub, ok := r.commands(s.target, seen, deps)
+		cmds = append(cmds, sub...)
+		safe = safe && ok
+	}
+	return cmds, safe
+}
+
+// inlineSafe reports whether commands that each ran in a shell of their
+// own still mean the same when joined with &&: none but the last may
+// change the directory or environment of the ones after it.
+func inlineSafe(cmds []string) bool {
+	for _, c := range cmds[:len(cmds)-1] {
+		for _, w := range strings.FieldsFunc(c, func(r rune) bool { return r == ';' || r == '&' || r == '|' }) {
+			switch strings.Fields(w + " ")[0] {
+			case "cd", "pushd", "export", "source", ".", "set", "unset":
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// quietCommand reports whether a command only prints a status message.
+func quietCommand(cmd string) bool {
+	return strings.HasPrefix(cmd, "echo ") || cmd == "echo" || strings.HasPrefix(cmd, "printf ")
+}
+
+var makeRefRe = regexp.MustCompile(`\$(?:\(([^()]*)\)|\{([^{}]*)\}|(.))`)
+
+// makeRunner reads the targets of a Makefile.
+func makeRunner(file string, mf *parser.Makefile) taskRunner {
+	r := taskRunner{file: file, tool: "gnumake", command: "make", targets: map[string]taskTarget{}, missingOK: true}
+	for _, t := range mf.Targets {
+		if _, dup := r.targets[t.Name]; dup {
+			continue
+		}
+		tt := taskTarget{line: t.Line, safe: true}
+		for _, p := range t.Prereqs {
+			p, ok := expandMake(p, mf.Vars, 0)
+			tt.safe = tt.safe && ok
+			for _, name := range strings.Fields(p) {
+				tt.steps = append(tt.steps, taskStep{target: name})
+			}
+		}
+		for _, line := range t.Recipe {
+			// Make echoes lines without @, ignores the failure of lines
+			// with - and runs lines with + even with -n.
+			trimmed := strings.TrimLeft(line, "@+")
+			if strings.HasPrefix(trimmed, "-") {
+				tt.safe = false
+				trimmed = strings.TrimLeft(trimmed, "-@+")
+			}
+			cmd, ok := expandMake(trimmed, mf.Vars, 0)
+			if first, _, _ := strings.Cut(cmd, " "); first == "make" || first == "$(MAKE)" {
+				ok = false
+			}
+			tt.safe = tt.safe && ok
+			if !quietCommand(cmd) {
+				tt.steps = append(tt.steps, taskStep{cmd: cmd})
+			}
+		}
+		r.targets[t.Name] = tt
+	}
+	return r
+}
+
+// expandMake expands the variable references in s. It reports false if s
+// calls a function, uses an automatic or undefined variable, or nests too
+// deep, leaving those references as written.
+func expandMake(s string, vars map[string]string, depth int) (string, bool) {
+	ok := depth < 10
+	out := makeRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		m := makeRefRe.FindStringSubmatch(ref)
+		name := m[1] + m[2] + m[3]
+		v, defined := vars[name]
+		if !defined || !ok || strings.ContainsAny(name, " ,:") {
+			ok = false
+			return ref
+		}
+		v, vok := expandMake(v, vars, depth+1)
+		ok = ok && vok
+		return v
+	})
+	return out, ok
+}
+
+var justRefRe = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// justRunner reads the recipes of a justfile.
+func justRunner(file string, jf *parser.Justfile) taskRunner {
+	r := taskRunner{file: file, tool: "just", command: "just", targets: map[string]taskTarget{}}
+	for _, rc := range jf.Recipes {
+		tt := taskTarget{line: rc.Line, safe: len(rc.Params) == 0}
+		for _, d := range rc.Deps {
+			tt.steps = append(tt.steps, taskStep{target: d})
+		}
+		for i, line := range rc.Body {
+			if i == 0 && strings.HasPrefix(line, "#!") {
+				tt.safe = false
+				continue
+			}
+			cmd, ok := expandTemplate(strings.TrimLeft(line, "@-"), justRefRe, func(name string) (string, bool) {
+				v, ok := jf.Vars[name]
+				return v, ok
+			})
+			if strings.HasPrefix(line, "-") || strings.HasPrefix(cmd, "just ") {
+				ok = false
+			}
+			tt.safe = tt.safe && ok
+			if !quietCommand(cmd) {
+				tt.steps = append(tt.steps, taskStep{cmd: cmd})
+			}
+		}
+		r.targets[rc.Name] = tt
+	}
+	return r
+}
+
+var taskRefRe = regexp.MustCompile(`\{\{\s*\.(\w+)\s*\}\}`)
+
+// taskfileRunner reads the tasks of a Taskfile.
+func taskfileRunner(file string, tf *parser.Taskfile) taskRunner {
+	r := taskRunner{file: file, tool: "go-task", command: "task", targets: map[string]taskTarget{}}
+	for _, t := range tf.Tasks {
+		tt := taskTarget{line: t.Line, safe: t.Dir == ""}
+		for _, d := range t.Deps {
+			tt.steps = append(tt.steps, taskStep{target: d})
+		}
+		for _, c := range t.Cmds {
+			if c.Task != "" {
+				tt.steps = append(tt.steps, taskStep{target: c.Task})
+				continue
+			}
+			cmd, ok := expandTemplate(strings.TrimSpace(c.Cmd), taskRefRe, func(name string) (string, bool) {
+				v, ok := tf.Vars[name]
+				return v.Value, ok && v.Sh == ""
+			})
+			// Any template left over, such as a function call, can't
+			// be expanded here.
+			ok = ok && !strings.Contains(cmd, "{{") && !strings.Contains(cmd, "\n")
+			tt.safe = tt.safe && ok
+			if !quietCommand(cmd) {
+				tt.steps = append(tt.steps, taskStep{cmd: cmd})
+			}
+		}
+		r.targets[t.Name] = tt
+	}
+	return r
+}
+
+// expandTemplate replaces the references matched by re with the values
+// lookup returns for their first group. It reports false if a reference
+// has no usable value.
+func expandTemplate(s string, re *regexp.Regexp, lookup func(string) (string, bool)) (string, bool) {
+	ok := true
+	out := re.ReplaceAllStringFunc(s, func(ref string) string {
+		v, found := lookup(re.FindStringSubmatch(ref)[1])
+		if !found {
+			ok = false
+			return ref
+		}
+		return v
+	})
+	return out, ok
+}
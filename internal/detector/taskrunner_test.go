@@ -0,0 +1,184 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTaskTargets_Makefile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Makefile": &fstest.MapFile{Data: []byte(`MODULE := example.com/api
+VERSION ?= 1.2.0
+LDFLAGS = -ldflags "-X $(MODULE)/internal/version.Version=$(VERSION)"
+BIN := bin/api
+
+.PHONY: generate build run test
+
+generate:
+	go generate ./...
+
+build: generate ## Build the server
+	@echo "building $(BIN)"
+	CGO_ENABLED=0 go build $(LDFLAGS) \
+		-o $(BIN) ./cmd/api
+
+run: build
+	./$(BIN) serve
+
+test:
+	go test -race $(shell go list ./... | grep -v /e2e)
+
+docker:
+	docker build -t api .
+`)},
+	}
+
+	profile := &flkr.AppProfile{}
+	require.True(t, addTaskTargets(fsys, profile, newRecorder(profile, "crosscutting")))
+	assert.Equal(t, `go generate ./... && CGO_ENABLED=0 go build -ldflags "-X example.com/api/internal/version.Version=1.2.0" -o bin/api ./cmd/api`, profile.BuildCommand)
+	assert.Equal(t, "./bin/api serve", profile.StartCommand)
+	assert.Equal(t, "make test", profile.TestCommand, "$(shell) can't be inlined")
+	assert.Equal(t, []string{"gnumake"}, profile.BuildDeps)
+	assert.Empty(t, profile.SystemDeps)
+
+	ev := profile.EvidenceFor("buildCommand")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "Makefile", ev[0].File)
+	assert.Equal(t, 11, ev[0].Line)
+	assert.Equal(t, "recipe of Makefile target, inlined", ev[0].Rule)
+}
+
+func TestTaskTargets_NotInlined(t *testing.T) {
+	tests := []struct {
+		name     string
+		makefile string
+		want     string
+	}{
+		{name: "cd", makefile: "build:\n\tcd web && npm ci\n\tnpm run build\n", want: "make build"},
+		{name: "cd on the last line", makefile: "build:\n\tnpm ci\n\tcd web && npm run build\n", want: "npm ci && cd web && npm run build"},
+		{name: "undefined variable", makefile: "build:\n\tgo build -o $(OUT) .\n", want: "make build"},
+		{name: "automatic variable", makefile: "app: main.go\n\tgo build -o $@ .\nbuild: app\n", want: "make build"},
+		{name: "file prerequisite", makefile: "build: main.go\n\tgo build .\n", want: "go build ."},
+		{name: "recursive make", makefile: "build:\n\t$(MAKE) -C web build\n", want: "make build"},
+		{name: "ignored failure", makefile: "build:\n\t-rm -r dist\n\tvite build\n", want: "make build"},
+		{name: "escaped dollar", makefile: "build:\n\tgo build -ldflags \"-X main.sha=$$GIT_SHA\" .\n", want: `go build -ldflags "-X main.sha=$GIT_SHA" .`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fsys := fstest.MapFS{"Makefile": &fstest.MapFile{Data: []byte(tt.makefile)}}
+			profile := &flkr.AppProfile{}
+			addTaskTargets(fsys, profile, newRecorder(profile, "crosscutting"))
+			assert.Equal(t, tt.want, profile.BuildCommand)
+		})
+	}
+}
+
+func TestTaskTargets_Justfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"justfile": &fstest.MapFile{Data: []byte(`set dotenv-load
+
+out := "dist"
+sha := ` + "`git rev-parse HEAD`" + `
+
+# Compile assets, then the app.
+build: assets
+    cargo build --release --locked
+
+[private]
+assets:
+    @npm ci
+    npx tailwindcss -o {{ out }}/app.css
+
+serve port="3000":
+    ./target/release/app --port {{port}}
+
+test:
+    #!/usr/bin/env bash
+    set -euo pipefail
+    cargo test
+`)},
+		"Makefile": &fstest.MapFile{Data: []byte("build:\n\tcargo build\n")},
+	}
+
+	profile := &flkr.AppProfile{}
+	require.True(t, addTaskTargets(fsys, profile, newRecorder(profile, "crosscutting")))
+	assert.Equal(t, "npm ci && npx tailwindcss -o dist/app.css && cargo build --release --locked", profile.BuildCommand, "the justfile wins over the Makefile")
+	assert.Equal(t, "just serve", profile.StartCommand, "recipes with parameters are run by just")
+	assert.Equal(t, "just test", profile.TestCommand, "shebang recipes are run by just")
+	assert.Equal(t, []string{"just"}, profile.SystemDeps)
+	assert.Equal(t, []string{"just"}, profile.BuildDeps)
+	assert.Equal(t, 7, profile.EvidenceFor("buildCommand")[0].Line)
+}
+
+func TestTaskTargets_Taskfile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Taskfile.yml": &fstest.MapFile{Data: []byte(`version: '3'
+
+vars:
+  BIN: ./bin/worker
+  SHA:
+    sh: git rev-parse --short HEAD
+
+tasks:
+  proto:
+    cmds:
+      - buf generate
+  build:
+    deps: [proto]
+    cmds:
+      - go build -o {{.BIN}} ./cmd/worker
+  start:
+    cmds:
+      - task: build
+      - cmd: '{{.BIN}} --queue default'
+  test: go test -ldflags "-X main.sha={{.SHA}}" ./...
+`)},
+	}
+
+	profile := &flkr.AppProfile{}
+	require.True(t, addTaskTargets(fsys, profile, newRecorder(profile, "crosscutting")))
+	assert.Equal(t, "buf generate && go build -o ./bin/worker ./cmd/worker", profile.BuildCommand)
+	assert.Equal(t, "./bin/worker --queue default", profile.StartCommand)
+	assert.Equal(t, "task test", profile.TestCommand)
+	assert.Equal(t, 12, profile.EvidenceFor("buildCommand")[0].Line)
+}
+
+func TestTaskTargets_Skipped(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Makefile": &fstest.MapFile{Data: []byte(`build:
+	docker compose build
+
+run:
+	go run ./cmd/api
+`)},
+	}
+
+	profile := &flkr.AppProfile{}
+	assert.False(t, addTaskTargets(fsys, profile, newRecorder(profile, "crosscutting")))
+	assert.Empty(t, profile.BuildCommand)
+	assert.Empty(t, profile.StartCommand)
+}
+
+func TestTaskTargets_OverrideDetected(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/api\n\ngo 1.24\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+		"Makefile": &fstest.MapFile{Data: []byte(`build:
+	go build -tags netgo -o api .
+`)},
+		"Procfile": &fstest.MapFile{Data: []byte("web: ./api --listen :$PORT\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "go build -tags netgo -o api .", profile.BuildCommand)
+	assert.Equal(t, "./api --listen :$PORT", profile.StartCommand, "the Procfile wins over task targets")
+	assert.Equal(t, "Makefile", profile.EvidenceFor("buildCommand")[0].File)
+}
@@ -0,0 +1,400 @@
+package detector
+
+import (
+	"cmp"
+	_ "embed"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+//go:embed toolchains.toml
+var toolchainsData string
+
+// toolchain is a nixpkgs attribute providing one release line of a
+// language toolchain.
+type toolchain struct {
+	Attr    string `toml:"attr"`
+	Version string `toml:"version"`
+}
+
+// toolchainSet lists the toolchains nixpkgs provides for one ecosystem.
+type toolchainSet struct {
+	Default    string      `toml:"default"`
+	Toolchains []toolchain `toml:"toolchains"`
+}
+
+// toolchainCatalog parses the embedded catalog once.
+var toolchainCatalog = sync.OnceValue(func() map[string]toolchainSet {
+	var m map[string]toolchainSet
+	if _, err := toml.Decode(toolchainsData, &m); err != nil {
+		panic("detector: invalid toolchains.toml: " + err.Error())
+	}
+	return m
+})
+
+// toolchainWarning starts the warning given when a version constraint
+// matches no toolchain in the catalog, and toolchainLineWarning the one
+// given when a Go version is built with a later release line.
+const (
+	toolchainWarning     = "no nixpkgs toolchain matches "
+	toolchainLineWarning = "no nixpkgs toolchain has the release line of "
+)
+
+// ResolveToolchain sets the nixpkgs toolchain attribute of profile, of its
+// Erlang/OTP release and of each of its build stages from their version
+// constraints, replacing any earlier resolution. A toolchain pinned in
+// flkr.toml is kept. Constraints that no toolchain in nixpkgs satisfies
+// leave the toolchain unset and add a warning, as does a Go version whose
+// release line nixpkgs no longer has.
+func ResolveToolchain(profile *flkr.AppProfile) {
+	profile.Warnings = slices.DeleteFunc(profile.Warnings, func(w string) bool {
+		return strings.HasPrefix(w, toolchainWarning) || strings.HasPrefix(w, toolchainLineWarning)
+	})
+
+	pinned := slices.ContainsFunc(profile.EvidenceFor("toolchain"), func(e flkr.Evidence) bool {
+		return e.Detector == "config"
+	})
+	if !pinned {
+		ev := newRecorder(profile, "toolchain")
+		ev.reset("toolchain")
+		profile.Toolchain = ""
+		if c := cmp.Or(profile.VersionConstraint, profile.Version); c != "" {
+			if attr, ok := resolveToolchain(string(profile.Language), c); ok {
+				profile.Toolchain = attr
+				var file string
+				var line int
+				if e := profile.EvidenceFor("version"); len(e) > 0 {
+					file, line = e[len(e)-1].File, e[len(e)-1].Line
+				}
+				ev.found("toolchain", attr, file, line, "nixpkgs toolchain for version "+c)
+				if lineMissing(string(profile.Language), c) {
+					profile.Warnings = append(profile.Warnings, toolchainLineWarning+string(profile.Language)+" "+strconv.Quote(c)+"; building with the later "+attr)
+				}
+			} else {
+				profile.Warnings = append(profile.Warnings, toolchainMiss(string(profile.Language), c, ""))
+			}
+		}
+	}
+
+	profile.ErlangToolchain = ""
+	if profile.ErlangVersion != "" {
+		if attr, ok := resolveToolchain("erlang", profile.ErlangVersion); ok {
+			profile.ErlangToolchain = attr
+		} else {
+			profile.Warnings = append(profile.Warnings, toolchainMiss("erlang", profile.ErlangVersion, ""))
+		}
+	}
+
+	for i := range profile.Stages {
+		s := &profile.Stages[i]
+		s.Toolchain = ""
+		c := cmp.Or(s.VersionConstraint, s.Version)
+		if c == "" {
+			continue
+		}
+		if attr, ok := resolveToolchain(string(s.Language), c); ok {
+			s.Toolchain = attr
+		} else {
+			profile.Warnings = append(profile.Warnings, toolchainMiss(string(s.Language), c, s.Name))
+		}
+	}
+}
+
+// toolchainMiss describes a version constraint no toolchain satisfies,
+// listing the toolchains that are available.
+func toolchainMiss(eco, constraint, stage string) string {
+	w := toolchainWarning + eco + " " + strconv.Quote(constraint)
+	if stage != "" {
+		w += " in build stage " + stage
+	}
+	var attrs []string
+	for _, t := range toolchainCatalog()[eco].Toolchains {
+		attrs = append(attrs, t.Attr)
+	}
+	if len(attrs) > 0 {
+		w += "; available: " + strings.Join(attrs, ", ")
+	}
+	return w
+}
+
+// resolveToolchain returns the nixpkgs attribute best satisfying a version
+// constraint written in the syntax of the given ecosystem: the default
+// toolchain if it satisfies the constraint, otherwise the newest one that
+// does. A plain Go version is a minimum: its own release line is preferred,
+// and the newest later one is used when nixpkgs no longer has it.
+func resolveToolchain(eco, constraint string) (string, bool) {
+	spans, ok := parseConstraint(eco, constraint)
+	if !ok {
+		return "", false
+	}
+	if attr, ok := bestToolchain(eco, spans); ok {
+		return attr, true
+	}
+	if c := strings.TrimSpace(constraint); eco == "go" && goVersionRe.MatchString(c) {
+		v, _ := parseVersion(strings.TrimPrefix(c, "go"))
+		return bestToolchain(eco, []span{{lo: v.v}})
+	}
+	return "", false
+}
+
+// lineMissing reports whether constraint is a plain Go version whose
+// release line the catalog doesn't have, so that resolveToolchain falls
+// back to a later one.
+func lineMissing(eco, constraint string) bool {
+	c := strings.TrimSpace(constraint)
+	if eco != "go" || !goVersionRe.MatchString(c) {
+		return false
+	}
+	v, _ := parseVersion(strings.TrimPrefix(c, "go"))
+	_, ok := bestToolchain(eco, []span{v.line()})
+	return !ok
+}
+
+// bestToolchain returns the default toolchain of eco if it falls in one
+// of spans, otherwise the newest one that does.
+func bestToolchain(eco string, spans []span) (string, bool) {
+	set, ok := toolchainCatalog()[eco]
+	if !ok {
+		return "", false
+	}
+
+	var best *toolchain
+	var bestVersion version
+	for i, t := range set.Toolchains {
+		v, ok := parseVersion(t.Version)
+		if !ok || !slices.ContainsFunc(spans, v.line().overlaps) {
+			continue
+		}
+		if t.Attr == set.Default {
+			return t.Attr, true
+		}
+		if best == nil || compareVersions(v.v, bestVersion.v) > 0 {
+			best, bestVersion = &set.Toolchains[i], v
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	return best.Attr, true
+}
+
+// version is a release number. n is the number of components given, so
+// "3.11" has n == 2 and stands for any 3.11.x; "*" has n == 0.
+type version struct {
+	v [3]int
+	n int
+}
+
+// parseVersion parses up to three dot-separated components. A wildcard
+// component (x, X or *) ends the version, as does any suffix such as a
+// pre-release tag.
+func parseVersion(s string) (version, bool) {
+	var ver version
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	for _, part := range strings.SplitN(s, ".", 4) {
+		if ver.n == 3 || part == "x" || part == "X" || part == "*" {
+			break
+		}
+		end := 0
+		for end < len(part) && part[end] >= '0' && part[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			if ver.n == 0 {
+				return ver, false
+			}
+			break
+		}
+		ver.v[ver.n], _ = strconv.Atoi(part[:end])
+		ver.n++
+		if end < len(part) {
+			break
+		}
+	}
+	return ver, ver.n > 0 || strings.ContainsAny(s, "xX*")
+}
+
+// bump returns the version with component i incremented and the ones
+// after it zeroed.
+func (ver version) bump(i int) [3]int {
+	v := ver.v
+	v[i]++
+	for j := i + 1; j < 3; j++ {
+		v[j] = 0
+	}
+	return v
+}
+
+// line returns the span of releases the version stands for: exactly
+// 3.11.2, or every 3.11.x for "3.11".
+func (ver version) line() span {
+	if ver.n == 0 {
+		return span{}
+	}
+	return span{lo: ver.v, hi: ver.bump(ver.n - 1), bounded: true}
+}
+
+func compareVersions(a, b [3]int) int {
+	return slices.Compare(a[:], b[:])
+}
+
+// span is a half-open range of releases [lo, hi), unbounded above unless
+// bounded is set.
+type span struct {
+	lo, hi  [3]int
+	bounded bool
+}
+
+func (s span) overlaps(o span) bool {
+	return (!o.bounded || compareVersions(s.lo, o.hi) < 0) &&
+		(!s.bounded || compareVersions(o.lo, s.hi) < 0)
+}
+
+// intersect narrows s to the releases also in o.
+func (s span) intersect(o span) span {
+	if compareVersions(o.lo, s.lo) > 0 {
+		s.lo = o.lo
+	}
+	if o.bounded && (!s.bounded || compareVersions(o.hi, s.hi) < 0) {
+		s.hi, s.bounded = o.hi, true
+	}
+	return s
+}
+
+// nodeLTS maps the codenames nvm accepts as lts/<name> to their major
+// release.
+var nodeLTS = map[string]string{
+	"*":        "*",
+	"hydrogen": "18",
+	"iron":     "20",
+	"jod":      "22",
+	"krypton":  "24",
+}
+
+var (
+	javaVendorRe  = regexp.MustCompile(`^[A-Za-z][\w.]*?-(\d)`)
+	goVersionRe   = regexp.MustCompile(`^(?:go)?\d+(?:\.\d+)*$`)
+	hyphenRangeRe = regexp.MustCompile(`^\s*(\S+)\s+-\s+(\S+)\s*$`)
+	constraintRe  = regexp.MustCompile(`(~>|~=|===|==|!=|>=|<=|\^|~|>|<|=)?\s*(v?[0-9xX*][\w.*+-]*)`)
+)
+
+// parseConstraint parses a version constraint into the spans of releases
+// it allows, one per alternative. It understands npm and Composer ranges
+// (^, ~, x-ranges, hyphen ranges, ||), PEP 440 specifiers (~=, ==, !=,
+// .*), RubyGems and Mix requirements (~>) and plain version pins. How a
+// plain version is read depends on the ecosystem: a Java version names a
+// major release and may carry a vendor (temurin-21), a Go version may be
+// spelled go1.23.4, nvm accepts lts/<codename>, and ruby-3.2.2 or
+// 1.15.7-otp-26 pin the version before the suffix.
+func parseConstraint(eco, constraint string) ([]span, bool) {
+	c := strings.TrimSpace(constraint)
+	switch eco {
+	case "ruby":
+		c = strings.TrimPrefix(c, "ruby-")
+	case "node":
+		if name, ok := strings.CutPrefix(c, "lts/"); ok {
+			c = nodeLTS[name]
+		}
+	case "java":
+		c = javaVendorRe.ReplaceAllString(c, "$1")
+		if rest, ok := strings.CutPrefix(c, "1."); ok {
+			c = rest
+		}
+	case "go":
+		c = strings.TrimPrefix(c, "go")
+	case "rust":
+		if c == "stable" {
+			c = "*"
+		}
+	}
+
+	c = strings.NewReplacer("||", "|", " or ", "|", " and ", " ").Replace(c)
+	var spans []span
+	for _, alt := range strings.Split(c, "|") {
+		if m := hyphenRangeRe.FindStringSubmatch(alt); m != nil {
+			lo, ok1 := parseVersion(m[1])
+			hi, ok2 := parseVersion(m[2])
+			if !ok1 || !ok2 {
+				return nil, false
+			}
+			spans = append(spans, span{lo: lo.v}.intersect(span{hi: hi.line().hi, bounded: hi.n > 0}))
+			continue
+		}
+
+		s := span{}
+		terms := constraintRe.FindAllStringSubmatchIndex(alt, -1)
+		if len(terms) == 0 {
+			return nil, false
+		}
+		// Everything but separators must belong to a term.
+		rest := alt
+		for i := len(terms) - 1; i >= 0; i-- {
+			rest = rest[:terms[i][0]] + rest[terms[i][1]:]
+		}
+		if strings.Trim(rest, " ,") != "" {
+			return nil, false
+		}
+		for _, t := range terms {
+			var op string
+			if t[2] >= 0 {
+				op = alt[t[2]:t[3]]
+			}
+			v, ok := parseVersion(alt[t[4]:t[5]])
+			if !ok {
+				return nil, false
+			}
+			if op == "!=" {
+				continue
+			}
+			s = s.intersect(termSpan(eco, op, v))
+		}
+		spans = append(spans, s)
+	}
+	return spans, true
+}
+
+// termSpan returns the releases allowed by a single operator and version.
+func termSpan(eco, op string, v version) span {
+	if v.n == 0 {
+		return span{}
+	}
+	// npm reads a partial version in > and <= as the whole line: >20 is
+	// >=21 and <=20 is <21. Elsewhere >3.11 admits 3.11.1.
+	after := v.bump(2)
+	if eco == "node" || v.n == 3 {
+		after = v.bump(v.n - 1)
+	}
+	switch op {
+	case ">=":
+		return span{lo: v.v}
+	case ">":
+		return span{lo: after}
+	case "<":
+		return span{hi: v.v, bounded: true}
+	case "<=":
+		return span{hi: after, bounded: true}
+	case "^":
+		switch {
+		case v.v[0] > 0 || v.n == 1:
+			return span{lo: v.v, hi: v.bump(0), bounded: true}
+		case v.v[1] > 0 || v.n == 2:
+			return span{lo: v.v, hi: v.bump(1), bounded: true}
+		}
+		return v.line()
+	case "~":
+		// Composer's ~8.2 is >=8.2 <9; npm's ~20.1 is >=20.1 <20.2.
+		if v.n == 1 || (eco == "php" && v.n == 2) {
+			return span{lo: v.v, hi: v.bump(0), bounded: true}
+		}
+		return span{lo: v.v, hi: v.bump(1), bounded: true}
+	case "~>", "~=":
+		return span{lo: v.v, hi: v.bump(max(v.n-2, 0)), bounded: true}
+	}
+	return v.line()
+}
@@ -0,0 +1,162 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveToolchain(t *testing.T) {
+	tests := []struct {
+		eco        string
+		constraint string
+		want       string
+	}{
+		// npm ranges.
+		{"node", ">=18 <21", "nodejs_20"},
+		{"node", ">=20.0.0", "nodejs_22"},
+		{"node", "^20.11", "nodejs_20"},
+		{"node", "~24.1.0", "nodejs_24"},
+		{"node", "20.x", "nodejs_20"},
+		{"node", "18 || 20", "nodejs_20"},
+		{"node", "20 - 22", "nodejs_22"},
+		{"node", ">22", "nodejs_24"},
+		{"node", "v20.11.1", "nodejs_20"},
+		{"node", "<=20", "nodejs_20"},
+		{"node", "18", ""},
+		{"node", "lts/iron", "nodejs_20"},
+		{"node", "lts/*", "nodejs_22"},
+		{"node", "node", ""},
+
+		// PEP 440 and Poetry.
+		{"python", ">=3.11", "python313"},
+		{"python", ">=3.9,<3.13", "python312"},
+		{"python", "~=3.11", "python313"},
+		{"python", "~=3.11.4", "python311"},
+		{"python", "==3.10.*", "python310"},
+		{"python", "^3.12", "python313"},
+		{"python", ">=3.11, !=3.13.*, <3.14", "python313"},
+		{"python", "3.8", ""},
+
+		// Composer.
+		{"php", "^8.2", "php84"},
+		{"php", "~8.1", "php84"},
+		{"php", "~8.1.5", "php81"},
+		{"php", ">=8.1 <8.4", "php83"},
+		{"php", "^8.1|^8.2", "php84"},
+		{"php", "8.2.*", "php82"},
+		{"php", "^7.4", ""},
+
+		// Go versions prefer their own release line and are minimums.
+		{"go", "1.22.0", "go_1_25"},
+		{"go", "1.24", "go_1_24"},
+		{"go", "go1.23.4", "go_1_23"},
+		{"go", ">=1.23", "go_1_25"},
+		{"go", "1.26", ""},
+
+		// Ruby and Elixir pins and requirements.
+		{"ruby", "3.2.2", "ruby_3_2"},
+		{"ruby", "ruby-3.4.1", "ruby_3_4"},
+		{"ruby", "~> 3.2", "ruby_3_3"},
+		{"ruby", "~> 3.2.0", "ruby_3_2"},
+		{"elixir", "1.16.2-otp-26", "elixir_1_16"},
+		{"elixir", "~> 1.14", "elixir_1_18"},
+		{"erlang", "26.2.1", "erlang_26"},
+
+		// Java majors.
+		{"java", "21", "jdk21"},
+		{"java", "17", "jdk17"},
+		{"java", "1.8", "jdk8"},
+		{"java", "temurin-17.0.9+9", "jdk17"},
+		{"java", "openjdk-21", "jdk21"},
+		{"java", "19", ""},
+		{"java", "${java.version}", ""},
+
+		// Rust channels and minimums.
+		{"rust", "stable", "rustc"},
+		{"rust", ">=1.74", "rustc"},
+		{"rust", "1.78.0", ""},
+		{"rust", "nightly-2024-05-01", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.eco+" "+tt.constraint, func(t *testing.T) {
+			got, ok := resolveToolchain(tt.eco, tt.constraint)
+			assert.Equal(t, tt.want != "", ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestResolveToolchain_Profile(t *testing.T) {
+	profile := &flkr.AppProfile{
+		Language: flkr.LangGo,
+		Version:  "1.26.0",
+		Stages: []flkr.BuildStage{
+			{Name: "web", Language: flkr.LangNode, Version: "^20.11"},
+			{Name: "admin", Language: flkr.LangNode, Version: ">=26"},
+		},
+	}
+	ResolveToolchain(profile)
+	assert.Empty(t, profile.Toolchain)
+	assert.Equal(t, "nodejs_20", profile.Stages[0].Toolchain)
+	assert.Empty(t, profile.Stages[1].Toolchain)
+	assert.Equal(t, []string{
+		`no nixpkgs toolchain matches go "1.26.0"; available: go_1_23, go_1_24, go_1_25`,
+		`no nixpkgs toolchain matches node ">=26" in build stage admin; available: nodejs_20, nodejs_22, nodejs_24`,
+	}, profile.Warnings)
+
+	// Resolving again after the version changes replaces the warnings.
+	profile.Version = "1.24.1"
+	profile.Stages = profile.Stages[:1]
+	ResolveToolchain(profile)
+	assert.Equal(t, "go_1_24", profile.Toolchain)
+	assert.Empty(t, profile.Warnings)
+	assert.Len(t, profile.EvidenceFor("toolchain"), 1)
+
+	// A Go version whose release line nixpkgs no longer has builds with a
+	// later one, and says so.
+	profile.Version = "1.22.0"
+	ResolveToolchain(profile)
+	assert.Equal(t, "go_1_25", profile.Toolchain)
+	assert.Equal(t, []string{`no nixpkgs toolchain has the release line of go "1.22.0"; building with the later go_1_25`}, profile.Warnings)
+}
+
+func TestToolchain_DetectBest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{
+  "scripts": {"start": "node server.js"},
+  "engines": {"node": ">=18 <21"}
+}`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "18", profile.Version)
+	assert.Equal(t, ">=18 <21", profile.VersionConstraint)
+	assert.Equal(t, "nodejs_20", profile.Toolchain)
+
+	ev := profile.EvidenceFor("toolchain")
+	require.Len(t, ev, 1)
+	assert.Equal(t, "package.json", ev[0].File)
+	assert.Equal(t, 3, ev[0].Line)
+}
+
+func TestToolchain_ConfigPin(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"start": "node server.js"}, "engines": {"node": "18"}}`)},
+		"flkr.toml":    &fstest.MapFile{Data: []byte(`toolchain = "nodejs_18"` + "\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "nodejs_18", profile.Toolchain)
+	assert.Empty(t, profile.Warnings)
+}
@@ -0,0 +1,191 @@
+package detector
+
+import (
+	"bufio"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// versionPin is a toolchain version pinned by a version manager.
+type versionPin struct {
+	version string
+	file    string
+	line    int
+	rule    string
+}
+
+// versionTool names a toolchain in version manager files.
+type versionTool struct {
+	// names are the tool's names in .tool-versions and mise.toml.
+	names []string
+
+	// files are the tool's own version files, in order of precedence.
+	files []string
+}
+
+// versionTools maps languages, and Erlang for Elixir apps, to their
+// version manager names and files.
+var versionTools = map[string]versionTool{
+	"node":   {names: []string{"nodejs", "node"}, files: []string{".nvmrc", ".node-version"}},
+	"python": {names: []string{"python"}, files: []string{".python-version"}},
+	"go":     {names: []string{"golang", "go"}, files: []string{".go-version"}},
+	"rust":   {names: []string{"rust"}, files: []string{"rust-toolchain.toml", "rust-toolchain"}},
+	"ruby":   {names: []string{"ruby"}, files: []string{".ruby-version"}},
+	"elixir": {names: []string{"elixir"}, files: []string{".elixir-version"}},
+	"erlang": {names: []string{"erlang"}},
+	"java":   {names: []string{"java"}, files: []string{".java-version"}},
+	"php":    {names: []string{"php"}},
+}
+
+// findVersionPin returns the version of tool pinned in root. Version
+// managers take precedence in the order mise resolves them: mise.toml,
+// .mise.toml, .tool-versions, then the tool's own files such as .nvmrc or
+// rust-toolchain.toml.
+func findVersionPin(root fs.FS, tool string) (versionPin, bool) {
+	t, ok := versionTools[tool]
+	if !ok {
+		return versionPin{}, false
+	}
+	for _, name := range []string{"mise.toml", ".mise.toml"} {
+		if pin, ok := miseVersion(root, name, t.names); ok {
+			return pin, true
+		}
+	}
+	if pin, ok := toolVersionsVersion(root, t.names); ok {
+		return pin, true
+	}
+	for _, name := range t.files {
+		content := readFileString(root, name)
+		if content == "" {
+			continue
+		}
+		if strings.HasPrefix(name, "rust-toolchain") {
+			if pin, ok := rustToolchainVersion(root, name, content); ok {
+				return pin, true
+			}
+			continue
+		}
+		if v, line := firstValue(content); v != "" {
+			return versionPin{version: v, file: name, line: line, rule: name + " pin"}, true
+		}
+	}
+	return versionPin{}, false
+}
+
+// pinVersion replaces the version read from the manifest with the one
+// pinned by a version manager, so the flake builds with the runtime used
+// in development.
+func pinVersion(root fs.FS, profile *flkr.AppProfile, ev recorder) {
+	pin, ok := findVersionPin(root, string(profile.Language))
+	if !ok {
+		return
+	}
+	ev.reset("version")
+	profile.Version = pin.version
+	profile.VersionConstraint = ""
+	ev.found("version", pin.version, pin.file, pin.line, pin.rule)
+}
+
+// inheritVersionPin applies a version manager pin at the workspace root to
+// a member that pins no version of its own, as version managers look up
+// the directory tree.
+func inheritVersionPin(root, member fs.FS, profile *flkr.AppProfile) {
+	if _, ok := findVersionPin(member, string(profile.Language)); ok {
+		return
+	}
+	pin, ok := findVersionPin(root, string(profile.Language))
+	if !ok {
+		return
+	}
+	profile.Evidence = slices.DeleteFunc(profile.Evidence, func(e flkr.Evidence) bool {
+		return e.Field == "version"
+	})
+	profile.Version = pin.version
+	profile.VersionConstraint = ""
+	newRecorder(profile, profile.DetectedBy).found("version", pin.version, pin.file, pin.line, pin.rule+" (workspace root)")
+}
+
+// firstValue returns the first line of content that is not blank or a
+// comment, and its 1-based line number.
+func firstValue(content string) (string, int) {
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return line, n
+		}
+	}
+	return "", 0
+}
+
+// toolVersionsVersion reads an asdf .tool-versions file, where each line
+// names a tool followed by one or more versions; the first one is used.
+func toolVersionsVersion(root fs.FS, names []string) (versionPin, bool) {
+	content := readFileString(root, ".tool-versions")
+	sc := bufio.NewScanner(strings.NewReader(content))
+	for n := 1; sc.Scan(); n++ {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && slices.Contains(names, fields[0]) {
+			return versionPin{version: fields[1], file: ".tool-versions", line: n, rule: ".tool-versions " + fields[0]}, true
+		}
+	}
+	return versionPin{}, false
+}
+
+// miseVersion reads a tool version from the [tools] table of a mise
+// config. A tool may be given as a version string, a list whose first
+// entry is used, or a table with a version key.
+func miseVersion(root fs.FS, name string, names []string) (versionPin, bool) {
+	content := readFileString(root, name)
+	if content == "" {
+		return versionPin{}, false
+	}
+	var cfg struct {
+		Tools map[string]any `toml:"tools"`
+	}
+	if _, err := toml.Decode(content, &cfg); err != nil {
+		return versionPin{}, false
+	}
+	for _, tool := range names {
+		v := cfg.Tools[tool]
+		switch val := v.(type) {
+		case []any:
+			if len(val) > 0 {
+				v = val[0]
+			}
+		case map[string]any:
+			v = val["version"]
+		}
+		if s, ok := v.(string); ok && s != "" {
+			re := regexp.MustCompile(`(?m)^\s*"?` + regexp.QuoteMeta(tool) + `"?\s*=`)
+			line := 0
+			if loc := re.FindStringIndex(content); loc != nil {
+				line = strings.Count(content[:loc[0]], "\n") + 1
+			}
+			return versionPin{version: s, file: name, line: line, rule: name + " tools." + tool}, true
+		}
+	}
+	return versionPin{}, false
+}
+
+// rustToolchainVersion reads the channel from rust-toolchain.toml or the
+// legacy rust-toolchain file, which holds either the same TOML or just
+// the channel name.
+func rustToolchainVersion(root fs.FS, name, content string) (versionPin, bool) {
+	if name == "rust-toolchain" && !strings.Contains(content, "[toolchain]") {
+		v, line := firstValue(content)
+		return versionPin{version: v, file: name, line: line, rule: "rust-toolchain channel"}, v != ""
+	}
+	tc, err := parser.ParseRustToolchainTOML(root, name)
+	if err != nil || tc.Toolchain.Channel == "" {
+		return versionPin{}, false
+	}
+	return versionPin{version: tc.Toolchain.Channel, file: name, line: lineOf(content, "channel"), rule: "toolchain.channel"}, true
+}
@@ -0,0 +1,245 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindVersionPin(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		files    fstest.MapFS
+		want     string
+		wantFile string
+		wantLine int
+	}{
+		{
+			name: "nvmrc",
+			tool: "node",
+			files: fstest.MapFS{
+				".nvmrc": &fstest.MapFile{Data: []byte("v20.11.1\n")},
+			},
+			want:     "v20.11.1",
+			wantFile: ".nvmrc",
+			wantLine: 1,
+		},
+		{
+			name: "nvmrc before node-version",
+			tool: "node",
+			files: fstest.MapFS{
+				".node-version": &fstest.MapFile{Data: []byte("22\n")},
+				".nvmrc":        &fstest.MapFile{Data: []byte("# team default\nlts/iron\n")},
+			},
+			want:     "lts/iron",
+			wantFile: ".nvmrc",
+			wantLine: 2,
+		},
+		{
+			name: "tool-versions before own file",
+			tool: "python",
+			files: fstest.MapFS{
+				".python-version": &fstest.MapFile{Data: []byte("3.11\n")},
+				".tool-versions":  &fstest.MapFile{Data: []byte("nodejs 20.11.1\npython 3.12.2 3.11.8 # primary first\n")},
+			},
+			want:     "3.12.2",
+			wantFile: ".tool-versions",
+			wantLine: 2,
+		},
+		{
+			name: "mise before tool-versions",
+			tool: "node",
+			files: fstest.MapFS{
+				".tool-versions": &fstest.MapFile{Data: []byte("nodejs 18.19.0\n")},
+				"mise.toml":      &fstest.MapFile{Data: []byte("[env]\nNODE_ENV = \"production\"\n\n[tools]\nnode = \"22\"\n")},
+			},
+			want:     "22",
+			wantFile: "mise.toml",
+			wantLine: 5,
+		},
+		{
+			name: "mise list and table",
+			tool: "java",
+			files: fstest.MapFS{
+				".mise.toml": &fstest.MapFile{Data: []byte("[tools]\njava = { version = \"temurin-21\" }\n")},
+			},
+			want:     "temurin-21",
+			wantFile: ".mise.toml",
+			wantLine: 2,
+		},
+		{
+			name: "asdf golang",
+			tool: "go",
+			files: fstest.MapFS{
+				".tool-versions": &fstest.MapFile{Data: []byte("golang 1.23.4\n")},
+			},
+			want:     "1.23.4",
+			wantFile: ".tool-versions",
+			wantLine: 1,
+		},
+		{
+			name: "erlang",
+			tool: "erlang",
+			files: fstest.MapFS{
+				".tool-versions": &fstest.MapFile{Data: []byte("erlang 26.2.1\nelixir 1.16.2-otp-26\n")},
+			},
+			want:     "26.2.1",
+			wantFile: ".tool-versions",
+			wantLine: 1,
+		},
+		{
+			name: "legacy rust-toolchain",
+			tool: "rust",
+			files: fstest.MapFS{
+				"rust-toolchain": &fstest.MapFile{Data: []byte("1.78.0\n")},
+			},
+			want:     "1.78.0",
+			wantFile: "rust-toolchain",
+			wantLine: 1,
+		},
+		{
+			name: "legacy rust-toolchain in TOML",
+			tool: "rust",
+			files: fstest.MapFS{
+				"rust-toolchain": &fstest.MapFile{Data: []byte("[toolchain]\nchannel = \"stable\"\n")},
+			},
+			want:     "stable",
+			wantFile: "rust-toolchain",
+			wantLine: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pin, ok := findVersionPin(tt.files, tt.tool)
+			require.True(t, ok)
+			assert.Equal(t, tt.want, pin.version)
+			assert.Equal(t, tt.wantFile, pin.file)
+			assert.Equal(t, tt.wantLine, pin.line)
+		})
+	}
+
+	_, ok := findVersionPin(fstest.MapFS{".tool-versions": &fstest.MapFile{Data: []byte("ruby 3.3.0\n")}}, "node")
+	assert.False(t, ok)
+}
+
+func TestVersionPin_OverridesManifest(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{Data: []byte(`{"scripts": {"start": "node server.js"}, "engines": {"node": ">=18"}}`)},
+		".nvmrc":       &fstest.MapFile{Data: []byte("20.11.1\n")},
+	}
+
+	d := &NodeDetector{}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "20.11.1", profile.Version)
+	ev := profile.EvidenceFor("version")
+	require.Len(t, ev, 1)
+	assert.Equal(t, ".nvmrc", ev[0].File)
+}
+
+func TestGoDetector_ToolchainDirective(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod":  &fstest.MapFile{Data: []byte("module example.com/api\n\ngo 1.22.0\n\ntoolchain go1.23.4\n")},
+		"main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "1.23.4", profile.Version)
+	assert.Equal(t, "go_1_23", profile.Toolchain)
+	assert.Equal(t, 5, profile.EvidenceFor("version")[0].Line)
+
+	// A .go-version pin wins over go.mod.
+	fsys[".go-version"] = &fstest.MapFile{Data: []byte("1.24.1\n")}
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, "1.24.1", profile.Version)
+	assert.Equal(t, "go_1_24", profile.Toolchain)
+}
+
+func TestRubyDetector_GemfileRuby(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Gemfile": &fstest.MapFile{Data: []byte("source \"https://rubygems.org\"\n\nruby \">= 3.1\", \"< 3.4\"\ngem \"rack\"\n")},
+	}
+
+	d := &RubyDetector{}
+	profile, matched, err := d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.True(t, matched)
+	assert.Equal(t, "3.1", profile.Version)
+	assert.Equal(t, ">= 3.1 < 3.4", profile.VersionConstraint)
+	assert.Equal(t, 3, profile.EvidenceFor("version")[0].Line)
+
+	fsys[".ruby-version"] = &fstest.MapFile{Data: []byte("ruby-3.2.2\n")}
+	profile, _, err = d.Detect(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, "ruby-3.2.2", profile.Version)
+	assert.Empty(t, profile.VersionConstraint)
+}
+
+func TestElixirDetector_VersionPins(t *testing.T) {
+	fsys := fstest.MapFS{
+		"mix.exs": &fstest.MapFile{Data: []byte(`defmodule App.MixProject do
+  def project do
+    [app: :app, version: "0.1.0", elixir: "~> 1.15"]
+  end
+end
+`)},
+	}
+
+	reg := NewRegistry()
+	profile, err := reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, "1.15", profile.Version)
+	assert.Equal(t, "~> 1.15", profile.VersionConstraint)
+	assert.Equal(t, "elixir_1_18", profile.Toolchain)
+	assert.Empty(t, profile.ErlangVersion)
+
+	fsys[".elixir-version"] = &fstest.MapFile{Data: []byte("1.16.2-otp-26\n")}
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, "1.16.2-otp-26", profile.Version)
+	assert.Equal(t, "elixir_1_16", profile.Toolchain)
+	assert.Equal(t, "26", profile.ErlangVersion)
+	assert.Equal(t, "erlang_26", profile.ErlangToolchain)
+
+	fsys[".tool-versions"] = &fstest.MapFile{Data: []byte("erlang 27.1\nelixir 1.17.3\n")}
+	profile, err = reg.DetectBest(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, "1.17.3", profile.Version)
+	assert.Equal(t, "27.1", profile.ErlangVersion)
+	assert.Equal(t, "erlang_27", profile.ErlangToolchain)
+	assert.Equal(t, ".tool-versions", profile.EvidenceFor("erlangVersion")[0].File)
+}
+
+func TestVersionPin_WorkspaceRoot(t *testing.T) {
+	fsys := fstest.MapFS{
+		".tool-versions": &fstest.MapFile{Data: []byte("nodejs 24.1.0\n")},
+		"package.json":   &fstest.MapFile{Data: []byte(`{"workspaces": ["apps/*"]}`)},
+		"apps/api/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node server.js"}, "engines": {"node": ">=20"}}`),
+		},
+		"apps/worker/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node worker.js"}}`),
+		},
+		"apps/worker/.nvmrc": &fstest.MapFile{Data: []byte("20\n")},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 2)
+	assert.Equal(t, "24.1.0", profiles[0].Version)
+	assert.Equal(t, "nodejs_24", profiles[0].Toolchain)
+	assert.Equal(t, ".tool-versions", profiles[0].EvidenceFor("version")[0].File)
+	assert.Equal(t, "20", profiles[1].Version)
+	assert.Equal(t, "nodejs_20", profiles[1].Toolchain)
+}
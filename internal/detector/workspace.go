@@ -0,0 +1,289 @@
+package detector
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/narvanalabs/flkr/internal/parser"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// DetectWorkspace discovers the members of npm/yarn/pnpm workspaces, Cargo
+// workspaces, go.work setups and Maven multi-module builds, and runs
+// detection on each member directory. Members that are clearly libraries
+// are skipped. Each returned profile has Path set relative to root.
+// Repositories that are not workspaces yield the root profile with Path ".".
+func (r *Registry) DetectWorkspace(ctx context.Context, root fs.FS) ([]*flkr.AppProfile, error) {
+	root, err := r.indexed(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := workspaceMembers(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		profile, err := r.DetectBest(ctx, root)
+		if err != nil || profile == nil {
+			return nil, err
+		}
+		profile.Path = "."
+		return []*flkr.AppProfile{profile}, nil
+	}
+	r.logf("workspace: found %d members", len(members))
+
+	// Root flkr.toml [apps."<path>"] tables override individual members.
+	cfg, raw, err := loadConfig(root)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", ConfigFile, err)
+	}
+
+	// Lockfiles usually live at the workspace root; members inherit them.
+	rootProfiles, _, err := r.DetectAll(ctx, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var profiles []*flkr.AppProfile
+	for _, dir := range members {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		sub := root
+		if dir != "." {
+			if sub, err = fs.Sub(root, dir); err != nil {
+				return nil, err
+			}
+		}
+		want := r.languages[dir]
+		if o, ok := cfg.App(dir); ok && want == "" {
+			want = flkr.Language(o.Language)
+		}
+		profile, err := r.detectBest(ctx, sub, want, cfg.SystemDepRules())
+		if err != nil {
+			return nil, fmt.Errorf("workspace member %s: %w", dir, err)
+		}
+		if profile == nil {
+			r.logf("workspace: %s: no application stack detected", dir)
+			continue
+		}
+		if isLibraryMember(sub, profile) {
+			r.logf("workspace: %s: skipping library %s package", dir, profile.Language)
+			continue
+		}
+		inheritLockfile(profile, rootProfiles)
+		inheritVersionPin(root, sub, profile)
+		inheritServices(root, dir, profile)
+		addWorkModules(root, profile, dir)
+		if o, ok := cfg.App(dir); ok {
+			applyOverrides(profile, o, raw, `apps."`+dir+`"`)
+		}
+		ResolveToolchain(profile)
+		placeLocalModules(profile, dir)
+		profile.Path = dir
+		profiles = append(profiles, profile)
+	}
+	dropMemberStages(profiles, members)
+	return profiles, nil
+}
+
+// dropMemberStages removes build stages that are workspace members in
+// their own right, so a frontend member is not also built as a stage of a
+// backend member above it.
+func dropMemberStages(profiles []*flkr.AppProfile, members []string) {
+	for _, p := range profiles {
+		kept := p.Stages[:0]
+		for _, s := range p.Stages {
+			dir := path.Join(p.Path, s.Dir)
+			if dir != p.Path && slices.Contains(members, dir) {
+				p.Evidence = slices.DeleteFunc(p.Evidence, func(e flkr.Evidence) bool {
+					return e.Field == "stages" && strings.HasPrefix(e.Value, s.Dir+": ")
+				})
+				continue
+			}
+			kept = append(kept, s)
+		}
+		p.Stages = kept
+	}
+}
+
+// inheritLockfile copies lockfile information from the workspace root
+// profile of the same language onto a member that has none of its own.
+func inheritLockfile(profile *flkr.AppProfile, rootProfiles []*flkr.AppProfile) {
+	if profile.HasLockfile {
+		return
+	}
+	for _, rp := range rootProfiles {
+		if rp.Language == profile.Language && rp.HasLockfile {
+			profile.PackageManager = rp.PackageManager
+			profile.HasLockfile = true
+			profile.LockfileType = rp.LockfileType
+			kept := profile.Evidence[:0]
+			for _, e := range profile.Evidence {
+				if e.Field != "packageManager" {
+					kept = append(kept, e)
+				}
+			}
+			profile.Evidence = kept
+			for _, e := range rp.EvidenceFor("packageManager") {
+				e.Rule += " (workspace root)"
+				profile.AddEvidence(e)
+			}
+			return
+		}
+	}
+}
+
+// isLibraryMember reports whether a detected workspace member is a library
+// rather than a deployable app.
+func isLibraryMember(root fs.FS, profile *flkr.AppProfile) bool {
+	switch profile.Language {
+	case flkr.LangNode:
+		return profile.StartCommand == "" && profile.Framework == ""
+	case flkr.LangRust:
+		if fileExists(root, "src/main.rs") || fileExists(root, "src/bin") {
+			return false
+		}
+		cargo, err := parser.ParseCargoTOML(root, "Cargo.toml")
+		return err == nil && len(cargo.Bin) == 0
+	case flkr.LangGo:
+		return len(profile.Binaries) == 0
+	case flkr.LangJava:
+		pom, err := parser.ParsePomXML(root, "pom.xml")
+		return err == nil && pom.Packaging == "pom"
+	}
+	return false
+}
+
+// workspaceMembers returns the member directories declared by workspace
+// manifests at the repository root, relative to root and sorted. It returns
+// nil when the repository is not a workspace.
+func workspaceMembers(ctx context.Context, root fs.FS) ([]string, error) {
+	var patterns []string
+
+	if fileExists(root, "package.json") {
+		if pkg, err := parser.ParsePackageJSON(root, "package.json"); err == nil {
+			patterns = append(patterns, pkg.Workspaces...)
+		}
+	}
+	if fileExists(root, "pnpm-workspace.yaml") {
+		if ws, err := parser.ParsePnpmWorkspace(root, "pnpm-workspace.yaml"); err == nil {
+			patterns = append(patterns, ws.Packages...)
+		}
+	}
+	if fileExists(root, "Cargo.toml") {
+		if cargo, err := parser.ParseCargoTOML(root, "Cargo.toml"); err == nil && len(cargo.Workspace.Members) > 0 {
+			patterns = append(patterns, cargo.Workspace.Members...)
+			for _, ex := range cargo.Workspace.Exclude {
+				patterns = append(patterns, "!"+ex)
+			}
+			if cargo.Package.Name != "" {
+				patterns = append(patterns, ".")
+			}
+		}
+	}
+	if fileExists(root, "go.work") {
+		if work, err := parser.ParseGoWork(root, "go.work"); err == nil {
+			patterns = append(patterns, work.Use...)
+		}
+	}
+	if fileExists(root, "pom.xml") {
+		if pom, err := parser.ParsePomXML(root, "pom.xml"); err == nil {
+			patterns = append(patterns, pom.Modules.Module...)
+		}
+	}
+
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return expandWorkspacePatterns(ctx, root, patterns)
+}
+
+// expandWorkspacePatterns resolves workspace globs to existing directories.
+// Patterns prefixed with "!" exclude matches; a "**" segment matches any
+// number of nested directories. Paths escaping the root are ignored.
+func expandWorkspacePatterns(ctx context.Context, root fs.FS, patterns []string) ([]string, error) {
+	include := map[string]bool{}
+	exclude := map[string]bool{}
+
+	for _, p := range patterns {
+		target := include
+		if strings.HasPrefix(p, "!") {
+			target = exclude
+			p = p[1:]
+		}
+		p = path.Clean(strings.TrimPrefix(strings.TrimSpace(p), "./"))
+		if p == ".." || strings.HasPrefix(p, "../") || path.IsAbs(p) {
+			continue
+		}
+
+		matches, err := globDirs(ctx, root, p)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			target[m] = true
+		}
+	}
+
+	var members []string
+	for m := range include {
+		if !exclude[m] {
+			members = append(members, m)
+		}
+	}
+	sort.Strings(members)
+	return members, nil
+}
+
+// globDirs returns the directories matching pattern.
+func globDirs(ctx context.Context, root fs.FS, pattern string) ([]string, error) {
+	prefix, rest, recursive := strings.Cut(pattern, "**")
+	if !recursive {
+		matches, err := fs.Glob(root, pattern)
+		if err != nil {
+			return nil, err
+		}
+		var dirs []string
+		for _, m := range matches {
+			if info, err := fs.Stat(root, m); err == nil && info.IsDir() {
+				dirs = append(dirs, m)
+			}
+		}
+		return dirs, nil
+	}
+
+	base := strings.TrimSuffix(prefix, "/")
+	if base == "" {
+		base = "."
+	}
+	rest = strings.TrimPrefix(rest, "/")
+	var dirs []string
+	err := fs.WalkDir(root, base, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.IsDir() || p == base {
+			return nil
+		}
+		if d.Name() == "node_modules" || strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		if rest == "" {
+			dirs = append(dirs, p)
+		} else if ok, _ := path.Match(rest, d.Name()); ok {
+			dirs = append(dirs, p)
+		}
+		return nil
+	})
+	return dirs, err
+}
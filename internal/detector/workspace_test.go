@@ -0,0 +1,137 @@
+package detector
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func profilePaths(profiles []*flkr.AppProfile) []string {
+	var paths []string
+	for _, p := range profiles {
+		paths = append(paths, p.Path)
+	}
+	return paths
+}
+
+func TestDetectWorkspace_NPM(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json": &fstest.MapFile{
+			Data: []byte(`{"name": "root", "private": true, "workspaces": ["apps/*", "packages/*"]}`),
+		},
+		"package-lock.json": &fstest.MapFile{Data: []byte(`{}`)},
+		"apps/web/package.json": &fstest.MapFile{
+			Data: []byte(`{"name": "web", "dependencies": {"next": "14.0.0"}}`),
+		},
+		"apps/api/package.json": &fstest.MapFile{
+			Data: []byte(`{"name": "api", "scripts": {"start": "node server.js"}}`),
+		},
+		"packages/ui/package.json": &fstest.MapFile{
+			Data: []byte(`{"name": "ui", "scripts": {"build": "tsc"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"apps/api", "apps/web"}, profilePaths(profiles))
+	assert.Equal(t, "node server.js", profiles[0].StartCommand)
+	assert.Equal(t, flkr.FrameworkNextJS, profiles[1].Framework)
+	assert.True(t, profiles[1].HasLockfile, "members inherit the root lockfile")
+}
+
+func TestDetectWorkspace_PNPMYarnObject(t *testing.T) {
+	fsys := fstest.MapFS{
+		"package.json":        &fstest.MapFile{Data: []byte(`{"workspaces": {"packages": ["services/**"]}}`)},
+		"pnpm-workspace.yaml": &fstest.MapFile{Data: []byte("packages:\n  - 'apps/*'\n  - '!apps/legacy'\n")},
+		"pnpm-lock.yaml":      &fstest.MapFile{Data: []byte("lockfileVersion: 6\n")},
+		"apps/site/package.json": &fstest.MapFile{
+			Data: []byte(`{"dependencies": {"vite": "5.0.0"}}`),
+		},
+		"apps/legacy/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node index.js"}}`),
+		},
+		"services/billing/worker/package.json": &fstest.MapFile{
+			Data: []byte(`{"scripts": {"start": "node worker.js"}}`),
+		},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"apps/site", "services/billing/worker"}, profilePaths(profiles))
+	assert.Equal(t, flkr.PkgPNPM, profiles[0].PackageManager)
+}
+
+func TestDetectWorkspace_Cargo(t *testing.T) {
+	fsys := fstest.MapFS{
+		"Cargo.toml": &fstest.MapFile{
+			Data: []byte("[workspace]\nmembers = [\"crates/*\"]\nexclude = [\"crates/scratch\"]\n"),
+		},
+		"Cargo.lock":                 &fstest.MapFile{Data: []byte("version = 3\n")},
+		"crates/server/Cargo.toml":   &fstest.MapFile{Data: []byte("[package]\nname = \"server\"\n\n[dependencies]\nactix-web = \"4\"\n")},
+		"crates/server/src/main.rs":  &fstest.MapFile{Data: []byte("fn main() {}\n")},
+		"crates/core/Cargo.toml":     &fstest.MapFile{Data: []byte("[package]\nname = \"core\"\n")},
+		"crates/core/src/lib.rs":     &fstest.MapFile{Data: []byte("")},
+		"crates/scratch/Cargo.toml":  &fstest.MapFile{Data: []byte("[package]\nname = \"scratch\"\n")},
+		"crates/scratch/src/main.rs": &fstest.MapFile{Data: []byte("fn main() {}\n")},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"crates/server"}, profilePaths(profiles))
+	assert.Equal(t, flkr.FrameworkActix, profiles[0].Framework)
+	assert.True(t, profiles[0].HasLockfile)
+}
+
+func TestDetectWorkspace_GoWork(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.work": &fstest.MapFile{
+			Data: []byte("go 1.22\n\nuse (\n\t./api\n\t./lib // shared code\n\t../outside\n)\nuse ./tools\n"),
+		},
+		"api/go.mod":            &fstest.MapFile{Data: []byte("module example.com/api\n\ngo 1.22\n")},
+		"api/main.go":           &fstest.MapFile{Data: []byte("package main\n\nfunc main() {}\n")},
+		"lib/go.mod":            &fstest.MapFile{Data: []byte("module example.com/lib\n\ngo 1.22\n")},
+		"lib/lib.go":            &fstest.MapFile{Data: []byte("package lib\n")},
+		"tools/go.mod":          &fstest.MapFile{Data: []byte("module example.com/tools\n\ngo 1.22\n")},
+		"tools/cmd/gen/main.go": &fstest.MapFile{Data: []byte("package main\n")},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"api", "tools"}, profilePaths(profiles))
+}
+
+func TestDetectWorkspace_MavenModules(t *testing.T) {
+	fsys := fstest.MapFS{
+		"pom.xml": &fstest.MapFile{
+			Data: []byte(`<project><packaging>pom</packaging><modules><module>service</module><module>common</module></modules></project>`),
+		},
+		"service/pom.xml": &fstest.MapFile{Data: []byte(`<project><artifactId>service</artifactId></project>`)},
+		"common/pom.xml":  &fstest.MapFile{Data: []byte(`<project><packaging>pom</packaging></project>`)},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Equal(t, []string{"service"}, profilePaths(profiles))
+	assert.Equal(t, flkr.LangJava, profiles[0].Language)
+}
+
+func TestDetectWorkspace_SingleApp(t *testing.T) {
+	fsys := fstest.MapFS{
+		"go.mod": &fstest.MapFile{Data: []byte("module myapp\n\ngo 1.22.0\n")},
+	}
+
+	reg := NewRegistry()
+	profiles, err := reg.DetectWorkspace(context.Background(), fsys)
+	require.NoError(t, err)
+	require.Len(t, profiles, 1)
+	assert.Equal(t, ".", profiles[0].Path)
+}
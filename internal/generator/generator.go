@@ -2,8 +2,12 @@ package generator
 
 import (
 	"bytes"
+	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
 
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
@@ -13,11 +17,22 @@ type Options struct {
 	OutputPath      string
 	TemplateVersion string
 	DryRun          bool
+
+	// Default names the app exposed as packages.default and apps.default
+	// in a multi-app flake. Empty picks one automatically.
+	Default string
+}
+
+// App is a named profile rendered into a multi-app flake.
+type App struct {
+	Name    string
+	Profile *flkr.AppProfile
 }
 
 // Generator renders flake.nix files.
 type Generator interface {
 	Generate(profile *flkr.AppProfile, opts Options) (*flkr.GenerateResult, error)
+	GenerateApps(apps []App, opts Options) (*flkr.GenerateResult, error)
 }
 
 // DefaultGenerator uses text/template to render flake.nix.
@@ -28,12 +43,67 @@ func (g *DefaultGenerator) Generate(profile *flkr.AppProfile, opts Options) (*fl
 	data := newTemplateData(profile, opts.TemplateVersion)
 
 	var buf bytes.Buffer
-	if err := flakeTemplate.Execute(&buf, data); err != nil {
+	if err := templates.ExecuteTemplate(&buf, "flake.nix.tmpl", data); err != nil {
+		return nil, err
+	}
+	result, err := writeResult(buf.Bytes(), opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, data.Warnings...)
+	return result, nil
+}
+
+// GenerateApps renders a flake.nix exposing a named package and app for
+// each of the given apps, each built from its own profile Path by a mkApp
+// call of its own.
+func (g *DefaultGenerator) GenerateApps(apps []App, opts Options) (*flkr.GenerateResult, error) {
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("no apps to generate")
+	}
+
+	data := multiTemplateData{
+		TemplateVersion: opts.TemplateVersion,
+	}
+	var names, warnings []string
+	for _, app := range apps {
+		appData := newTemplateData(app.Profile, opts.TemplateVersion)
+		for _, w := range appData.Warnings {
+			warnings = append(warnings, app.Name+": "+w)
+		}
+		data.Extended = data.Extended || appData.Extended()
+		data.Apps = append(data.Apps, namedTemplateData{
+			Name: app.Name,
+			Data: appData,
+		})
+		names = append(names, app.Name)
+	}
+	data.Name = strings.Join(names, ", ")
+
+	data.Default = opts.Default
+	if data.Default == "" {
+		data.Default = defaultApp(apps)
+	} else if !contains(names, data.Default) {
+		return nil, fmt.Errorf("default app %q is not one of: %s", data.Default, data.Name)
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "flake-multi.nix.tmpl", data); err != nil {
 		return nil, err
 	}
+	result, err := writeResult(buf.Bytes(), opts)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	return result, nil
+}
 
+// writeResult wraps rendered content in a result and writes it to
+// opts.OutputPath unless this is a dry run.
+func writeResult(content []byte, opts Options) (*flkr.GenerateResult, error) {
 	result := &flkr.GenerateResult{
-		FlakeContent: buf.String(),
+		FlakeContent: string(content),
 	}
 
 	if !opts.DryRun && opts.OutputPath != "" {
@@ -41,7 +111,7 @@ func (g *DefaultGenerator) Generate(profile *flkr.AppProfile, opts Options) (*fl
 		if err := os.MkdirAll(dir, 0o755); err != nil {
 			return nil, err
 		}
-		if err := os.WriteFile(opts.OutputPath, buf.Bytes(), 0o644); err != nil {
+		if err := os.WriteFile(opts.OutputPath, content, 0o644); err != nil {
 			return nil, err
 		}
 		result.OutputPath = opts.OutputPath
@@ -49,3 +119,105 @@ func (g *DefaultGenerator) Generate(profile *flkr.AppProfile, opts Options) (*fl
 
 	return result, nil
 }
+
+// defaultApp picks the app exposed as the flake default: the app at the
+// repository root, else the first app that serves on a port, else the
+// first app.
+func defaultApp(apps []App) string {
+	for _, app := range apps {
+		if path.Clean(app.Profile.Path) == "." {
+			return app.Name
+		}
+	}
+	for _, app := range apps {
+		if app.Profile.Port != 0 && app.Profile.StartCommand != "" {
+			return app.Name
+		}
+	}
+	return apps[0].Name
+}
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// NameApps assigns a flake attribute name to each profile. Names come from
+// the names map (keyed by profile Path) when present, otherwise from the
+// last element of the profile Path, falling back to the full path and
+// finally a numeric suffix to keep names unique.
+func NameApps(profiles []*flkr.AppProfile, names map[string]string) ([]App, error) {
+	used := map[string]bool{}
+	apps := make([]App, 0, len(profiles))
+
+	for _, p := range profiles {
+		if name, ok := names[path.Clean(p.Path)]; ok {
+			if used[name] {
+				return nil, fmt.Errorf("app name %q is used more than once", name)
+			}
+			used[name] = true
+			apps = append(apps, App{Name: name, Profile: p})
+		}
+	}
+
+	for _, p := range profiles {
+		if _, ok := names[path.Clean(p.Path)]; ok {
+			continue
+		}
+		candidates := []string{
+			sanitizeName(path.Base(path.Clean(p.Path))),
+			sanitizeName(path.Clean(p.Path)),
+		}
+		name := ""
+		for _, c := range candidates {
+			if c != "" && !used[c] {
+				name = c
+				break
+			}
+		}
+		if name == "" {
+			base := candidates[0]
+			if base == "" {
+				base = "app"
+			}
+			for i := 2; ; i++ {
+				if c := fmt.Sprintf("%s-%d", base, i); !used[c] {
+					name = c
+					break
+				}
+			}
+		}
+		used[name] = true
+		apps = append(apps, App{Name: name, Profile: p})
+	}
+
+	// Keep the original profile order.
+	order := make(map[*flkr.AppProfile]int, len(profiles))
+	for i, p := range profiles {
+		order[p] = i
+	}
+	sorted := make([]App, len(apps))
+	for _, app := range apps {
+		sorted[order[app.Profile]] = app
+	}
+	return sorted, nil
+}
+
+// sanitizeName turns a path into a valid flake attribute name.
+func sanitizeName(s string) string {
+	if s == "." {
+		return "app"
+	}
+	s = invalidNameChars.ReplaceAllString(strings.ToLower(s), "-")
+	s = strings.Trim(s, "-")
+	if s != "" && s[0] >= '0' && s[0] <= '9' {
+		s = "app-" + s
+	}
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -1,35 +1,136 @@
 package generator
 
 import (
+	"bytes"
+	"cmp"
 	"embed"
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
 
-//go:embed templates/flake.nix.tmpl
+//go:embed templates/*.tmpl
 var templateFS embed.FS
 
-var flakeTemplate = template.Must(
-	template.ParseFS(templateFS, "templates/flake.nix.tmpl"),
-)
+// templates holds flake.nix.tmpl, flake-multi.nix.tmpl and the shared "app",
+// "service", "extend" and "extras" blocks. It is built in init because the
+// include func refers back to it.
+var templates *template.Template
+
+func init() {
+	templates = template.Must(
+		template.New("flkr").Funcs(template.FuncMap{
+			"include": func(name string, data any) (string, error) {
+				var buf bytes.Buffer
+				err := templates.ExecuteTemplate(&buf, name, data)
+				return buf.String(), err
+			},
+			"indent": func(n int, s string) string {
+				pad := strings.Repeat(" ", n)
+				lines := strings.Split(s, "\n")
+				for i, line := range lines {
+					if line != "" {
+						lines[i] = pad + line
+					}
+				}
+				return strings.Join(lines, "\n")
+			},
+			"nixString":   nixString,
+			"nixAttr":     nixAttr,
+			"nixAttrPath": nixAttrPath,
+			"nixIndented": nixIndented,
+			"shellArg":    shellArg,
+		}).ParseFS(templateFS, "templates/*.tmpl"),
+	)
+}
 
 // templateData is the view model passed to the flake.nix template.
 type templateData struct {
 	Name            string
+	Src             string // Nix path expression for the app directory
+	Source          string // Nix path expression for a source widened beyond Src
+	ModRoot         string // app directory within Source, if Source is set
 	Ecosystem       string
 	Version         string
+	Toolchain       string // nixpkgs attribute, e.g. "nodejs_22"
+	ErlangToolchain string
 	PackageManager  string
 	Framework       string
 	BuildCommand    string
 	StartCommand    string
+	Start           string         // StartCommand as run from the build output
+	Release         string         // ReleaseCommand as run from the build output
+	Processes       []flkr.Process // commands as run from the build output
+	TestCommand     string
+	HealthCheck     string
 	OutputDir       string
 	Port            int
+	PortProtocol    string
 	SystemDeps      []string
+	BuildDeps       []string
 	EnvVars         []string
+	RequiredEnvVars []string
+	EnvDefaults     []envDefault
+	Stages          []stageData
+	Services        []serviceData
+	ServiceEnv      []flkr.Setting
 	TemplateVersion string
 	AppVersion      string
 	VendorHash      string // Nix expression: "null" for vendor/, or quoted hash string
+	SubPackages     []string
+	Binaries        []flkr.Binary // those besides the one the app starts
+	CGOEnabled      string        // "1" or "0"; empty when not set
+	CGOOptional     bool          // cgo is used but has a pure-Go fallback
+	Ldflags         []string      // Nix expressions, one per flag
+	LinkVarsFromRev bool          // some -X value comes from the flake's revision
+	Tags            []string
+
+	// Warnings are about what the flake can't build as detected.
+	Warnings []string
+}
+
+// stageData is the view model for an auxiliary build stage.
+type stageData struct {
+	Name         string
+	Src          string // Nix path expression for the stage's directory
+	Toolchain    string
+	Deps         string // package manager fetching its dependencies
+	BuildCommand string
+	Output       string // what the build writes, relative to Src
+	Target       string // where Output is copied, relative to the app
+}
+
+// serviceData is the view model for a backing service.
+type serviceData struct {
+	flkr.Service
+	Setup string // SQL run on the first start of a MySQL or MariaDB service
+}
+
+// envDefault is the fallback value of an optional env var.
+type envDefault struct {
+	Name  string
+	Value string
+}
+
+// multiTemplateData is the view model passed to the multi-app template.
+type multiTemplateData struct {
+	Name            string
+	Default         string
+	TemplateVersion string
+	Apps            []namedTemplateData
+	Extended        bool // some app needs the extend helper
+}
+
+// namedTemplateData pairs an app name with its view model.
+type namedTemplateData struct {
+	Name string
+	Data templateData
 }
 
 // newTemplateData converts an AppProfile into template data.
@@ -50,20 +151,339 @@ func newTemplateData(profile *flkr.AppProfile, templateVersion string) templateD
 		}
 	}
 
+	// Several binaries are built as subPackages, paths relative to src.
+	var subPackages []string
+	if len(profile.Binaries) > 1 {
+		for _, b := range profile.Binaries {
+			subPackages = append(subPackages, strings.TrimPrefix(b.Package, "./"))
+		}
+	}
+
+	ldflags, fromRev := nixLdflags(profile)
+
+	stages, warnings := newStageData(profile)
+
+	// Each process becomes an app of its own, named after it, unless the
+	// flake has an app of that name already.
+	taken := func(name string) bool {
+		return name == "default" || name == "release" && profile.ReleaseCommand != "" ||
+			name == "services" && len(profile.Services) > 0
+	}
+	var processes []flkr.Process
+	for _, p := range profile.Processes {
+		if taken(p.Name) {
+			warnings = append(warnings, fmt.Sprintf("process %s: its name is taken by the flake's %s app, so it has no app of its own", p.Name, p.Name))
+			continue
+		}
+		processes = append(processes, flkr.Process{Name: p.Name, Command: runCommand(profile.Language, p.Command)})
+	}
+
+	// So does each binary besides the first, the one the app starts.
+	var binaries []flkr.Binary
+	for _, b := range profile.Binaries[min(1, len(profile.Binaries)):] {
+		if taken(b.Name) || slices.ContainsFunc(processes, func(p flkr.Process) bool { return p.Name == b.Name }) {
+			warnings = append(warnings, fmt.Sprintf("binary %s: its name is taken by the flake's %s app, so it has no app of its own", b.Name, b.Name))
+			continue
+		}
+		binaries = append(binaries, b)
+	}
+
+	// An app needing local modules beside it is built from a directory
+	// holding them all.
+	var source, modRoot string
+	if s := path.Clean(profile.Source); profile.Source != "" && s != path.Clean(profile.Path) {
+		source, modRoot = nixPath(s), path.Clean(profile.Path)
+		if s != "." {
+			modRoot = strings.TrimPrefix(modRoot, s+"/")
+		}
+	}
+
+	var services []serviceData
+	for _, s := range profile.Services {
+		services = append(services, serviceData{Service: s, Setup: mysqlSetup(s)})
+	}
+
+	var required []string
+	var defaults []envDefault
+	for _, name := range profile.EnvVars {
+		v, ok := profile.LookupEnv(name)
+		switch {
+		case !ok:
+		case v.Required:
+			required = append(required, name)
+		case v.Default != "":
+			defaults = append(defaults, envDefault{Name: name, Value: v.Default})
+		}
+	}
+	// The port the app reads from an env var is the one it listens on
+	// unless the environment says otherwise.
+	if profile.PortEnv != "" && profile.Port > 0 && !slices.Contains(required, profile.PortEnv) &&
+		!slices.ContainsFunc(defaults, func(d envDefault) bool { return d.Name == profile.PortEnv }) {
+		defaults = append(defaults, envDefault{Name: profile.PortEnv, Value: strconv.Itoa(profile.Port)})
+	}
+
 	return templateData{
 		Name:            name + "-app",
+		Src:             nixPath(profile.Path),
+		Source:          source,
+		ModRoot:         modRoot,
 		Ecosystem:       string(profile.Language),
 		Version:         profile.Version,
+		Toolchain:       profile.Toolchain,
+		ErlangToolchain: profile.ErlangToolchain,
 		PackageManager:  string(profile.PackageManager),
 		Framework:       string(profile.Framework),
 		BuildCommand:    profile.BuildCommand,
 		StartCommand:    profile.StartCommand,
+		Start:           runCommand(profile.Language, profile.StartCommand),
+		Release:         runCommand(profile.Language, profile.ReleaseCommand),
+		Processes:       processes,
+		TestCommand:     profile.TestCommand,
+		HealthCheck:     profile.HealthCheck,
 		OutputDir:       profile.OutputDir,
 		Port:            profile.Port,
+		PortProtocol:    profile.PortProtocol,
 		SystemDeps:      profile.SystemDeps,
+		BuildDeps:       profile.BuildDeps,
 		EnvVars:         profile.EnvVars,
+		RequiredEnvVars: required,
+		EnvDefaults:     defaults,
+		Stages:          stages,
+		Services:        services,
+		ServiceEnv:      profile.ServiceEnv,
 		AppVersion:      profile.AppVersion,
 		TemplateVersion: templateVersion,
 		VendorHash:      vendorHash,
+		SubPackages:     subPackages,
+		Binaries:        binaries,
+		CGOEnabled:      profile.CGOEnabled,
+		CGOOptional:     profile.CGOOptional && profile.CGOEnabled == "1",
+		Ldflags:         ldflags,
+		LinkVarsFromRev: fromRev,
+		Tags:            profile.Tags,
+		Warnings:        warnings,
+	}
+}
+
+// newStageData converts the build stages of profile into template data.
+// A stage whose dependencies can't be fetched in the Nix sandbox is left
+// out, with a warning to build it before the app.
+func newStageData(profile *flkr.AppProfile) ([]stageData, []string) {
+	var stages []stageData
+	var warnings []string
+	for _, s := range profile.Stages {
+		dir := path.Clean(s.Dir)
+		switch {
+		case s.Language != flkr.LangNode:
+			warnings = append(warnings, fmt.Sprintf("build stage %s: %s stages aren't built by the flake; build %s before nix build", s.Name, s.Language, dir))
+			continue
+		case !s.HasLockfile:
+			warnings = append(warnings, fmt.Sprintf("build stage %s: without a lockfile its dependencies can't be fetched; build %s before nix build", s.Name, dir))
+			continue
+		case s.PackageManager == flkr.PkgYarn || s.PackageManager == flkr.PkgPNPM:
+			warnings = append(warnings, fmt.Sprintf("build stage %s: set the hash of its %s dependencies from the error of the first nix build", s.Name, s.PackageManager))
+		}
+
+		output, target := ".", dir
+		if s.OutputDir != "" {
+			target = path.Clean(s.OutputDir)
+			switch {
+			case dir == ".":
+				output = target
+			case strings.HasPrefix(target, dir+"/"):
+				output = strings.TrimPrefix(target, dir+"/")
+			default:
+				warnings = append(warnings, fmt.Sprintf("build stage %s: its output %s is outside %s; build it before nix build", s.Name, target, dir))
+				continue
+			}
+		}
+		stages = append(stages, stageData{
+			Name:         s.Name,
+			Src:          nixPath(path.Join(profile.Path, dir)),
+			Toolchain:    s.Toolchain,
+			Deps:         cmp.Or(string(s.PackageManager), string(flkr.PkgNPM)),
+			BuildCommand: s.BuildCommand,
+			Output:       output,
+			Target:       target,
+		})
+	}
+	return stages, warnings
+}
+
+// runCommand returns cmd as run from the app's build output. Go binaries
+// are installed in bin/, so a Go app's binary is run by name.
+func runCommand(lang flkr.Language, cmd string) string {
+	if exe, args, _ := strings.Cut(cmd, " "); lang == flkr.LangGo && strings.HasPrefix(exe, "./") {
+		cmd = strings.TrimSpace(path.Base(exe) + " " + args)
+	}
+	return cmd
+}
+
+// mysqlSetup returns the SQL creating the database and user of a MySQL or
+// MariaDB service, as its image does on its first start.
+func mysqlSetup(s flkr.Service) string {
+	if s.Kind != "mysql" && s.Kind != "mariadb" {
+		return ""
+	}
+	var stmts []string
+	if s.Database != "" {
+		stmts = append(stmts, "CREATE DATABASE IF NOT EXISTS "+sqlIdent(s.Database)+";")
+	}
+	switch {
+	case s.User != "" && s.User != "root":
+		on := "*.*"
+		if s.Database != "" {
+			on = sqlIdent(s.Database) + ".*"
+		}
+		stmts = append(stmts,
+			fmt.Sprintf(`CREATE USER IF NOT EXISTS %s@"%%" IDENTIFIED BY %s;`, sqlString(s.User), sqlString(s.Password)),
+			fmt.Sprintf(`GRANT ALL ON %s TO %s@"%%";`, on, sqlString(s.User)))
+	case s.Password != "":
+		stmts = append(stmts, `ALTER USER "root"@"localhost" IDENTIFIED BY `+sqlString(s.Password)+";")
+	}
+	return strings.Join(stmts, " ")
+}
+
+// sqlString quotes s as a MySQL string literal. Double quotes keep the
+// statements readable as a single-quoted shell word.
+func sqlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// sqlIdent quotes s as a MySQL identifier.
+func sqlIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// Rebuilds reports whether the flake rebuilds mkApp's package with what
+// mkApp doesn't take.
+func (d templateData) Rebuilds() bool {
+	return d.ModRoot != "" || len(d.SubPackages) > 0 || d.Toolchain != "" || d.ErlangToolchain != "" || len(d.BuildDeps) > 0 || len(d.Stages) > 0 ||
+		d.CGOEnabled != "" || len(d.Tags) > 0 || len(d.Ldflags) > 0
+}
+
+// Overrides reports whether the flake overrides mkApp's package, to
+// rebuild it or only to add to its passthru.
+func (d templateData) Overrides() bool {
+	return d.Rebuilds() || d.HealthCheck != "" || d.PortProtocol != ""
+}
+
+// Wraps reports whether the flake replaces mkApp's default app: to run
+// the rebuilt package, or to prepare the app's start.
+func (d templateData) Wraps() bool {
+	return d.Rebuilds() || d.Release != "" || d.SetsEnv()
+}
+
+// SetsEnv reports whether the app's env vars are set, or checked, before
+// it starts.
+func (d templateData) SetsEnv() bool {
+	return len(d.EnvDefaults) > 0 || len(d.RequiredEnvVars) > 0
+}
+
+// Runs reports whether the flake defines apps of its own.
+func (d templateData) Runs() bool {
+	return d.Wraps() || len(d.Processes) > 0 || len(d.Binaries) > 0
+}
+
+// Extended reports whether the flake adds to mkApp's outputs.
+func (d templateData) Extended() bool {
+	return d.Overrides() || d.Runs() || len(d.Services) > 0 || d.TestCommand != ""
+}
+
+// StartApp returns the Nix expression for the command the default app
+// starts. A rebuilt package is started by its main program, or for Go by
+// the start command; otherwise mkApp's own app is.
+func (d templateData) StartApp() string {
+	switch {
+	case !d.Rebuilds():
+		return "prev.apps.default.program"
+	case d.Ecosystem == string(flkr.LangGo) && d.Start != "":
+		return nixString(d.Start)
+	}
+	return `"${lib.getExe package}"`
+}
+
+// nixLdflags renders the ldflags of a Go app as Nix strings, setting its
+// version variables to AppVersion and the others filled by the build to
+// the flake's revision and date. It reports whether the revision is used.
+func nixLdflags(profile *flkr.AppProfile) (flags []string, fromRev bool) {
+	for _, f := range profile.Ldflags {
+		flags = append(flags, nixString(f))
+	}
+	for _, v := range profile.LinkVars {
+		prefix := strings.TrimSuffix(nixString("-X "+v.Name+"="), `"`)
+		switch {
+		case v.From == "version" && profile.AppVersion != "":
+			flags = append(flags, nixString("-X "+v.Name+"="+profile.AppVersion))
+		case v.From == "version" || v.From == "commit":
+			flags = append(flags, prefix+`${self.rev or "dirty"}"`)
+			fromRev = true
+		case v.From == "date":
+			flags = append(flags, prefix+`${self.lastModifiedDate}"`)
+			fromRev = true
+		default:
+			flags = append(flags, nixString("-X "+v.Name+"="+v.Value))
+		}
+	}
+	return flags, fromRev
+}
+
+// nixString quotes s as a Nix string literal.
+func nixString(s string) string {
+	s = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "${", `\${`, "\n", `\n`).Replace(s)
+	return `"` + s + `"`
+}
+
+// nixIndented escapes s for a Nix indented string, the one between two
+// pairs of single quotes.
+func nixIndented(s string) string {
+	return strings.NewReplacer("''", "'''", "${", "''${").Replace(s)
+}
+
+var shellWordRe = regexp.MustCompile(`^[A-Za-z0-9._/@%+=:,-]+$`)
+
+// shellArg quotes s as a single shell word, unless it is one already.
+func shellArg(s string) string {
+	if shellWordRe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+var nixIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_'-]*$`)
+
+// nixAttr renders s as a Nix attribute name, quoting it unless it is a
+// plain identifier.
+func nixAttr(s string) string {
+	if nixIdentRe.MatchString(s) {
+		return s
+	}
+	return nixString(s)
+}
+
+// nixAttrPath renders a dotted nixpkgs attribute path, such as
+// "python3Packages.psycopg2", quoting each name as needed.
+func nixAttrPath(s string) string {
+	names := strings.Split(s, ".")
+	for i, name := range names {
+		names[i] = nixAttr(name)
+	}
+	return strings.Join(names, ".")
+}
+
+var nixPathRe = regexp.MustCompile(`^[A-Za-z0-9._+\-/]+$`)
+
+// nixPath renders a repository-relative directory as a Nix path expression
+// rooted at the flake, e.g. "./services/api". Directories with characters
+// not allowed in Nix path literals are appended as a string.
+func nixPath(rel string) string {
+	rel = path.Clean(rel)
+	if rel == "." || rel == "/" {
+		return "./."
+	}
+	rel = strings.TrimPrefix(rel, "/")
+	if nixPathRe.MatchString(rel) {
+		return "./" + rel
 	}
+	return "./. + " + nixString("/"+rel)
 }
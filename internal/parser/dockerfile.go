@@ -0,0 +1,213 @@
+package parser
+
+import (
+	"encoding/json"
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Dockerfile represents a parsed Dockerfile.
+type Dockerfile struct {
+	// Args are the ARG instructions before the first FROM, which may be
+	// referenced in FROM lines.
+	Args []DockerInstruction
+
+	// Stages are the build stages in order; the last one is the image
+	// that runs.
+	Stages []DockerStage
+}
+
+// DockerStage is a FROM instruction and the instructions following it.
+type DockerStage struct {
+	// Name is set by "FROM image AS name".
+	Name string
+
+	// Image is the base image as written, which may be an earlier stage.
+	Image string
+
+	// Line is the 1-based line of the FROM instruction.
+	Line int
+
+	Instructions []DockerInstruction
+}
+
+// DockerInstruction is a single instruction with its line continuations
+// joined.
+type DockerInstruction struct {
+	// Cmd is the upper-cased instruction keyword, e.g. "RUN".
+	Cmd string
+
+	// Args is the rest of the instruction. Heredoc bodies follow it on
+	// separate lines.
+	Args string
+
+	// Line is the 1-based line the instruction starts on.
+	Line int
+}
+
+// DockerVar is a variable set by an ENV or ARG instruction.
+type DockerVar struct {
+	Name  string
+	Value string
+
+	// Set is false for an ARG declared without a default.
+	Set bool
+}
+
+var heredocRe = regexp.MustCompile(`<<-?["']?(\w+)["']?`)
+
+// ParseDockerfile reads and parses a Dockerfile.
+func ParseDockerfile(root fs.FS, path string) (*Dockerfile, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	df := &Dockerfile{}
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		start := i + 1
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			line = strings.TrimSuffix(line, `\`)
+			i++
+			next := strings.TrimSpace(lines[i])
+			if next == "" || strings.HasPrefix(next, "#") {
+				line += `\`
+				continue
+			}
+			line = strings.TrimSpace(line) + " " + next
+		}
+		line = strings.TrimSuffix(line, `\`)
+
+		cmd, args, _ := strings.Cut(line, " ")
+		inst := DockerInstruction{Cmd: strings.ToUpper(cmd), Args: strings.TrimSpace(args), Line: start}
+		for _, m := range heredocRe.FindAllStringSubmatch(inst.Args, -1) {
+			for i+1 < len(lines) {
+				i++
+				if strings.TrimSpace(lines[i]) == m[1] {
+					break
+				}
+				inst.Args += "\n" + lines[i]
+			}
+		}
+
+		switch {
+		case inst.Cmd == "FROM":
+			df.Stages = append(df.Stages, parseFrom(inst))
+		case len(df.Stages) == 0:
+			if inst.Cmd == "ARG" {
+				df.Args = append(df.Args, inst)
+			}
+		default:
+			s := &df.Stages[len(df.Stages)-1]
+			s.Instructions = append(s.Instructions, inst)
+		}
+	}
+	return df, nil
+}
+
+// parseFrom parses "FROM [--platform=...] image [AS name]".
+func parseFrom(inst DockerInstruction) DockerStage {
+	s := DockerStage{Line: inst.Line}
+	fields := strings.Fields(inst.Args)
+	for len(fields) > 0 && strings.HasPrefix(fields[0], "--") {
+		fields = fields[1:]
+	}
+	if len(fields) > 0 {
+		s.Image = fields[0]
+	}
+	if len(fields) >= 3 && strings.EqualFold(fields[1], "AS") {
+		s.Name = fields[2]
+	}
+	return s
+}
+
+// Stage returns the stage with the given name, which Docker matches
+// case-insensitively.
+func (d *Dockerfile) Stage(name string) (*DockerStage, bool) {
+	for i := range d.Stages {
+		if d.Stages[i].Name != "" && strings.EqualFold(d.Stages[i].Name, name) {
+			return &d.Stages[i], true
+		}
+	}
+	return nil, false
+}
+
+// Exec returns the arguments of an instruction in exec form, a JSON array
+// such as ["node", "server.js"].
+func (i DockerInstruction) Exec() ([]string, bool) {
+	if !strings.HasPrefix(i.Args, "[") {
+		return nil, false
+	}
+	var args []string
+	if err := json.Unmarshal([]byte(i.Args), &args); err != nil {
+		return nil, false
+	}
+	return args, true
+}
+
+// Vars returns the variables set by an ENV or ARG instruction, written
+// either as NAME=value pairs or, for ENV, in the legacy "NAME value" form.
+func (i DockerInstruction) Vars() []DockerVar {
+	words := ShellWords(i.Args)
+	if len(words) == 0 {
+		return nil
+	}
+	if i.Cmd == "ENV" && !strings.Contains(words[0], "=") {
+		_, value, _ := strings.Cut(i.Args, " ")
+		return []DockerVar{{Name: words[0], Value: strings.TrimSpace(value), Set: true}}
+	}
+	vars := make([]DockerVar, 0, len(words))
+	for _, w := range words {
+		name, value, ok := strings.Cut(w, "=")
+		vars = append(vars, DockerVar{Name: name, Value: value, Set: ok})
+	}
+	return vars
+}
+
+// ShellWords splits s into words the way a POSIX shell would, removing
+// quotes and backslash escapes. Expansions are left as written.
+func ShellWords(s string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
@@ -0,0 +1,113 @@
+package parser
+
+import (
+	"io/fs"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FlkrTOML represents a project-level flkr.toml file. Top-level settings
+// apply to the repository root app; Apps holds per-app settings keyed by
+// the app path in a workspace.
+type FlkrTOML struct {
+	ProfileOverrides
+	Apps map[string]ProfileOverrides `toml:"apps,omitempty"`
+
+	// SystemDepsMap extends the built-in mapping from ecosystem packages
+	// to nixpkgs attributes, keyed by ecosystem ("npm", "pypi",
+	// "rubygems", "crates", "hex", "composer", "go") and package name.
+	SystemDepsMap map[string]map[string]SystemDepRule `toml:"systemDepsMap,omitempty"`
+}
+
+// SystemDepRule lists the nixpkgs attributes an ecosystem package needs:
+// Build while compiling it (e.g. pkg-config) and Runtime for the libraries
+// it links against.
+type SystemDepRule struct {
+	Build   []string `toml:"build,omitempty"`
+	Runtime []string `toml:"runtime,omitempty"`
+}
+
+// ProfileOverrides pins AppProfile fields and edits list fields. Zero
+// values leave the detected value untouched, so a field can't be pinned
+// empty; only hasLockfile, cgoEnabled and processes can turn a detected
+// value off. Build stages and backing services can't be pinned; they follow
+// the repository's package.json files and compose file.
+type ProfileOverrides struct {
+	Language       string   `toml:"language,omitempty"`
+	Version        string   `toml:"version,omitempty"`
+	Toolchain      string   `toml:"toolchain,omitempty"`
+	PackageManager string   `toml:"packageManager,omitempty"`
+	Framework      string   `toml:"framework,omitempty"`
+	BuildCommand   string   `toml:"buildCommand,omitempty"`
+	StartCommand   string   `toml:"startCommand,omitempty"`
+	ReleaseCommand string   `toml:"releaseCommand,omitempty"`
+	TestCommand    string   `toml:"testCommand,omitempty"`
+	HealthCheck    string   `toml:"healthCheck,omitempty"`
+	OutputDir      string   `toml:"outputDir,omitempty"`
+	Port           int      `toml:"port,omitempty"`
+	PortEnv        string   `toml:"portEnv,omitempty"`
+	PortProtocol   string   `toml:"portProtocol,omitempty"`
+	AppVersion     string   `toml:"appVersion,omitempty"`
+	SystemDeps     ListEdit `toml:"systemDeps,omitempty"`
+	BuildDeps      ListEdit `toml:"buildDeps,omitempty"`
+	EnvVars        ListEdit `toml:"envVars,omitempty"`
+
+	// LockfileType pins the lockfile the build installs from, and
+	// HasLockfile = false builds without one.
+	LockfileType string `toml:"lockfileType,omitempty"`
+	HasLockfile  *bool  `toml:"hasLockfile,omitempty"`
+
+	// CGOEnabled turns cgo on or off for a Go app; off builds a static,
+	// pure-Go binary.
+	CGOEnabled *bool `toml:"cgoEnabled,omitempty"`
+
+	// Binaries edits the Go main packages built, by package path.
+	Binaries ListEdit `toml:"binaries,omitempty"`
+
+	// Tags and Ldflags edit the build tags and linker flags of a Go app.
+	// Variables set with -X are not among the ldflags.
+	Tags    ListEdit `toml:"tags,omitempty"`
+	Ldflags ListEdit `toml:"ldflags,omitempty"`
+
+	// Processes pins process types by name, replacing a detected command;
+	// an empty command removes the process.
+	Processes map[string]string `toml:"processes,omitempty"`
+}
+
+// ListEdit adds entries to and removes entries from a detected list.
+type ListEdit struct {
+	Add    []string `toml:"add,omitempty"`
+	Remove []string `toml:"remove,omitempty"`
+}
+
+// ParseFlkrTOML reads and parses a flkr.toml.
+func ParseFlkrTOML(root fs.FS, path string) (*FlkrTOML, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg FlkrTOML
+	if err := toml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// App returns the overrides for the app at path, if any. It is safe to
+// call on a nil config.
+func (c *FlkrTOML) App(path string) (ProfileOverrides, bool) {
+	if c == nil {
+		return ProfileOverrides{}, false
+	}
+	o, ok := c.Apps[path]
+	return o, ok
+}
+
+// SystemDepRules returns the user's additions to the system dependency
+// mapping. It is safe to call on a nil config.
+func (c *FlkrTOML) SystemDepRules() map[string]map[string]SystemDepRule {
+	if c == nil {
+		return nil
+	}
+	return c.SystemDepsMap
+}
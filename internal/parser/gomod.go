@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// GoMod represents a go.mod file. Exclude and retract directives are not
+// kept.
+type GoMod struct {
+	Module    string
+	Go        string
+	Toolchain string
+	Require   []GoRequire
+	Replace   []GoReplace
+
+	// Lines holds the 1-based line of the module, go and toolchain
+	// directives, keyed by directive.
+	Lines map[string]int
+}
+
+// GoRequire is a module required in go.mod.
+type GoRequire struct {
+	Path     string
+	Version  string
+	Indirect bool
+	Line     int
+}
+
+// GoReplace is a replace directive of go.mod or go.work. New is a
+// filesystem path when NewVersion is empty.
+type GoReplace struct {
+	Old        string
+	OldVersion string
+	New        string
+	NewVersion string
+	Line       int
+}
+
+// Local reports whether the replacement is a directory on disk rather than
+// a module version.
+func (r GoReplace) Local() bool {
+	return r.NewVersion == "" && (strings.HasPrefix(r.New, "./") || strings.HasPrefix(r.New, "../") || strings.HasPrefix(r.New, "/") || r.New == "." || r.New == "..")
+}
+
+// ParseGoMod reads and parses a go.mod file.
+func ParseGoMod(root fs.FS, path string) (*GoMod, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	mod := &GoMod{Lines: map[string]int{}}
+	for _, d := range goDirectives(string(data)) {
+		switch d.verb {
+		case "module":
+			if len(d.args) > 0 {
+				mod.Module = d.args[0]
+				mod.Lines["module"] = d.line
+			}
+		case "go":
+			if len(d.args) > 0 {
+				mod.Go = d.args[0]
+				mod.Lines["go"] = d.line
+			}
+		case "toolchain":
+			if len(d.args) > 0 {
+				mod.Toolchain = strings.TrimPrefix(d.args[0], "go")
+				mod.Lines["toolchain"] = d.line
+			}
+		case "require":
+			if len(d.args) >= 2 {
+				mod.Require = append(mod.Require, GoRequire{Path: d.args[0], Version: d.args[1], Indirect: d.indirect, Line: d.line})
+			}
+		case "replace":
+			if r, ok := goReplace(d); ok {
+				mod.Replace = append(mod.Replace, r)
+			}
+		}
+	}
+	return mod, nil
+}
+
+// Requires returns the requirement of the module at path.
+func (m *GoMod) Requires(path string) (GoRequire, bool) {
+	for _, r := range m.Require {
+		if r.Path == path {
+			return r, true
+		}
+	}
+	return GoRequire{}, false
+}
+
+// goDirective is a directive of a go.mod or go.work file, with those of a
+// block spelled out one per line.
+type goDirective struct {
+	verb     string
+	args     []string
+	indirect bool // marked // indirect
+	line     int
+}
+
+// goDirectives splits the content of a go.mod or go.work file into its
+// directives.
+func goDirectives(content string) []goDirective {
+	var dirs []goDirective
+	block := ""
+	for i, line := range strings.Split(content, "\n") {
+		comment := ""
+		if j := strings.Index(line, "//"); j != -1 {
+			line, comment = line[:j], strings.TrimSpace(line[j+2:])
+		}
+		words := goWords(line)
+		switch {
+		case len(words) == 0:
+			continue
+		case block != "" && words[0] == ")":
+			block = ""
+			continue
+		case block == "" && len(words) == 2 && words[1] == "(":
+			block = words[0]
+			continue
+		}
+		d := goDirective{verb: block, args: words, indirect: comment == "indirect", line: i + 1}
+		if block == "" {
+			d.verb, d.args = words[0], words[1:]
+		}
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+// goWords splits a go.mod line into words, unquoting quoted strings.
+func goWords(line string) []string {
+	var words []string
+	for line = strings.TrimSpace(line); line != ""; line = strings.TrimSpace(line) {
+		if line[0] == '"' || line[0] == '`' {
+			end := strings.IndexByte(line[1:], line[0])
+			if end == -1 {
+				end = len(line) - 1
+			}
+			words = append(words, line[1:end+1])
+			line = line[min(end+2, len(line)):]
+			continue
+		}
+		if strings.HasPrefix(line, "(") || strings.HasPrefix(line, ")") {
+			words = append(words, line[:1])
+			line = line[1:]
+			continue
+		}
+		end := strings.IndexAny(line, " \t()")
+		if end == -1 {
+			end = len(line)
+		}
+		words = append(words, line[:end])
+		line = line[end:]
+	}
+	return words
+}
+
+// goReplace reads the arguments of a replace directive:
+// old [version] => new [version].
+func goReplace(d goDirective) (GoReplace, bool) {
+	arrow := -1
+	for i, a := range d.args {
+		if a == "=>" {
+			arrow = i
+		}
+	}
+	if arrow < 1 || arrow > 2 || len(d.args)-arrow < 2 || len(d.args)-arrow > 3 {
+		return GoReplace{}, false
+	}
+	r := GoReplace{Old: d.args[0], New: d.args[arrow+1], Line: d.line}
+	if arrow == 2 {
+		r.OldVersion = d.args[1]
+	}
+	if len(d.args)-arrow == 3 {
+		r.NewVersion = d.args[arrow+2]
+	}
+	return r, true
+}
@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// GoWork represents a go.work file.
+type GoWork struct {
+	Go        string
+	Toolchain string
+	Use       []string
+	Replace   []GoReplace
+
+	// Lines holds the 1-based line of the go and toolchain directives,
+	// keyed by directive.
+	Lines map[string]int
+}
+
+// ParseGoWork reads and parses the go, toolchain, use and replace
+// directives of a go.work file.
+func ParseGoWork(root fs.FS, path string) (*GoWork, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	work := &GoWork{Lines: map[string]int{}}
+	for _, d := range goDirectives(string(data)) {
+		switch d.verb {
+		case "go":
+			if len(d.args) > 0 {
+				work.Go = d.args[0]
+				work.Lines["go"] = d.line
+			}
+		case "toolchain":
+			if len(d.args) > 0 {
+				work.Toolchain = strings.TrimPrefix(d.args[0], "go")
+				work.Lines["toolchain"] = d.line
+			}
+		case "use":
+			work.Use = append(work.Use, d.args...)
+		case "replace":
+			if r, ok := goReplace(d); ok {
+				work.Replace = append(work.Replace, r)
+			}
+		}
+	}
+	return work, nil
+}
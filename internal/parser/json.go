@@ -2,9 +2,34 @@ package parser
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/fs"
+	"path"
+
+	"github.com/BurntSushi/toml"
 )
 
+// Workspaces holds the package.json "workspaces" globs. Both the array form
+// and the object form ({"packages": [...]}) used by yarn are accepted.
+type Workspaces []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (w *Workspaces) UnmarshalJSON(data []byte) error {
+	var list []string
+	if err := json.Unmarshal(data, &list); err == nil {
+		*w = list
+		return nil
+	}
+	var obj struct {
+		Packages []string `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	*w = obj.Packages
+	return nil
+}
+
 // PackageJSON represents a Node.js package.json file.
 type PackageJSON struct {
 	Name            string            `json:"name"`
@@ -12,6 +37,7 @@ type PackageJSON struct {
 	Scripts         map[string]string `json:"scripts"`
 	Dependencies    map[string]string `json:"dependencies"`
 	DevDependencies map[string]string `json:"devDependencies"`
+	Workspaces      Workspaces        `json:"workspaces"`
 	Engines         struct {
 		Node string `json:"node"`
 	} `json:"engines"`
@@ -68,3 +94,123 @@ func ParseComposerJSON(root fs.FS, path string) (*ComposerJSON, error) {
 	}
 	return &comp, nil
 }
+
+// AppJSON represents a Heroku app.json file.
+type AppJSON struct {
+	Env        map[string]AppJSONEnv `json:"env"`
+	Buildpacks []struct {
+		URL string `json:"url"`
+	} `json:"buildpacks"`
+	Scripts map[string]string `json:"scripts"`
+}
+
+// AppJSONEnv is an entry of the app.json "env" object, written either as
+// a plain value or as an object.
+type AppJSONEnv struct {
+	Description string `json:"description"`
+	Value       string `json:"value"`
+	Generator   string `json:"generator"`
+
+	// Required defaults to true, as it does on Heroku.
+	Required bool `json:"required"`
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (e *AppJSONEnv) UnmarshalJSON(data []byte) error {
+	var value string
+	if err := json.Unmarshal(data, &value); err == nil {
+		*e = AppJSONEnv{Value: value, Required: true}
+		return nil
+	}
+	type plain AppJSONEnv
+	v := plain{Required: true}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = AppJSONEnv(v)
+	return nil
+}
+
+// ParseAppJSON reads and parses an app.json.
+func ParseAppJSON(root fs.FS, path string) (*AppJSON, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var app AppJSON
+	if err := json.Unmarshal(data, &app); err != nil {
+		return nil, err
+	}
+	return &app, nil
+}
+
+// RailwayConfig represents a Railway railway.json or railway.toml file.
+type RailwayConfig struct {
+	Build struct {
+		Builder      string `json:"builder" toml:"builder"`
+		BuildCommand string `json:"buildCommand" toml:"buildCommand"`
+	} `json:"build" toml:"build"`
+	Deploy struct {
+		StartCommand     string   `json:"startCommand" toml:"startCommand"`
+		PreDeployCommand Commands `json:"preDeployCommand" toml:"preDeployCommand"`
+		HealthcheckPath  string   `json:"healthcheckPath" toml:"healthcheckPath"`
+		CronSchedule     string   `json:"cronSchedule" toml:"cronSchedule"`
+	} `json:"deploy" toml:"deploy"`
+}
+
+// Commands holds commands written either as a single string or as a list.
+type Commands []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (c *Commands) UnmarshalJSON(data []byte) error {
+	var cmd string
+	if err := json.Unmarshal(data, &cmd); err == nil {
+		*c = Commands{cmd}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*c = list
+	return nil
+}
+
+// UnmarshalTOML implements toml.Unmarshaler.
+func (c *Commands) UnmarshalTOML(data any) error {
+	switch v := data.(type) {
+	case string:
+		*c = Commands{v}
+	case []any:
+		*c = make(Commands, 0, len(v))
+		for _, cmd := range v {
+			s, ok := cmd.(string)
+			if !ok {
+				return fmt.Errorf("command must be a string, got %T", cmd)
+			}
+			*c = append(*c, s)
+		}
+	default:
+		return fmt.Errorf("commands must be a string or a list, got %T", data)
+	}
+	return nil
+}
+
+// ParseRailwayConfig reads and parses a railway.json, or a railway.toml
+// when name has a .toml extension.
+func ParseRailwayConfig(root fs.FS, name string) (*RailwayConfig, error) {
+	data, err := fs.ReadFile(root, name)
+	if err != nil {
+		return nil, err
+	}
+	var cfg RailwayConfig
+	if path.Ext(name) == ".toml" {
+		err = toml.Unmarshal(data, &cfg)
+	} else {
+		err = json.Unmarshal(data, &cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
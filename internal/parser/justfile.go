@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"io/fs"
+	"strings"
+)
+
+// Justfile represents the variables and recipes of a justfile. Imports
+// and modules are not followed.
+type Justfile struct {
+	// Vars holds the variables set to a string literal. Those set by
+	// other expressions, such as backtick commands or function calls,
+	// are left out.
+	Vars map[string]string
+
+	// Recipes are in file order.
+	Recipes []JustRecipe
+}
+
+// JustRecipe is a recipe of a justfile.
+type JustRecipe struct {
+	Name string
+
+	// Params are the recipe's parameter names.
+	Params []string
+
+	// Deps are the names of the recipes run before this one.
+	Deps []string
+
+	// Body holds the recipe lines with their indentation removed. A
+	// shebang recipe starts with a "#!" line.
+	Body []string
+
+	// Line is the 1-based line of the recipe header.
+	Line int
+}
+
+// ParseJustfile reads and parses a justfile.
+func ParseJustfile(root fs.FS, path string) (*Justfile, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	jf := &Justfile{Vars: map[string]string{}}
+	var recipe *JustRecipe
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimRight(lines[i], " \t\r")
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimRight(strings.TrimSuffix(line, `\`), " \t") + " " + strings.TrimSpace(lines[i])
+		}
+
+		if line == "" {
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			body := strings.TrimSpace(line)
+			if recipe != nil && (len(recipe.Body) == 0 && strings.HasPrefix(body, "#!") || !strings.HasPrefix(body, "#")) {
+				recipe.Body = append(recipe.Body, body)
+			}
+			continue
+		}
+		if recipe != nil {
+			jf.Recipes = append(jf.Recipes, *recipe)
+			recipe = nil
+		}
+		if line[0] == '#' || line[0] == '[' {
+			continue
+		}
+		word, _, _ := strings.Cut(line, " ")
+		switch word {
+		case "set", "alias", "import", "mod":
+			continue
+		}
+
+		assign := strings.Index(line, ":=")
+		colon := strings.Index(line, ":")
+		if assign != -1 && assign == colon {
+			name := strings.TrimSpace(strings.TrimPrefix(line[:assign], "export "))
+			if v, ok := justString(strings.TrimSpace(line[assign+2:])); ok {
+				jf.Vars[name] = v
+			}
+			continue
+		}
+		if colon == -1 {
+			continue
+		}
+
+		header := strings.Fields(line[:colon])
+		if len(header) == 0 {
+			continue
+		}
+		recipe = &JustRecipe{Name: strings.TrimPrefix(header[0], "@"), Line: start}
+		for _, p := range header[1:] {
+			p, _, _ = strings.Cut(p, "=")
+			recipe.Params = append(recipe.Params, strings.TrimLeft(p, "*+$"))
+		}
+		deps, _, _ := strings.Cut(line[colon+1:], "&&")
+		for _, d := range strings.Fields(deps) {
+			if d = strings.Trim(d, "()"); d != "" && !strings.ContainsAny(d, `"'`) {
+				recipe.Deps = append(recipe.Deps, d)
+			}
+		}
+	}
+	if recipe != nil {
+		jf.Recipes = append(jf.Recipes, *recipe)
+	}
+	return jf, nil
+}
+
+// justString unquotes an expression that is a single string literal.
+func justString(v string) (string, bool) {
+	if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && strings.IndexByte(v[1:], v[0]) == len(v)-2 {
+		return v[1 : len(v)-1], true
+	}
+	return "", false
+}
+
+// Recipe returns the named recipe.
+func (j *Justfile) Recipe(name string) (*JustRecipe, bool) {
+	for i := range j.Recipes {
+		if j.Recipes[i].Name == name {
+			return &j.Recipes[i], true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,147 @@
+package parser
+
+import (
+	"io/fs"
+	"regexp"
+	"strings"
+)
+
+// Makefile represents the variables and rules of a Makefile. Conditionals
+// are not evaluated: the assignments and rules of every branch are kept,
+// and includes are not followed.
+type Makefile struct {
+	// Vars holds the value of each variable as written, unexpanded. A
+	// shell assignment (VAR != cmd) is recorded as $(shell cmd).
+	Vars map[string]string
+
+	// Targets are the explicit rules in file order. Special targets such
+	// as .PHONY and pattern rules are left out.
+	Targets []MakeTarget
+}
+
+// MakeTarget is an explicit rule of a Makefile.
+type MakeTarget struct {
+	Name string
+
+	// Prereqs are the prerequisites as written, unexpanded.
+	Prereqs []string
+
+	// Recipe holds the recipe lines without their leading tab, with
+	// continuations joined.
+	Recipe []string
+
+	// Line is the 1-based line of the rule.
+	Line int
+}
+
+var (
+	makeVarRe  = regexp.MustCompile(`^(?:(?:export|override)\s+)*([A-Za-z_][A-Za-z0-9_.-]*)\s*(\?=|::?=|:::=|\+=|!=|=)\s*(.*)$`)
+	makeRuleRe = regexp.MustCompile(`^([^:=#\t][^:=#]*?)\s*::?(?:\s+(.*))?$`)
+)
+
+// ParseMakefile reads and parses a Makefile.
+func ParseMakefile(root fs.FS, path string) (*Makefile, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(string(data), "\n")
+
+	mf := &Makefile{Vars: map[string]string{}}
+	var rule []int // indexes of the targets the current recipe belongs to
+	inDefine := false
+	for i := 0; i < len(lines); i++ {
+		start := i + 1
+		line := strings.TrimSuffix(lines[i], "\r")
+		for strings.HasSuffix(line, `\`) && i+1 < len(lines) {
+			i++
+			line = strings.TrimRight(strings.TrimSuffix(line, `\`), " \t") + " " + strings.TrimLeft(lines[i], " \t")
+		}
+
+		if inDefine {
+			inDefine = strings.TrimSpace(line) != "endef"
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			recipe := strings.TrimSpace(line)
+			if rule == nil || recipe == "" || strings.HasPrefix(recipe, "#") {
+				continue
+			}
+			for _, t := range rule {
+				mf.Targets[t].Recipe = append(mf.Targets[t].Recipe, recipe)
+			}
+			continue
+		}
+
+		if j := strings.Index(line, "#"); j != -1 {
+			line = line[:j]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		word, _, _ := strings.Cut(line, " ")
+		switch word {
+		case "define":
+			inDefine = true
+			rule = nil
+			continue
+		case "ifeq", "ifneq", "ifdef", "ifndef", "else", "endif", "include", "-include", "sinclude", "unexport", "vpath":
+			continue
+		}
+
+		if m := makeVarRe.FindStringSubmatch(line); m != nil {
+			rule = nil
+			name, op, value := m[1], m[2], m[3]
+			switch op {
+			case "?=":
+				if _, ok := mf.Vars[name]; !ok {
+					mf.Vars[name] = value
+				}
+			case "+=":
+				if prev := mf.Vars[name]; prev != "" {
+					value = prev + " " + value
+				}
+				mf.Vars[name] = value
+			case "!=":
+				mf.Vars[name] = "$(shell " + value + ")"
+			default:
+				mf.Vars[name] = value
+			}
+			continue
+		}
+
+		m := makeRuleRe.FindStringSubmatch(line)
+		if m == nil {
+			rule = nil
+			continue
+		}
+		prereqs, inline, _ := strings.Cut(m[2], ";")
+		rule = rule[:0:0]
+		for _, name := range strings.Fields(m[1]) {
+			if strings.HasPrefix(name, ".") || strings.Contains(name, "%") {
+				continue
+			}
+			t := MakeTarget{Name: name, Line: start}
+			if !strings.Contains(prereqs, "=") {
+				t.Prereqs = strings.Fields(strings.ReplaceAll(prereqs, "|", " "))
+			}
+			if inline = strings.TrimSpace(inline); inline != "" {
+				t.Recipe = append(t.Recipe, inline)
+			}
+			rule = append(rule, len(mf.Targets))
+			mf.Targets = append(mf.Targets, t)
+		}
+	}
+	return mf, nil
+}
+
+// Target returns the first rule for the named target.
+func (m *Makefile) Target(name string) (*MakeTarget, bool) {
+	for i := range m.Targets {
+		if m.Targets[i].Name == name {
+			return &m.Targets[i], true
+		}
+	}
+	return nil, false
+}
@@ -58,8 +58,23 @@ type CargoTOML struct {
 		Name    string `toml:"name"`
 		Version string `toml:"version"`
 		Edition string `toml:"edition"`
+
+		// RustVersion is the minimum supported Rust version, or a table
+		// when it is inherited from the workspace.
+		RustVersion any `toml:"rust-version"`
 	} `toml:"package"`
 	Dependencies map[string]any `toml:"dependencies"`
+	Bin          []CargoBin     `toml:"bin"`
+	Workspace    struct {
+		Members []string `toml:"members"`
+		Exclude []string `toml:"exclude"`
+	} `toml:"workspace"`
+}
+
+// CargoBin represents a [[bin]] target in Cargo.toml.
+type CargoBin struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
 }
 
 // HasDep checks if a cargo dependency exists.
@@ -100,3 +115,86 @@ func ParseRustToolchainTOML(root fs.FS, path string) (*RustToolchainTOML, error)
 	}
 	return &tc, nil
 }
+
+// FlyTOML represents a Fly.io fly.toml file.
+type FlyTOML struct {
+	Build struct {
+		Dockerfile string            `toml:"dockerfile"`
+		Image      string            `toml:"image"`
+		Builder    string            `toml:"builder"`
+		Args       map[string]string `toml:"args"`
+	} `toml:"build"`
+	Deploy struct {
+		ReleaseCommand string `toml:"release_command"`
+	} `toml:"deploy"`
+	Env         map[string]any      `toml:"env"`
+	Processes   map[string]string   `toml:"processes"`
+	HTTPService *FlyService         `toml:"http_service"`
+	Services    []FlyService        `toml:"services"`
+	Checks      map[string]FlyCheck `toml:"checks"`
+}
+
+// FlyService is the [http_service] table or a [[services]] entry of a
+// fly.toml.
+type FlyService struct {
+	InternalPort int      `toml:"internal_port"`
+	Processes    []string `toml:"processes"`
+
+	// Checks are the [[http_service.checks]] of an [http_service] and
+	// HTTPChecks the [[services.http_checks]] of a [[services]] entry.
+	Checks     []FlyCheck `toml:"checks"`
+	HTTPChecks []FlyCheck `toml:"http_checks"`
+}
+
+// FlyCheck is a health check of a fly.toml. Path is empty for TCP checks.
+type FlyCheck struct {
+	Type string `toml:"type"`
+	Port int    `toml:"port"`
+	Path string `toml:"path"`
+}
+
+// ParseFlyTOML reads and parses a fly.toml.
+func ParseFlyTOML(root fs.FS, path string) (*FlyTOML, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var fly FlyTOML
+	if err := toml.Unmarshal(data, &fly); err != nil {
+		return nil, err
+	}
+	return &fly, nil
+}
+
+// NixpacksTOML represents a nixpacks.toml file, as used by Railway and
+// other platforms building with Nixpacks.
+type NixpacksTOML struct {
+	Providers []string                 `toml:"providers"`
+	Variables map[string]any           `toml:"variables"`
+	Phases    map[string]NixpacksPhase `toml:"phases"`
+	Start     struct {
+		Cmd string `toml:"cmd"`
+	} `toml:"start"`
+}
+
+// NixpacksPhase is a [phases.<name>] table of a nixpacks.toml. The
+// entry "..." in a list stands for the provider's defaults.
+type NixpacksPhase struct {
+	Cmds    []string `toml:"cmds"`
+	NixPkgs []string `toml:"nixPkgs"`
+	NixLibs []string `toml:"nixLibs"`
+	AptPkgs []string `toml:"aptPkgs"`
+}
+
+// ParseNixpacksTOML reads and parses a nixpacks.toml.
+func ParseNixpacksTOML(root fs.FS, path string) (*NixpacksTOML, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var np NixpacksTOML
+	if err := toml.Unmarshal(data, &np); err != nil {
+		return nil, err
+	}
+	return &np, nil
+}
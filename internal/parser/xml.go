@@ -11,7 +11,11 @@ type PomXML struct {
 	GroupID    string         `xml:"groupId"`
 	ArtifactID string        `xml:"artifactId"`
 	Version    string         `xml:"version"`
+	Packaging  string         `xml:"packaging"`
 	Properties PomProperties  `xml:"properties"`
+	Modules    struct {
+		Module []string `xml:"module"`
+	} `xml:"modules"`
 	Dependencies struct {
 		Dependency []PomDependency `xml:"dependency"`
 	} `xml:"dependencies"`
@@ -0,0 +1,389 @@
+package parser
+
+import (
+	"io/fs"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PnpmWorkspace represents a pnpm-workspace.yaml file.
+type PnpmWorkspace struct {
+	Packages []string `yaml:"packages"`
+}
+
+// ParsePnpmWorkspace reads and parses a pnpm-workspace.yaml.
+func ParsePnpmWorkspace(root fs.FS, path string) (*PnpmWorkspace, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var ws PnpmWorkspace
+	if err := yaml.Unmarshal(data, &ws); err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+// ComposeFile represents a docker-compose.yml or compose.yaml file.
+type ComposeFile struct {
+	// Services are listed in file order.
+	Services []ComposeService
+}
+
+// ComposeService is a service of a compose file.
+type ComposeService struct {
+	Name  string
+	Image string
+
+	// Build is the build context directory, set for services built from
+	// the repository rather than pulled as an image.
+	Build string
+
+	// Ports are the published ports in short syntax, e.g. "5433:5432".
+	Ports []string
+
+	// Environment is the service's environment, in file order.
+	Environment []ComposeVar
+
+	// Line is the 1-based line of the service's key.
+	Line int
+}
+
+// ComposeVar is an environment variable of a compose service. Value is
+// empty for a variable passed through from the host.
+type ComposeVar struct {
+	Name  string
+	Value string
+	Line  int
+}
+
+// ParseCompose reads and parses the services of a compose file.
+func ParseCompose(root fs.FS, path string) (*ComposeFile, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	cf := &ComposeFile{}
+	services := mappingValue(&doc, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return cf, nil
+	}
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		key, node := services.Content[i], services.Content[i+1]
+		s := ComposeService{Name: key.Value, Line: key.Line}
+		if v := mappingValue(node, "image"); v != nil {
+			s.Image = v.Value
+		}
+		if v := mappingValue(node, "build"); v != nil {
+			s.Build = v.Value
+			if ctx := mappingValue(v, "context"); ctx != nil {
+				s.Build = ctx.Value
+			} else if v.Kind == yaml.MappingNode {
+				s.Build = "."
+			}
+		}
+		if v := mappingValue(node, "ports"); v != nil {
+			for _, p := range v.Content {
+				if p.Kind == yaml.MappingNode {
+					target, published := mappingValue(p, "target"), mappingValue(p, "published")
+					switch {
+					case target != nil && published != nil:
+						s.Ports = append(s.Ports, published.Value+":"+target.Value)
+					case target != nil:
+						s.Ports = append(s.Ports, target.Value)
+					}
+					continue
+				}
+				s.Ports = append(s.Ports, p.Value)
+			}
+		}
+		if v := mappingValue(node, "environment"); v != nil {
+			switch v.Kind {
+			case yaml.MappingNode:
+				for j := 0; j+1 < len(v.Content); j += 2 {
+					s.Environment = append(s.Environment, ComposeVar{Name: v.Content[j].Value, Value: v.Content[j+1].Value, Line: v.Content[j].Line})
+				}
+			case yaml.SequenceNode:
+				for _, e := range v.Content {
+					name, value, _ := strings.Cut(e.Value, "=")
+					s.Environment = append(s.Environment, ComposeVar{Name: name, Value: value, Line: e.Line})
+				}
+			}
+		}
+		cf.Services = append(cf.Services, s)
+	}
+	return cf, nil
+}
+
+// mappingValue returns the value of key in a mapping node, looking
+// through a document node.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// RenderYAML represents a Render blueprint, render.yaml.
+type RenderYAML struct {
+	Services []RenderService `yaml:"services"`
+}
+
+// RenderService is a service of a Render blueprint.
+type RenderService struct {
+	Type             string         `yaml:"type"`
+	Name             string         `yaml:"name"`
+	RootDir          string         `yaml:"rootDir"`
+	BuildCommand     string         `yaml:"buildCommand"`
+	StartCommand     string         `yaml:"startCommand"`
+	PreDeployCommand string         `yaml:"preDeployCommand"`
+	HealthCheckPath  string         `yaml:"healthCheckPath"`
+	EnvVars          []RenderEnvVar `yaml:"envVars"`
+
+	// Lines holds the 1-based line of each key of the service.
+	Lines map[string]int `yaml:"-"`
+}
+
+// RenderEnvVar is an entry of a Render service's envVars. Variables
+// without a value are set in the dashboard (sync: false), generated, or
+// taken from another service or database.
+type RenderEnvVar struct {
+	Key   string `yaml:"key"`
+	Value string `yaml:"value"`
+	Line  int    `yaml:"-"`
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (s *RenderService) UnmarshalYAML(node *yaml.Node) error {
+	type plain RenderService
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.Lines = make(map[string]int, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		s.Lines[node.Content[i].Value] = node.Content[i].Line
+	}
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (v *RenderEnvVar) UnmarshalYAML(node *yaml.Node) error {
+	type plain RenderEnvVar
+	if err := node.Decode((*plain)(v)); err != nil {
+		return err
+	}
+	v.Line = node.Line
+	return nil
+}
+
+// ParseRenderYAML reads and parses a render.yaml.
+func ParseRenderYAML(root fs.FS, path string) (*RenderYAML, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var r RenderYAML
+	if err := yaml.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// GoReleaser represents the builds of a GoReleaser config,
+// .goreleaser.yaml.
+type GoReleaser struct {
+	Builds []GoReleaserBuild `yaml:"builds"`
+}
+
+// GoReleaserBuild is a build of a GoReleaser config. Values may hold
+// templates such as {{ .Version }}, left as written.
+type GoReleaserBuild struct {
+	ID string `yaml:"id"`
+
+	// Builder is "go" or empty for a Go build.
+	Builder string `yaml:"builder"`
+
+	// Main is the package or file built, "." by default.
+	Main    string     `yaml:"main"`
+	Binary  string     `yaml:"binary"`
+	Ldflags StringList `yaml:"ldflags"`
+	Flags   StringList `yaml:"flags"`
+	Tags    StringList `yaml:"tags"`
+	Env     []string   `yaml:"env"`
+
+	// Lines holds the 1-based line of each key of the build.
+	Lines map[string]int `yaml:"-"`
+}
+
+// StringList is a YAML value written as a single string or a list of
+// strings.
+type StringList []string
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (b *GoReleaserBuild) UnmarshalYAML(node *yaml.Node) error {
+	type plain GoReleaserBuild
+	if err := node.Decode((*plain)(b)); err != nil {
+		return err
+	}
+	b.Lines = make(map[string]int, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		b.Lines[node.Content[i].Value] = node.Content[i].Line
+	}
+	return nil
+}
+
+// ParseGoReleaser reads and parses a GoReleaser config.
+func ParseGoReleaser(root fs.FS, path string) (*GoReleaser, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var g GoReleaser
+	if err := yaml.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// Taskfile represents a Taskfile.yml of the Task runner. Includes are not
+// followed.
+type Taskfile struct {
+	Vars map[string]TaskfileVar
+
+	// Tasks are in file order.
+	Tasks []TaskfileTask
+}
+
+// TaskfileVar is a variable of a Taskfile. Sh is set for a dynamic
+// variable, whose value is the output of a shell command.
+type TaskfileVar struct {
+	Value string
+	Sh    string
+}
+
+// TaskfileTask is a task of a Taskfile.
+type TaskfileTask struct {
+	Name string
+
+	// Deps are the names of the tasks run before this one.
+	Deps []string
+
+	// Cmds are the task's commands in order.
+	Cmds []TaskfileCmd
+
+	// Dir is the directory the commands run in, if not the Taskfile's.
+	Dir string
+
+	// Line is the 1-based line of the task's key.
+	Line int
+}
+
+// TaskfileCmd is a command of a task: either a shell command or a call of
+// another task.
+type TaskfileCmd struct {
+	Cmd  string
+	Task string
+}
+
+// ParseTaskfile reads and parses a Taskfile.yml.
+func ParseTaskfile(root fs.FS, path string) (*Taskfile, error) {
+	data, err := fs.ReadFile(root, path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	tf := &Taskfile{Vars: map[string]TaskfileVar{}}
+	if vars := mappingValue(&doc, "vars"); vars != nil {
+		for i := 0; i+1 < len(vars.Content); i += 2 {
+			v := vars.Content[i+1]
+			if sh := mappingValue(v, "sh"); sh != nil {
+				tf.Vars[vars.Content[i].Value] = TaskfileVar{Sh: sh.Value}
+			} else {
+				tf.Vars[vars.Content[i].Value] = TaskfileVar{Value: v.Value}
+			}
+		}
+	}
+	tasks := mappingValue(&doc, "tasks")
+	if tasks == nil {
+		return tf, nil
+	}
+	for i := 0; i+1 < len(tasks.Content); i += 2 {
+		key, node := tasks.Content[i], tasks.Content[i+1]
+		t := TaskfileTask{Name: key.Value, Line: key.Line}
+		cmds := node
+		switch node.Kind {
+		case yaml.ScalarNode:
+			t.Cmds = append(t.Cmds, TaskfileCmd{Cmd: node.Value})
+		case yaml.MappingNode:
+			if v := mappingValue(node, "dir"); v != nil {
+				t.Dir = v.Value
+			}
+			if v := mappingValue(node, "deps"); v != nil {
+				for _, d := range v.Content {
+					if task := mappingValue(d, "task"); task != nil {
+						d = task
+					}
+					t.Deps = append(t.Deps, d.Value)
+				}
+			}
+			if v := mappingValue(node, "cmd"); v != nil {
+				t.Cmds = append(t.Cmds, TaskfileCmd{Cmd: v.Value})
+			}
+			cmds = mappingValue(node, "cmds")
+		}
+		if cmds != nil && cmds.Kind == yaml.SequenceNode {
+			for _, c := range cmds.Content {
+				switch {
+				case c.Kind == yaml.ScalarNode:
+					t.Cmds = append(t.Cmds, TaskfileCmd{Cmd: c.Value})
+				case mappingValue(c, "cmd") != nil:
+					t.Cmds = append(t.Cmds, TaskfileCmd{Cmd: mappingValue(c, "cmd").Value})
+				case mappingValue(c, "task") != nil:
+					t.Cmds = append(t.Cmds, TaskfileCmd{Task: mappingValue(c, "task").Value})
+				}
+			}
+		}
+		tf.Tasks = append(tf.Tasks, t)
+	}
+	return tf, nil
+}
+
+// Task returns the named task.
+func (t *Taskfile) Task(name string) (*TaskfileTask, bool) {
+	for i := range t.Tasks {
+		if t.Tasks[i].Name == name {
+			return &t.Tasks[i], true
+		}
+	}
+	return nil, false
+}
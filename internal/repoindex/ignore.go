@@ -0,0 +1,147 @@
+package repoindex
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ignoreRule is a single compiled .gitignore pattern.
+type ignoreRule struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// ignoreFile holds the rules of one .gitignore, which apply to paths below
+// the directory it lives in.
+type ignoreFile struct {
+	dir   string
+	rules []ignoreRule
+}
+
+// parseIgnore compiles the contents of a .gitignore located in dir.
+func parseIgnore(dir, content string) ignoreFile {
+	f := ignoreFile{dir: dir}
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		var rule ignoreRule
+		if strings.HasPrefix(line, "!") {
+			rule.negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			rule.dirOnly = true
+			line = strings.TrimRight(line, "/")
+		}
+		if line == "" {
+			continue
+		}
+
+		// A pattern containing a slash is relative to the .gitignore
+		// directory; otherwise it matches at any depth.
+		anchored := strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+		expr := globToRegexp(line)
+		if !anchored {
+			expr = "(?:.*/)?" + expr
+		}
+		re, err := regexp.Compile("^" + expr + "$")
+		if err != nil {
+			continue
+		}
+		rule.re = re
+		f.rules = append(f.rules, rule)
+	}
+	return f
+}
+
+// match reports whether the rules decide on name (relative to the index
+// root), and if so whether it is ignored. Later rules win.
+func (f ignoreFile) match(name string, isDir bool) (ignored, decided bool) {
+	rel := name
+	if f.dir != "." {
+		if !strings.HasPrefix(name, f.dir+"/") {
+			return false, false
+		}
+		rel = strings.TrimPrefix(name, f.dir+"/")
+	}
+	for i := len(f.rules) - 1; i >= 0; i-- {
+		r := f.rules[i]
+		if r.dirOnly && !isDir {
+			continue
+		}
+		if r.re.MatchString(rel) {
+			return !r.negate, true
+		}
+	}
+	return false, false
+}
+
+// ignored reports whether name is excluded by the given .gitignore files,
+// ordered from the root down; deeper files take precedence.
+func ignored(files []ignoreFile, name string, isDir bool) bool {
+	for i := len(files) - 1; i >= 0; i-- {
+		if ign, ok := files[i].match(name, isDir); ok {
+			return ign
+		}
+	}
+	return false
+}
+
+// globToRegexp translates a glob to a regular expression. "*" and "?" do
+// not cross directory boundaries; a "**" segment matches any number of
+// directories.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			b.WriteString("(?:/.*)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// compileGlob compiles a glob matched against the whole path.
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^" + globToRegexp(path.Clean(pattern)) + "$")
+}
@@ -0,0 +1,354 @@
+// Package repoindex builds a cached, ignore-aware view of a repository in
+// a single walk. The resulting Index implements flkr.Index and is handed to
+// detectors in place of the raw filesystem.
+package repoindex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// DefaultSkipDirs are directory names whose contents are never indexed:
+// dependency caches and build outputs that are large and irrelevant to
+// detection. The directories themselves stay visible, so detectors can
+// still tell that e.g. a Go vendor directory exists.
+var DefaultSkipDirs = []string{".git", ".venv", "__pycache__", "node_modules", "target", "vendor"}
+
+// DefaultCacheLimit is the largest file kept in the read cache.
+const DefaultCacheLimit = 1 << 20
+
+// Options configures how an index is built.
+type Options struct {
+	// SkipDirs lists directory names whose contents are not indexed.
+	// Nil uses DefaultSkipDirs.
+	SkipDirs []string
+
+	// CacheLimit is the largest file size, in bytes, whose contents are
+	// cached after the first read. Larger files are read from disk each
+	// time. Zero uses DefaultCacheLimit.
+	CacheLimit int64
+}
+
+// entry is an indexed file or directory.
+type entry struct {
+	info     fs.FileInfo
+	children []fs.DirEntry
+}
+
+// snapshot is the state shared by an index and its sub-indexes.
+type snapshot struct {
+	fsys       fs.FS
+	entries    map[string]*entry
+	files      []string
+	skip       map[string]bool
+	cacheLimit int64
+
+	mu    sync.Mutex
+	cache map[string][]byte
+}
+
+// Index is a read-only view of a repository. It implements flkr.Index.
+type Index struct {
+	s      *snapshot
+	prefix string
+}
+
+var _ flkr.Index = (*Index)(nil)
+
+// Build walks fsys once and returns its index. Files and directories
+// excluded by .gitignore (and .git/info/exclude) are left out, matching
+// what a Nix flake sees of a git repository.
+func Build(ctx context.Context, fsys fs.FS, opts Options) (*Index, error) {
+	skipDirs := opts.SkipDirs
+	if skipDirs == nil {
+		skipDirs = DefaultSkipDirs
+	}
+	s := &snapshot{
+		fsys:       fsys,
+		entries:    map[string]*entry{},
+		skip:       map[string]bool{},
+		cacheLimit: opts.CacheLimit,
+		cache:      map[string][]byte{},
+	}
+	if s.cacheLimit == 0 {
+		s.cacheLimit = DefaultCacheLimit
+	}
+	for _, d := range skipDirs {
+		s.skip[d] = true
+	}
+
+	info, err := fs.Stat(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	s.entries["."] = &entry{info: info}
+
+	var ignores []ignoreFile
+	if data, err := fs.ReadFile(fsys, ".git/info/exclude"); err == nil {
+		ignores = append(ignores, parseIgnore(".", string(data)))
+	}
+	if err := s.walk(ctx, ".", ignores); err != nil {
+		return nil, err
+	}
+	sort.Strings(s.files)
+	return &Index{s: s, prefix: "."}, nil
+}
+
+// walk indexes dir and everything below it that is not ignored.
+func (s *snapshot) walk(ctx context.Context, dir string, ignores []ignoreFile) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	dirEntries, err := fs.ReadDir(s.fsys, dir)
+	if err != nil {
+		if dir == "." {
+			return err
+		}
+		// Unreadable subdirectories are indexed as empty.
+		return nil
+	}
+	if data, err := fs.ReadFile(s.fsys, join(dir, ".gitignore")); err == nil {
+		ignores = append(ignores[:len(ignores):len(ignores)], parseIgnore(dir, string(data)))
+	}
+
+	var children []fs.DirEntry
+	for _, d := range dirEntries {
+		name := join(dir, d.Name())
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		symlink := info.Mode()&fs.ModeSymlink != 0
+		if symlink {
+			if info, err = fs.Stat(s.fsys, name); err != nil {
+				continue
+			}
+		}
+		if ignored(ignores, name, info.IsDir()) {
+			continue
+		}
+
+		s.entries[name] = &entry{info: info}
+		children = append(children, fs.FileInfoToDirEntry(info))
+		switch {
+		case !info.IsDir():
+			s.files = append(s.files, name)
+		case symlink || s.skip[d.Name()]:
+			// Linked directories are not followed, to avoid cycles.
+		default:
+			if err := s.walk(ctx, name, ignores); err != nil {
+				return err
+			}
+		}
+	}
+	s.entries[dir].children = children
+	return nil
+}
+
+func join(dir, name string) string {
+	if dir == "." {
+		return name
+	}
+	return dir + "/" + name
+}
+
+// resolve maps a name relative to the index onto the snapshot.
+func (idx *Index) resolve(op, name string) (string, *entry, error) {
+	if !fs.ValidPath(name) {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	full := name
+	if idx.prefix != "." {
+		full = path.Join(idx.prefix, name)
+	}
+	e, ok := idx.s.entries[full]
+	if !ok {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+	}
+	return full, e, nil
+}
+
+// rel returns full relative to the index, or false if it lies outside.
+func (idx *Index) rel(full string) (string, bool) {
+	if idx.prefix == "." {
+		return full, true
+	}
+	if full == idx.prefix {
+		return ".", true
+	}
+	if !strings.HasPrefix(full, idx.prefix+"/") {
+		return "", false
+	}
+	return full[len(idx.prefix)+1:], true
+}
+
+// Open opens the named file or directory.
+func (idx *Index) Open(name string) (fs.File, error) {
+	full, e, err := idx.resolve("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.info.IsDir() {
+		return &dirFile{name: name, entry: e}, nil
+	}
+	return idx.s.fsys.Open(full)
+}
+
+// ReadFile returns the contents of the named file, caching small files.
+func (idx *Index) ReadFile(name string) ([]byte, error) {
+	full, e, err := idx.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.info.IsDir() {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: errors.New("is a directory")}
+	}
+
+	idx.s.mu.Lock()
+	data, ok := idx.s.cache[full]
+	idx.s.mu.Unlock()
+	if !ok {
+		if data, err = fs.ReadFile(idx.s.fsys, full); err != nil {
+			return nil, err
+		}
+		if int64(len(data)) <= idx.s.cacheLimit {
+			idx.s.mu.Lock()
+			idx.s.cache[full] = data
+			idx.s.mu.Unlock()
+		}
+	}
+	return append([]byte(nil), data...), nil
+}
+
+// ReadFileLimit reads the named file unless it is larger than limit bytes.
+func (idx *Index) ReadFileLimit(name string, limit int64) ([]byte, error) {
+	_, e, err := idx.resolve("read", name)
+	if err != nil {
+		return nil, err
+	}
+	if e.info.Size() > limit {
+		return nil, &fs.PathError{
+			Op:   "read",
+			Path: name,
+			Err:  fmt.Errorf("%w: %d bytes exceeds %d", flkr.ErrFileTooLarge, e.info.Size(), limit),
+		}
+	}
+	return idx.ReadFile(name)
+}
+
+// ReadDir returns the indexed entries of the named directory.
+func (idx *Index) ReadDir(name string) ([]fs.DirEntry, error) {
+	_, e, err := idx.resolve("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !e.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: errors.New("not a directory")}
+	}
+	return append([]fs.DirEntry(nil), e.children...), nil
+}
+
+// Stat returns file information for the named file or directory.
+func (idx *Index) Stat(name string) (fs.FileInfo, error) {
+	_, e, err := idx.resolve("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return e.info, nil
+}
+
+// Glob returns the indexed files and directories matching pattern, using
+// path.Match syntax.
+func (idx *Index) Glob(pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	var matches []string
+	for full := range idx.s.entries {
+		name, ok := idx.rel(full)
+		if !ok {
+			continue
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			matches = append(matches, name)
+		}
+	}
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// Sub returns an index rooted at dir sharing this index's cache.
+func (idx *Index) Sub(dir string) (fs.FS, error) {
+	full, e, err := idx.resolve("sub", dir)
+	if err != nil {
+		return nil, err
+	}
+	if !e.info.IsDir() {
+		return nil, &fs.PathError{Op: "sub", Path: dir, Err: errors.New("not a directory")}
+	}
+	return &Index{s: idx.s, prefix: full}, nil
+}
+
+// Files returns the paths of all indexed regular files, sorted.
+func (idx *Index) Files() []string {
+	var files []string
+	for _, full := range idx.s.files {
+		if name, ok := idx.rel(full); ok {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// Match returns the indexed files matching pattern, sorted. A "**" segment
+// matches any number of directories.
+func (idx *Index) Match(pattern string) []string {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return nil
+	}
+	var matches []string
+	for _, name := range idx.Files() {
+		if re.MatchString(name) {
+			matches = append(matches, name)
+		}
+	}
+	return matches
+}
+
+// dirFile is an open indexed directory.
+type dirFile struct {
+	name   string
+	entry  *entry
+	offset int
+}
+
+func (d *dirFile) Stat() (fs.FileInfo, error) { return d.entry.info, nil }
+func (d *dirFile) Close() error               { return nil }
+
+func (d *dirFile) Read([]byte) (int, error) {
+	return 0, &fs.PathError{Op: "read", Path: d.name, Err: errors.New("is a directory")}
+}
+
+func (d *dirFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	rest := d.entry.children[d.offset:]
+	if n <= 0 {
+		d.offset += len(rest)
+		return append([]fs.DirEntry(nil), rest...), nil
+	}
+	if len(rest) == 0 {
+		return nil, io.EOF
+	}
+	n = min(n, len(rest))
+	d.offset += n
+	return append([]fs.DirEntry(nil), rest[:n]...), nil
+}
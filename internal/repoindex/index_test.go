@@ -0,0 +1,158 @@
+package repoindex
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRepo() fstest.MapFS {
+	return fstest.MapFS{
+		".gitignore":              &fstest.MapFile{Data: []byte("# build output\n/dist\n*.log\n!keep.log\ntmp/\n")},
+		"go.mod":                  &fstest.MapFile{Data: []byte("module myapp\n")},
+		"main.go":                 &fstest.MapFile{Data: []byte("package main\n")},
+		"debug.log":               &fstest.MapFile{Data: []byte("noise")},
+		"keep.log":                &fstest.MapFile{Data: []byte("kept")},
+		"dist/app.js":             &fstest.MapFile{Data: []byte("bundle")},
+		"web/dist/index.html":     &fstest.MapFile{Data: []byte("<html>")},
+		"web/tmp/cache":           &fstest.MapFile{Data: []byte("x")},
+		"web/.gitignore":          &fstest.MapFile{Data: []byte("generated.ts\n")},
+		"web/src/generated.ts":    &fstest.MapFile{Data: []byte("// generated")},
+		"web/src/app.ts":          &fstest.MapFile{Data: []byte("export {}")},
+		"web/node_modules/x/a.js": &fstest.MapFile{Data: []byte("dep")},
+		"vendor/modules.txt":      &fstest.MapFile{Data: []byte("# vendored")},
+		".git/info/exclude":       &fstest.MapFile{Data: []byte("local.env\n")},
+		"local.env":               &fstest.MapFile{Data: []byte("SECRET=1")},
+	}
+}
+
+func TestBuild_IgnoreRules(t *testing.T) {
+	idx, err := Build(context.Background(), testRepo(), Options{})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		".gitignore",
+		"go.mod",
+		"keep.log",
+		"main.go",
+		"web/.gitignore",
+		"web/dist/index.html",
+		"web/src/app.ts",
+	}, idx.Files())
+
+	// Skipped directories exist but are empty.
+	info, err := fs.Stat(idx, "vendor")
+	require.NoError(t, err)
+	assert.True(t, info.IsDir())
+	entries, err := fs.ReadDir(idx, "web/node_modules")
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = fs.Stat(idx, ".git")
+	require.NoError(t, err)
+	_, err = fs.Stat(idx, "dist")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestIndex_FS(t *testing.T) {
+	idx, err := Build(context.Background(), testRepo(), Options{})
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(idx, "go.mod", "web/src/app.ts", "vendor"))
+
+	sub, err := fs.Sub(idx, "web")
+	require.NoError(t, err)
+	require.NoError(t, fstest.TestFS(sub, "src/app.ts", "dist/index.html"))
+
+	subIdx, ok := sub.(flkr.Index)
+	require.True(t, ok)
+	assert.Equal(t, []string{"src/app.ts"}, subIdx.Match("**/*.ts"))
+	assert.Equal(t, []string{".gitignore", "dist/index.html", "src/app.ts"}, subIdx.Match("**"))
+
+	matches, err := fs.Glob(idx, "*.mod")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"go.mod"}, matches)
+	matches, err = fs.Glob(sub, ".")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"."}, matches)
+}
+
+func TestIndex_CachedReads(t *testing.T) {
+	repo := testRepo()
+	idx, err := Build(context.Background(), repo, Options{})
+	require.NoError(t, err)
+
+	data, err := idx.ReadFile("go.mod")
+	require.NoError(t, err)
+	data[0] = 'X'
+
+	// The cache outlives the underlying file and is not aliased.
+	delete(repo, "go.mod")
+	data, err = idx.ReadFile("go.mod")
+	require.NoError(t, err)
+	assert.Equal(t, "module myapp\n", string(data))
+}
+
+func TestIndex_ReadFileLimit(t *testing.T) {
+	idx, err := Build(context.Background(), testRepo(), Options{})
+	require.NoError(t, err)
+
+	data, err := idx.ReadFileLimit("go.mod", 1024)
+	require.NoError(t, err)
+	assert.Equal(t, "module myapp\n", string(data))
+
+	_, err = idx.ReadFileLimit("go.mod", 4)
+	assert.True(t, errors.Is(err, flkr.ErrFileTooLarge))
+}
+
+func TestBuild_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Build(ctx, testRepo(), Options{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestParseIgnore(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		isDir   bool
+		want    bool
+	}{
+		{"*.log", "logs/app.log", false, true},
+		{"/build", "build", true, true},
+		{"/build", "src/build", true, false},
+		{"out/", "out", false, false},
+		{"out/", "pkg/out", true, true},
+		{"docs/*.md", "docs/a.md", false, true},
+		{"docs/*.md", "docs/x/a.md", false, false},
+		{"**/fixtures", "a/b/fixtures", true, true},
+		{"logs/**", "logs/a/b.txt", false, true},
+		{"a/**/z", "a/z", true, true},
+		{"a/**/z", "a/b/c/z", true, true},
+		{"file[0-9].txt", "file3.txt", false, true},
+		{`\#notes`, "#notes", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.name, func(t *testing.T) {
+			f := parseIgnore(".", tt.pattern)
+			assert.Equal(t, tt.want, ignored([]ignoreFile{f}, tt.name, tt.isDir))
+		})
+	}
+}
+
+func TestParseIgnore_NestedAndNegated(t *testing.T) {
+	files := []ignoreFile{
+		parseIgnore(".", "*.env\n!example.env\n"),
+		parseIgnore("api", "example.env\n"),
+	}
+	assert.True(t, ignored(files, "prod.env", false))
+	assert.False(t, ignored(files, "example.env", false))
+	assert.True(t, ignored(files, "api/example.env", false))
+	assert.False(t, ignored(files, "web/example.env", false))
+}
@@ -3,11 +3,13 @@ package tui
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strconv"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/huh"
+	"github.com/narvanalabs/flkr/internal/detector"
 	"github.com/narvanalabs/flkr/internal/generator"
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
@@ -17,6 +19,7 @@ type step int
 
 const (
 	stepDetect  step = iota
+	stepChoose
 	stepReview
 	stepConfirm
 	stepDone
@@ -32,10 +35,21 @@ type Model struct {
 	profile *flkr.AppProfile
 	err     error
 
+	// detected is the profile as detected, before review-form edits.
+	detected flkr.AppProfile
+	pins     []detector.ConfigPin
+
+	// chosen is the language picked when detection was ambiguous.
+	chosen     flkr.Language
+	chooseForm *huh.Form
+	choice     *string
+
 	reviewForm  *huh.Form
 	confirmForm *huh.Form
 	portStr     *string
 	confirmed   *bool
+	saveConfig  *bool
+	configSaved bool
 	preview     string
 	outputPath  string
 }
@@ -46,6 +60,8 @@ func New(path, templateVersion string) Model {
 	s.Spinner = spinner.Dot
 
 	confirmed := false
+	saveConfig := true
+	choice := ""
 
 	return Model{
 		path:            path,
@@ -53,11 +69,13 @@ func New(path, templateVersion string) Model {
 		step:            stepDetect,
 		spinner:         s,
 		confirmed:       &confirmed,
+		saveConfig:      &saveConfig,
+		choice:          &choice,
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return tea.Batch(m.spinner.Tick, runDetection(m.path))
+	return tea.Batch(m.spinner.Tick, runDetection(m.path, ""))
 }
 
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
@@ -71,6 +89,8 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch m.step {
 	case stepDetect:
 		return m.updateDetect(msg)
+	case stepChoose:
+		return m.updateChoose(msg)
 	case stepReview:
 		return m.updateReview(msg)
 	case stepConfirm:
@@ -95,6 +115,13 @@ func (m Model) View() string {
 		}
 		return header + renderDetecting(m.spinner)
 
+	case stepChoose:
+		s := header + renderDetectResult(m.profile)
+		if m.chooseForm != nil {
+			s += m.chooseForm.View()
+		}
+		return s
+
 	case stepReview:
 		s := header + subtitleStyle.Render("  Review detected settings:") + "\n\n"
 		if m.reviewForm != nil {
@@ -112,7 +139,11 @@ func (m Model) View() string {
 
 	case stepDone:
 		if m.confirmed != nil && *m.confirmed {
-			return header + successStyle.Render(fmt.Sprintf("  Wrote %s", m.outputPath)) + "\n\n"
+			s := header + successStyle.Render(fmt.Sprintf("  Wrote %s", m.outputPath)) + "\n"
+			if m.configSaved {
+				s += successStyle.Render(fmt.Sprintf("  Saved changes to %s", filepath.Join(m.path, detector.ConfigFile))) + "\n"
+			}
+			return s + "\n"
 		}
 		return header + dimStyle.Render("  Cancelled.") + "\n\n"
 	}
@@ -134,6 +165,12 @@ func (m Model) updateDetect(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		m.profile = msg.profile
+		if m.profile.Ambiguous() && m.chosen == "" {
+			m.chooseForm = buildChooseForm(m.profile, m.choice)
+			m.step = stepChoose
+			return m, m.chooseForm.Init()
+		}
+		m.detected = *msg.profile
 		portStr := strconv.Itoa(m.profile.Port)
 		m.portStr = &portStr
 		m.reviewForm = buildReviewForm(m.profile, m.portStr)
@@ -147,6 +184,27 @@ func (m Model) updateDetect(msg tea.Msg) (tea.Model, tea.Cmd) {
 	}
 }
 
+func (m Model) updateChoose(msg tea.Msg) (tea.Model, tea.Cmd) {
+	form, cmd := m.chooseForm.Update(msg)
+	if f, ok := form.(*huh.Form); ok {
+		m.chooseForm = f
+	}
+
+	if m.chooseForm.State == huh.StateCompleted {
+		m.chosen = flkr.Language(*m.choice)
+		m.profile = nil
+		m.step = stepDetect
+		return m, tea.Batch(m.spinner.Tick, runDetection(m.path, m.chosen))
+	}
+
+	if m.chooseForm.State == huh.StateAborted {
+		m.step = stepDone
+		return m, tea.Quit
+	}
+
+	return m, cmd
+}
+
 func (m Model) updateReview(msg tea.Msg) (tea.Model, tea.Cmd) {
 	form, cmd := m.reviewForm.Update(msg)
 	if f, ok := form.(*huh.Form); ok {
@@ -155,6 +213,13 @@ func (m Model) updateReview(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	if m.reviewForm.State == huh.StateCompleted {
 		applyFormValues(m.profile, *m.portStr)
+		if m.profile.Language != m.detected.Language || m.profile.Version != m.detected.Version {
+			if m.profile.Version != m.detected.Version {
+				// A version typed in replaces the constraint it was read from.
+				m.profile.VersionConstraint = ""
+			}
+			detector.ResolveToolchain(m.profile)
+		}
 
 		preview, err := generatePreview(m.profile, m.templateVersion)
 		if err != nil {
@@ -163,7 +228,14 @@ func (m Model) updateReview(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, tea.Quit
 		}
 		m.preview = preview
-		m.confirmForm = buildConfirmForm(m.confirmed)
+		m.pins = reviewPins(&m.detected, m.profile)
+		if m.chosen != "" {
+			// Remember the choice so the next run is not ambiguous.
+			if !slices.ContainsFunc(m.pins, func(p detector.ConfigPin) bool { return p.Key == "language" }) {
+				m.pins = append(m.pins, detector.ConfigPin{Key: "language", Value: string(m.chosen)})
+			}
+		}
+		m.confirmForm = buildConfirmForm(m.confirmed, m.saveConfig, m.pins)
 		m.step = stepConfirm
 		return m, m.confirmForm.Init()
 	}
@@ -193,6 +265,13 @@ func (m Model) updateConfirm(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if err != nil {
 				m.err = err
 			}
+			if err == nil && len(m.pins) > 0 && *m.saveConfig {
+				if err := detector.SaveConfigPins(m.path, m.pins); err != nil {
+					m.err = err
+				} else {
+					m.configSaved = true
+				}
+			}
 		}
 		m.step = stepDone
 		return m, tea.Quit
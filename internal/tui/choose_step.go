@@ -0,0 +1,24 @@
+package tui
+
+import (
+	"github.com/charmbracelet/huh"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+// buildChooseForm asks which of several equally likely stacks is the app.
+func buildChooseForm(profile *flkr.AppProfile, choice *string) *huh.Form {
+	var options []huh.Option[string]
+	for _, c := range profile.Candidates() {
+		options = append(options, huh.NewOption(c.String(), string(c.Language)))
+	}
+
+	return huh.NewForm(
+		huh.NewGroup(
+			huh.NewSelect[string]().
+				Title("Which one is the app?").
+				Description("Several stacks match almost equally.").
+				Options(options...).
+				Value(choice),
+		),
+	)
+}
@@ -1,7 +1,10 @@
 package tui
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/huh"
+	"github.com/narvanalabs/flkr/internal/detector"
 	"github.com/narvanalabs/flkr/internal/generator"
 	"github.com/narvanalabs/flkr/pkg/flkr"
 )
@@ -19,15 +22,56 @@ func generatePreview(profile *flkr.AppProfile, templateVersion string) (string,
 	return result.FlakeContent, nil
 }
 
-// buildConfirmForm creates a confirmation form.
-func buildConfirmForm(confirmed *bool) *huh.Form {
-	return huh.NewForm(
-		huh.NewGroup(
-			huh.NewConfirm().
-				Title("Write flake.nix?").
-				Affirmative("Yes").
-				Negative("No").
-				Value(confirmed),
-		),
-	)
+// buildConfirmForm creates a confirmation form. When the review step
+// changed detected values, it also asks whether to pin them in flkr.toml
+// so the next regeneration keeps them.
+func buildConfirmForm(confirmed, saveConfig *bool, pins []detector.ConfigPin) *huh.Form {
+	fields := []huh.Field{
+		huh.NewConfirm().
+			Title("Write flake.nix?").
+			Affirmative("Yes").
+			Negative("No").
+			Value(confirmed),
+	}
+	if len(pins) > 0 {
+		desc := "Pinned values survive the next flkr generate."
+		var cleared []string
+		for _, p := range pins {
+			if p.Value == nil {
+				cleared = append(cleared, p.Key)
+			}
+		}
+		if len(cleared) > 0 {
+			desc += " A field can't be pinned empty: " + strings.Join(cleared, ", ") + " will be unpinned and detected again."
+		}
+		fields = append(fields, huh.NewConfirm().
+			Title("Save your changes to "+detector.ConfigFile+"?").
+			Description(desc).
+			Affirmative("Yes").
+			Negative("No").
+			Value(saveConfig))
+	}
+	return huh.NewForm(huh.NewGroup(fields...))
+}
+
+// reviewPins returns the flkr.toml keys for the fields the user changed in
+// the review form, or nil if nothing changed. A field cleared to empty
+// can't be pinned, so its key is removed instead.
+func reviewPins(detected, reviewed *flkr.AppProfile) []detector.ConfigPin {
+	var pins []detector.ConfigPin
+	pin := func(key string, changed, empty bool, value any) {
+		switch {
+		case !changed:
+		case empty:
+			pins = append(pins, detector.ConfigPin{Key: key})
+		default:
+			pins = append(pins, detector.ConfigPin{Key: key, Value: value})
+		}
+	}
+	pin("language", reviewed.Language != detected.Language, reviewed.Language == "", string(reviewed.Language))
+	pin("version", reviewed.Version != detected.Version, reviewed.Version == "", reviewed.Version)
+	pin("buildCommand", reviewed.BuildCommand != detected.BuildCommand, reviewed.BuildCommand == "", reviewed.BuildCommand)
+	pin("startCommand", reviewed.StartCommand != detected.StartCommand, reviewed.StartCommand == "", reviewed.StartCommand)
+	pin("port", reviewed.Port != detected.Port, reviewed.Port == 0, reviewed.Port)
+	return pins
 }
@@ -19,9 +19,13 @@ type detectResultMsg struct {
 }
 
 // runDetection starts detection in the background and returns a message.
-func runDetection(path string) tea.Cmd {
+// A non-empty language selects that stack when several match.
+func runDetection(path string, language flkr.Language) tea.Cmd {
 	return func() tea.Msg {
 		reg := detector.NewRegistry()
+		if language != "" {
+			reg.SetLanguages(map[string]flkr.Language{".": language})
+		}
 		profile, err := reg.DetectFromPath(context.Background(), path)
 		if err == nil && profile != nil && profile.Language == flkr.LangGo && !profile.HasVendor {
 			absPath, _ := filepath.Abs(path)
@@ -45,6 +49,9 @@ func renderDetectResult(profile *flkr.AppProfile) string {
 	if profile.Version != "" {
 		s += formatField("Version", profile.Version)
 	}
+	if profile.Toolchain != "" {
+		s += formatField("Toolchain", profile.Toolchain)
+	}
 	s += formatField("Package Manager", string(profile.PackageManager))
 	if profile.Framework != "" {
 		s += formatField("Framework", string(profile.Framework))
@@ -58,7 +65,19 @@ func renderDetectResult(profile *flkr.AppProfile) string {
 	if profile.Port != 0 {
 		s += formatField("Port", fmt.Sprintf("%d", profile.Port))
 	}
+	if profile.PortEnv != "" {
+		s += formatField("Port Env", profile.PortEnv)
+	}
+	for _, stage := range profile.Stages {
+		s += formatField("Build Stage", fmt.Sprintf("%s (%s in %s)", stage.Name, stage.BuildCommand, stage.Dir))
+	}
 	s += formatField("Confidence", fmt.Sprintf("%.0f%%", profile.Confidence*100))
+	for _, c := range profile.Alternatives {
+		s += formatField("Also Matches", c.String())
+	}
+	for _, w := range profile.Warnings {
+		s += "  " + dimStyle.Render("warning: "+w) + "\n"
+	}
 	s += "\n"
 	return s
 }
@@ -10,20 +10,16 @@ import (
 
 // buildReviewForm creates a huh form for reviewing/editing the detected profile.
 func buildReviewForm(profile *flkr.AppProfile, portStr *string) *huh.Form {
+	var languages []huh.Option[string]
+	for _, l := range flkr.Languages() {
+		languages = append(languages, huh.NewOption(l.Label, string(l.Language)))
+	}
+
 	return huh.NewForm(
 		huh.NewGroup(
 			huh.NewSelect[string]().
 				Title("Language").
-				Options(
-					huh.NewOption("Node.js", "node"),
-					huh.NewOption("Python", "python"),
-					huh.NewOption("Go", "go"),
-					huh.NewOption("Rust", "rust"),
-					huh.NewOption("Ruby", "ruby"),
-					huh.NewOption("Elixir", "elixir"),
-					huh.NewOption("PHP", "php"),
-					huh.NewOption("Java", "java"),
-				).
+				Options(languages...).
 				Value((*string)(&profile.Language)),
 
 			huh.NewInput().
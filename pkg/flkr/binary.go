@@ -0,0 +1,29 @@
+package flkr
+
+// Binary is an executable the app's build produces, such as one of the
+// main packages of a Go module.
+type Binary struct {
+	// Name is the executable's name, e.g. "migrate".
+	Name string `json:"name"`
+
+	// Package is the package it is built from, e.g. "./cmd/migrate".
+	Package string `json:"package"`
+}
+
+// mergeBinaries overlays the binaries in b onto a, matching by Package.
+func mergeBinaries(a, b []Binary) []Binary {
+	for _, bin := range b {
+		replaced := false
+		for i := range a {
+			if a[i].Package == bin.Package {
+				a[i] = bin
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			a = append(a, bin)
+		}
+	}
+	return a
+}
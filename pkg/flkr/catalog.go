@@ -0,0 +1,114 @@
+package flkr
+
+import (
+	"slices"
+	"sync"
+)
+
+// LanguageInfo describes a language known to flkr.
+type LanguageInfo struct {
+	Language Language
+	Label    string
+}
+
+// FrameworkInfo describes a framework known to flkr.
+type FrameworkInfo struct {
+	Framework Framework
+	Language  Language
+	Label     string
+}
+
+var catalog = struct {
+	sync.RWMutex
+	languages  []LanguageInfo
+	frameworks []FrameworkInfo
+}{
+	languages:  slices.Clone(builtinLanguages),
+	frameworks: slices.Clone(builtinFrameworks),
+}
+
+var (
+	builtinLanguages = []LanguageInfo{
+		{LangNode, "Node.js"},
+		{LangPython, "Python"},
+		{LangGo, "Go"},
+		{LangRust, "Rust"},
+		{LangRuby, "Ruby"},
+		{LangElixir, "Elixir"},
+		{LangPHP, "PHP"},
+		{LangJava, "Java"},
+	}
+	builtinFrameworks = []FrameworkInfo{
+		{FrameworkNextJS, LangNode, "Next.js"},
+		{FrameworkNuxt, LangNode, "Nuxt"},
+		{FrameworkRemix, LangNode, "Remix"},
+		{FrameworkVite, LangNode, "Vite"},
+		{FrameworkDjango, LangPython, "Django"},
+		{FrameworkFlask, LangPython, "Flask"},
+		{FrameworkFastAPI, LangPython, "FastAPI"},
+		{FrameworkGin, LangGo, "Gin"},
+		{FrameworkEcho, LangGo, "Echo"},
+		{FrameworkFiber, LangGo, "Fiber"},
+		{FrameworkChi, LangGo, "Chi"},
+		{FrameworkGorilla, LangGo, "gorilla/mux"},
+		{FrameworkGRPC, LangGo, "gRPC"},
+		{FrameworkConnect, LangGo, "Connect"},
+		{FrameworkActix, LangRust, "Actix"},
+		{FrameworkRails, LangRuby, "Rails"},
+		{FrameworkPhoenix, LangElixir, "Phoenix"},
+		{FrameworkLaravel, LangPHP, "Laravel"},
+		{FrameworkSpring, LangJava, "Spring"},
+	}
+)
+
+// RegisterLanguage makes a custom language known to flkr, for example so it
+// can be selected in the flkr init review form. Registering an existing
+// language updates its label.
+func RegisterLanguage(lang Language, label string) {
+	catalog.Lock()
+	defer catalog.Unlock()
+	for i, l := range catalog.languages {
+		if l.Language == lang {
+			catalog.languages[i].Label = label
+			return
+		}
+	}
+	catalog.languages = append(catalog.languages, LanguageInfo{Language: lang, Label: label})
+}
+
+// RegisterFramework makes a custom framework known to flkr. Registering an
+// existing framework updates its language and label.
+func RegisterFramework(fw Framework, lang Language, label string) {
+	catalog.Lock()
+	defer catalog.Unlock()
+	for i, f := range catalog.frameworks {
+		if f.Framework == fw {
+			catalog.frameworks[i] = FrameworkInfo{Framework: fw, Language: lang, Label: label}
+			return
+		}
+	}
+	catalog.frameworks = append(catalog.frameworks, FrameworkInfo{Framework: fw, Language: lang, Label: label})
+}
+
+// Languages returns the built-in and registered languages.
+func Languages() []LanguageInfo {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	return append([]LanguageInfo(nil), catalog.languages...)
+}
+
+// ResetCatalog discards every registered language and framework, and
+// restores the labels of the built-in ones. It is mainly useful in tests.
+func ResetCatalog() {
+	catalog.Lock()
+	defer catalog.Unlock()
+	catalog.languages = slices.Clone(builtinLanguages)
+	catalog.frameworks = slices.Clone(builtinFrameworks)
+}
+
+// Frameworks returns the built-in and registered frameworks.
+func Frameworks() []FrameworkInfo {
+	catalog.RLock()
+	defer catalog.RUnlock()
+	return append([]FrameworkInfo(nil), catalog.frameworks...)
+}
@@ -0,0 +1,77 @@
+package flkr
+
+import (
+	"fmt"
+	"math"
+)
+
+// AmbiguityMargin is how close in confidence two candidate stacks must be
+// for detection to be considered too close to call.
+const AmbiguityMargin = 0.1
+
+// evidenceWeights is how strongly evidence for each field supports a
+// detection. Only the fields that identify the stack are weighted;
+// supporting fields such as commands, env vars and system dependencies
+// carry none, so a stack can't win on them alone.
+var evidenceWeights = map[string]float64{
+	"language":       0.5,
+	"framework":      0.4,
+	"packageManager": 0.3,
+	"lockfileType":   0.1,
+	"version":        0.1,
+}
+
+// Score computes a confidence between 0 and 1 from the profile's evidence.
+// Each field backed by evidence read from the repository contributes its
+// weight once, combined so that independent signals reinforce each other
+// without ever reaching 1; hard-coded defaults contribute nothing. A
+// language pinned in flkr.toml scores 1. The score is rounded to three
+// decimals.
+func (p *AppProfile) Score() float64 {
+	seen := map[string]bool{}
+	miss := 1.0
+	for _, e := range p.Evidence {
+		if e.Field == "language" && e.Detector == "config" {
+			return 1
+		}
+		if e.Default || seen[e.Field] {
+			continue
+		}
+		seen[e.Field] = true
+		miss *= 1 - evidenceWeights[e.Field]
+	}
+	return math.Round((1-miss)*1000) / 1000
+}
+
+// Candidate is a stack that matched almost as well as the selected one.
+type Candidate struct {
+	Language   Language  `json:"language"`
+	Framework  Framework `json:"framework,omitempty"`
+	Confidence float64   `json:"confidence"`
+	DetectedBy string    `json:"detectedBy,omitempty"`
+}
+
+// String describes the candidate, e.g. "java (spring, 79%)".
+func (c Candidate) String() string {
+	if c.Framework != FrameworkNone {
+		return fmt.Sprintf("%s (%s, %.0f%%)", c.Language, c.Framework, c.Confidence*100)
+	}
+	return fmt.Sprintf("%s (%.0f%%)", c.Language, c.Confidence*100)
+}
+
+// Ambiguous reports whether other stacks matched almost as well as this
+// profile, so the choice should be confirmed by the user.
+func (p *AppProfile) Ambiguous() bool {
+	return len(p.Alternatives) > 0
+}
+
+// Candidates returns the selected stack followed by its alternatives.
+func (p *AppProfile) Candidates() []Candidate {
+	selected := Candidate{
+		Language:   p.Language,
+		Framework:  p.Framework,
+		Confidence: p.Confidence,
+		DetectedBy: p.DetectedBy,
+	}
+	return append([]Candidate{selected}, p.Alternatives...)
+}
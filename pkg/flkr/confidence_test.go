@@ -0,0 +1,65 @@
+package flkr
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAppProfile_Score(t *testing.T) {
+	p := &AppProfile{Evidence: []Evidence{
+		{Field: "language", Value: "node", Detector: "node", File: "package.json"},
+		{Field: "packageManager", Value: "npm", Detector: "node", Rule: "npm is the default", Default: true},
+		{Field: "port", Value: "3000", Detector: "node", Rule: "default port", Default: true},
+	}}
+	assert.InDelta(t, 0.5, p.Score(), 0.001, "defaults carry no weight")
+
+	p.AddEvidence(Evidence{Field: "framework", Value: "vite", Detector: "node", File: "package.json"})
+	p.AddEvidence(Evidence{Field: "lockfileType", Value: "npm", Detector: "node", File: "package-lock.json"})
+	p.AddEvidence(Evidence{Field: "lockfileType", Value: "npm", Detector: "node", File: "npm-shrinkwrap.json"})
+	assert.InDelta(t, 1-0.5*0.6*0.9, p.Score(), 0.001, "each field counts once")
+
+	p.AddEvidence(Evidence{Field: "envVars", Value: "A", Detector: "crosscutting", File: ".env.example"})
+	p.AddEvidence(Evidence{Field: "startCommand", Value: "node server.js", Detector: "crosscutting", File: "Procfile"})
+	assert.InDelta(t, 1-0.5*0.6*0.9, p.Score(), 0.001, "supporting fields carry no weight")
+
+	p.AddEvidence(Evidence{Field: "language", Value: "node", Detector: "config", File: "flkr.toml"})
+	assert.Equal(t, 1.0, p.Score())
+
+	assert.Equal(t, 0.0, (&AppProfile{}).Score())
+}
+
+func TestAppProfile_Merge_Rescores(t *testing.T) {
+	base := &AppProfile{
+		Language:   LangGo,
+		Confidence: 0.5,
+		Evidence: []Evidence{
+			{Field: "language", Value: "go", Detector: "go", File: "go.mod"},
+		},
+	}
+	base.Merge(&AppProfile{
+		Framework:  FrameworkGin,
+		Confidence: 0.99,
+		Evidence: []Evidence{
+			{Field: "framework", Value: "gin", Detector: "go", File: "go.mod"},
+		},
+	})
+	assert.InDelta(t, 1-0.5*0.6, base.Confidence, 0.001)
+}
+
+func TestAppProfile_Candidates(t *testing.T) {
+	p := &AppProfile{
+		Language:   LangJava,
+		Framework:  FrameworkSpring,
+		Confidence: 0.79,
+		Alternatives: []Candidate{
+			{Language: LangNode, Confidence: 0.75},
+		},
+	}
+	assert.True(t, p.Ambiguous())
+	var names []string
+	for _, c := range p.Candidates() {
+		names = append(names, c.String())
+	}
+	assert.Equal(t, []string{"java (spring, 79%)", "node (75%)"}, names)
+}
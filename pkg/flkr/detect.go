@@ -7,24 +7,52 @@ import (
 )
 
 // DetectFunc is the function signature that the internal detector registry
-// exposes. It is set during initialization by the cmd package to avoid
+// exposes. It is set during initialization by the engine package to avoid
 // an import cycle.
-var DetectFunc func(ctx context.Context, root string) (*AppProfile, error)
+var DetectFunc func(ctx context.Context, opts DetectOptions) (*AppProfile, error)
+
+// DetectWorkspaceFunc is the workspace-aware counterpart of DetectFunc.
+var DetectWorkspaceFunc func(ctx context.Context, opts DetectOptions) ([]*AppProfile, error)
 
 // Detect scans the repository at the given path and returns an AppProfile.
+// The detection engine is linked in by importing
+// github.com/narvanalabs/flkr/pkg/flkr/engine.
 func Detect(ctx context.Context, opts DetectOptions) (*AppProfile, error) {
+	if err := checkDetectPath(&opts); err != nil {
+		return nil, err
+	}
+	if DetectFunc == nil {
+		return nil, errEngineNotInitialized
+	}
+	return DetectFunc(ctx, opts)
+}
+
+// DetectWorkspace scans the repository at the given path and returns one
+// AppProfile per deployable workspace member, each with Path set relative
+// to the repository root. Repositories that are not workspaces yield a
+// single profile with Path ".".
+func DetectWorkspace(ctx context.Context, opts DetectOptions) ([]*AppProfile, error) {
+	if err := checkDetectPath(&opts); err != nil {
+		return nil, err
+	}
+	if DetectWorkspaceFunc == nil {
+		return nil, errEngineNotInitialized
+	}
+	return DetectWorkspaceFunc(ctx, opts)
+}
+
+var errEngineNotInitialized = fmt.Errorf("detection engine not initialized: import github.com/narvanalabs/flkr/pkg/flkr/engine")
+
+func checkDetectPath(opts *DetectOptions) error {
 	if opts.Path == "" {
 		opts.Path = "."
 	}
 	info, err := os.Stat(opts.Path)
 	if err != nil {
-		return nil, fmt.Errorf("cannot access path %q: %w", opts.Path, err)
+		return fmt.Errorf("cannot access path %q: %w", opts.Path, err)
 	}
 	if !info.IsDir() {
-		return nil, fmt.Errorf("path %q is not a directory", opts.Path)
-	}
-	if DetectFunc == nil {
-		return nil, fmt.Errorf("detection engine not initialized")
+		return fmt.Errorf("path %q is not a directory", opts.Path)
 	}
-	return DetectFunc(ctx, opts.Path)
+	return nil
 }
@@ -0,0 +1,148 @@
+// Package engine links the built-in detectors and flake generator into the
+// public flkr API. Programs embedding flkr import this package and call
+// Detect and Generate; importing it for side effects alone is enough to make
+// flkr.Detect and flkr.Generate usable.
+package engine
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/narvanalabs/flkr/internal/detector"
+	"github.com/narvanalabs/flkr/internal/generator"
+	"github.com/narvanalabs/flkr/internal/nixhash"
+	"github.com/narvanalabs/flkr/pkg/flkr"
+)
+
+func init() {
+	flkr.DetectFunc = detect
+	flkr.DetectWorkspaceFunc = detectWorkspace
+	flkr.GenerateFunc = generate
+	flkr.GenerateWorkspaceFunc = generateWorkspace
+}
+
+// Detect scans the repository at opts.Path and returns the best matching
+// AppProfile, or nil if no application stack was detected.
+func Detect(ctx context.Context, opts flkr.DetectOptions) (*flkr.AppProfile, error) {
+	return flkr.Detect(ctx, opts)
+}
+
+// DetectWorkspace scans the repository at opts.Path and returns one profile
+// per deployable workspace member.
+func DetectWorkspace(ctx context.Context, opts flkr.DetectOptions) ([]*flkr.AppProfile, error) {
+	return flkr.DetectWorkspace(ctx, opts)
+}
+
+// Generate renders a flake.nix for the given profile and, unless
+// opts.DryRun is set, writes it to opts.OutputPath.
+func Generate(profile *flkr.AppProfile, opts flkr.GenerateOptions) (*flkr.GenerateResult, error) {
+	return flkr.Generate(profile, opts)
+}
+
+// GenerateWorkspace renders a multi-app flake.nix with one named package
+// and app per profile.
+func GenerateWorkspace(profiles []*flkr.AppProfile, opts flkr.GenerateOptions) (*flkr.GenerateResult, error) {
+	return flkr.GenerateWorkspace(profiles, opts)
+}
+
+func detect(ctx context.Context, opts flkr.DetectOptions) (*flkr.AppProfile, error) {
+	return newRegistry(opts).DetectFromPath(ctx, opts.Path)
+}
+
+func detectWorkspace(ctx context.Context, opts flkr.DetectOptions) ([]*flkr.AppProfile, error) {
+	return newRegistry(opts).DetectWorkspaceFromPath(ctx, opts.Path)
+}
+
+func newRegistry(opts flkr.DetectOptions) *detector.Registry {
+	reg := detector.NewRegistry()
+	if opts.Verbose {
+		if opts.LogOutput != nil {
+			reg.SetLogOutput(opts.LogOutput)
+		} else {
+			reg.SetLogOutput(os.Stderr)
+		}
+	}
+	reg.SetLanguages(opts.Languages)
+	if opts.Parallelism > 0 {
+		reg.SetParallelism(opts.Parallelism)
+	}
+	switch {
+	case opts.DetectorTimeout > 0:
+		reg.SetDetectorTimeout(opts.DetectorTimeout)
+	case opts.DetectorTimeout < 0:
+		reg.SetDetectorTimeout(0)
+	}
+	return reg
+}
+
+func generate(profile *flkr.AppProfile, opts flkr.GenerateOptions) (*flkr.GenerateResult, error) {
+	opts = withDefaults(opts)
+	warnings := computeVendorHash(profile, opts.Path)
+
+	gen := &generator.DefaultGenerator{}
+	result, err := gen.Generate(profile, generatorOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	return result, nil
+}
+
+func generateWorkspace(profiles []*flkr.AppProfile, opts flkr.GenerateOptions) (*flkr.GenerateResult, error) {
+	opts = withDefaults(opts)
+
+	var warnings []string
+	for _, p := range profiles {
+		for _, w := range computeVendorHash(p, filepath.Join(opts.Path, p.Path)) {
+			warnings = append(warnings, p.Path+": "+w)
+		}
+	}
+
+	apps, err := generator.NameApps(profiles, opts.Names)
+	if err != nil {
+		return nil, err
+	}
+	gen := &generator.DefaultGenerator{}
+	result, err := gen.GenerateApps(apps, generatorOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = append(result.Warnings, warnings...)
+	return result, nil
+}
+
+func withDefaults(opts flkr.GenerateOptions) flkr.GenerateOptions {
+	if opts.Path == "" {
+		opts.Path = "."
+	}
+	if opts.OutputPath == "" {
+		opts.OutputPath = filepath.Join(opts.Path, "flake.nix")
+	}
+	return opts
+}
+
+func generatorOptions(opts flkr.GenerateOptions) generator.Options {
+	return generator.Options{
+		OutputPath:      opts.OutputPath,
+		TemplateVersion: opts.TemplateVersion,
+		DryRun:          opts.DryRun,
+		Default:         opts.Default,
+	}
+}
+
+// computeVendorHash fills in the vendorHash of a Go profile, returning a
+// warning if it could not be computed.
+func computeVendorHash(profile *flkr.AppProfile, dir string) []string {
+	if profile.Language != flkr.LangGo || profile.HasVendor || profile.VendorHash != "" {
+		return nil
+	}
+	absPath, _ := filepath.Abs(dir)
+	hash, err := nixhash.GoVendorHash(absPath)
+	if err != nil {
+		return []string{fmt.Sprintf("could not compute vendorHash: %v", err)}
+	}
+	profile.VendorHash = hash
+	return nil
+}
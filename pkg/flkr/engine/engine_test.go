@@ -0,0 +1,114 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/narvanalabs/flkr/pkg/flkr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect_NextJS(t *testing.T) {
+	profile, err := Detect(context.Background(), flkr.DetectOptions{Path: "../../../testdata/node-nextjs"})
+	require.NoError(t, err)
+	require.NotNil(t, profile)
+	assert.Equal(t, flkr.LangNode, profile.Language)
+	assert.Equal(t, flkr.FrameworkNextJS, profile.Framework)
+	assert.Contains(t, profile.EnvVars, "DATABASE_URL")
+}
+
+func TestDetect_Verbose(t *testing.T) {
+	var log bytes.Buffer
+	_, err := flkr.Detect(context.Background(), flkr.DetectOptions{
+		Path:      "../../../testdata/rust-actix",
+		Verbose:   true,
+		LogOutput: &log,
+	})
+	require.NoError(t, err)
+	assert.Contains(t, log.String(), "detector rust: matched rust")
+	assert.Contains(t, log.String(), "detector node: no match")
+}
+
+func TestGenerate_DryRun(t *testing.T) {
+	profile := &flkr.AppProfile{
+		Language:       flkr.LangRust,
+		PackageManager: flkr.PkgCargo,
+		Confidence:     0.9,
+	}
+	result, err := Generate(profile, flkr.GenerateOptions{DryRun: true, TemplateVersion: "v1.0.0"})
+	require.NoError(t, err)
+	assert.Contains(t, result.FlakeContent, `ecosystem = "rust"`)
+	assert.Contains(t, result.FlakeContent, "flkr-templates/v1.0.0")
+	assert.Empty(t, result.OutputPath)
+}
+
+func TestGenerate_DefaultOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	profile := &flkr.AppProfile{
+		Language:       flkr.LangNode,
+		PackageManager: flkr.PkgNPM,
+		Confidence:     0.9,
+	}
+	result, err := flkr.Generate(profile, flkr.GenerateOptions{Path: dir})
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "flake.nix"), result.OutputPath)
+
+	data, err := os.ReadFile(result.OutputPath)
+	require.NoError(t, err)
+	assert.Equal(t, result.FlakeContent, string(data))
+}
+
+func TestGenerate_InvalidProfile(t *testing.T) {
+	_, err := Generate(&flkr.AppProfile{}, flkr.GenerateOptions{DryRun: true})
+	assert.ErrorContains(t, err, "language is required")
+}
+
+func TestGenerate_Problems(t *testing.T) {
+	profile := &flkr.AppProfile{
+		Language:       flkr.LangGo,
+		PackageManager: flkr.PkgGoMod,
+		Problems:       []string{"go.mod:5: replace example.com/shared => ../shared points outside the source tree"},
+	}
+	_, err := Generate(profile, flkr.GenerateOptions{DryRun: true})
+	assert.ErrorContains(t, err, "cannot build from the source tree: go.mod:5")
+
+	profile.Path = "api"
+	_, err = flkr.GenerateWorkspace([]*flkr.AppProfile{profile}, flkr.GenerateOptions{DryRun: true})
+	assert.ErrorContains(t, err, "api: cannot build")
+}
+
+func TestGenerateWorkspace(t *testing.T) {
+	profiles := []*flkr.AppProfile{
+		{Path: "api", Language: flkr.LangRust, PackageManager: flkr.PkgCargo, Port: 8080, StartCommand: "./api"},
+		{Path: "web", Language: flkr.LangNode, PackageManager: flkr.PkgNPM},
+	}
+	result, err := GenerateWorkspace(profiles, flkr.GenerateOptions{DryRun: true, Names: map[string]string{"web": "site"}})
+	require.NoError(t, err)
+	assert.Contains(t, result.FlakeContent, "default = o.api;")
+	assert.Contains(t, result.FlakeContent, "site = flkr-templates.lib.mkApp {")
+	assert.Contains(t, result.FlakeContent, "src = ./web;")
+}
+
+func TestGenerate_InlinedRecipe(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"go.mod":   "module example.com/srv\n\ngo 1.24\n",
+		"main.go":  "package main\n\nfunc main() {}\n",
+		"Makefile": "build:\n\tgo build -ldflags \"-s -w\" -o srv .\n\tmkdir -p \"$${out}/share\"\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+
+	profile, err := Detect(context.Background(), flkr.DetectOptions{Path: dir})
+	require.NoError(t, err)
+	require.Equal(t, `go build -ldflags "-s -w" -o srv . && mkdir -p "${out}/share"`, profile.BuildCommand)
+
+	result, err := Generate(profile, flkr.GenerateOptions{Path: dir, DryRun: true})
+	require.NoError(t, err)
+	assert.Contains(t, result.FlakeContent, `buildCommand = "go build -ldflags \"-s -w\" -o srv . && mkdir -p \"\${out}/share\"";`)
+}
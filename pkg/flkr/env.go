@@ -0,0 +1,44 @@
+package flkr
+
+// EnvVar describes how the app reads an environment variable.
+type EnvVar struct {
+	Name string `json:"name"`
+
+	// Required is true when the app reads the variable without a
+	// fallback, so it must be set for the app to run.
+	Required bool `json:"required"`
+
+	// Default is the fallback value used when an optional variable is
+	// unset. It is empty for optional variables read without one.
+	Default string `json:"default,omitempty"`
+}
+
+// LookupEnv returns how the app reads the named variable, if known.
+// Variables listed in EnvVars only because of e.g. .env.example have no
+// entry.
+func (p *AppProfile) LookupEnv(name string) (EnvVar, bool) {
+	for _, v := range p.Env {
+		if v.Name == name {
+			return v, true
+		}
+	}
+	return EnvVar{}, false
+}
+
+// mergeEnv overlays the variables in b onto a, matching by Name.
+func mergeEnv(a, b []EnvVar) []EnvVar {
+	for _, v := range b {
+		replaced := false
+		for i := range a {
+			if a[i].Name == v.Name {
+				a[i] = v
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			a = append(a, v)
+		}
+	}
+	return a
+}
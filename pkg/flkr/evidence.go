@@ -0,0 +1,71 @@
+package flkr
+
+// Evidence records where a detected field value came from.
+type Evidence struct {
+	// Field is the JSON name of the AppProfile field, e.g. "port".
+	Field string `json:"field"`
+
+	// Value is the value the detector assigned, formatted as a string.
+	// For list fields such as envVars there is one entry per element.
+	Value string `json:"value"`
+
+	// Detector is the name of the detector that set the value.
+	Detector string `json:"detector"`
+
+	// File and Line locate the source of the value, if it was read from
+	// a file. Line is 1-based and zero when unknown.
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+
+	// Rule describes why the detector chose the value.
+	Rule string `json:"rule"`
+
+	// Default is true when the value is a hard-coded fallback rather
+	// than something read from the repository.
+	Default bool `json:"default,omitempty"`
+}
+
+// listFields are the AppProfile fields whose values accumulate on Merge
+// rather than being replaced.
+var listFields = map[string]bool{
+	"systemDeps":   true,
+	"buildDeps":    true,
+	"envVars":      true,
+	"stages":       true,
+	"processes":    true,
+	"binaries":     true,
+	"services":     true,
+	"serviceEnv":   true,
+	"localModules": true,
+}
+
+// AddEvidence records evidence for a field value.
+func (p *AppProfile) AddEvidence(e Evidence) {
+	p.Evidence = append(p.Evidence, e)
+}
+
+// EvidenceFor returns the evidence recorded for the given field.
+func (p *AppProfile) EvidenceFor(field string) []Evidence {
+	var out []Evidence
+	for _, e := range p.Evidence {
+		if e.Field == field {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// mergeEvidence drops evidence for fields replaced by other and appends
+// other's evidence.
+func (p *AppProfile) mergeEvidence(other *AppProfile, replaced map[string]bool) {
+	if len(other.Evidence) == 0 {
+		return
+	}
+	kept := p.Evidence[:0:0]
+	for _, e := range p.Evidence {
+		if !replaced[e.Field] || listFields[e.Field] {
+			kept = append(kept, e)
+		}
+	}
+	p.Evidence = append(kept, other.Evidence...)
+}
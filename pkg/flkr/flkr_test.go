@@ -56,8 +56,62 @@ func TestAppProfile_Merge(t *testing.T) {
 	assert.Equal(t, []string{"DB_URL", "SECRET"}, base.EnvVars)
 }
 
+func TestAppProfile_Merge_PortEnv(t *testing.T) {
+	base := &AppProfile{Port: 3000, PortEnv: "PORT"}
+	base.Merge(&AppProfile{Port: 5000})
+	assert.Equal(t, 5000, base.Port)
+	assert.Empty(t, base.PortEnv, "a literal port replaces the env var")
+
+	base.Merge(&AppProfile{PortEnv: "HTTP_PORT"})
+	assert.Equal(t, 5000, base.Port)
+	assert.Equal(t, "HTTP_PORT", base.PortEnv)
+}
+
+func TestAppProfile_Merge_Env(t *testing.T) {
+	base := &AppProfile{Env: []EnvVar{{Name: "PORT", Default: "3000"}, {Name: "DB_URL", Required: true}}}
+	base.Merge(&AppProfile{Env: []EnvVar{{Name: "PORT", Required: true}, {Name: "REDIS_URL"}}})
+	assert.Equal(t, []EnvVar{
+		{Name: "PORT", Required: true},
+		{Name: "DB_URL", Required: true},
+		{Name: "REDIS_URL"},
+	}, base.Env)
+
+	v, ok := base.LookupEnv("REDIS_URL")
+	assert.True(t, ok)
+	assert.False(t, v.Required)
+	_, ok = base.LookupEnv("MISSING")
+	assert.False(t, ok)
+}
+
 func TestAppProfile_Merge_Nil(t *testing.T) {
 	p := &AppProfile{Language: LangNode}
 	p.Merge(nil)
 	assert.Equal(t, LangNode, p.Language)
 }
+
+func TestAppProfile_Merge_Evidence(t *testing.T) {
+	base := &AppProfile{
+		Port:    3000,
+		EnvVars: []string{"DB_URL"},
+		Evidence: []Evidence{
+			{Field: "port", Value: "3000", Detector: "node", Rule: "default port", Default: true},
+			{Field: "envVars", Value: "DB_URL", Detector: "node", File: ".env"},
+			{Field: "language", Value: "node", Detector: "node", File: "package.json"},
+		},
+	}
+	other := &AppProfile{
+		Port:    8080,
+		EnvVars: []string{"SECRET"},
+		Evidence: []Evidence{
+			{Field: "port", Value: "8080", Detector: "procfile", File: "Procfile", Line: 1},
+			{Field: "envVars", Value: "SECRET", Detector: "crosscutting", File: ".env.example", Line: 2},
+		},
+	}
+
+	base.Merge(other)
+	require.Len(t, base.EvidenceFor("port"), 1)
+	assert.Equal(t, "Procfile", base.EvidenceFor("port")[0].File)
+	assert.False(t, base.EvidenceFor("port")[0].Default)
+	assert.Len(t, base.EvidenceFor("envVars"), 2)
+	assert.Len(t, base.EvidenceFor("language"), 1)
+}
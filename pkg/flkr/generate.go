@@ -1,5 +1,10 @@
 package flkr
 
+import (
+	"fmt"
+	"strings"
+)
+
 // GenerateResult holds the output of flake generation.
 type GenerateResult struct {
 	// FlakeContent is the rendered flake.nix content.
@@ -7,18 +12,68 @@ type GenerateResult struct {
 
 	// OutputPath is the file path where the flake was written (empty if DryRun).
 	OutputPath string
+
+	// Warnings lists non-fatal problems hit during generation, such as a
+	// vendorHash that could not be computed.
+	Warnings []string
 }
 
-// GenerateFunc is set during initialization to avoid import cycles.
+// GenerateFunc is set during initialization by the engine package to avoid
+// import cycles.
 var GenerateFunc func(profile *AppProfile, opts GenerateOptions) (*GenerateResult, error)
 
-// Generate renders a flake.nix for the given profile.
+// GenerateWorkspaceFunc is the multi-app counterpart of GenerateFunc.
+var GenerateWorkspaceFunc func(profiles []*AppProfile, opts GenerateOptions) (*GenerateResult, error)
+
+var errGeneratorNotInitialized = fmt.Errorf("generation engine not initialized: import github.com/narvanalabs/flkr/pkg/flkr/engine")
+
+// Generate renders a flake.nix for the given profile. The generator is
+// linked in by importing github.com/narvanalabs/flkr/pkg/flkr/engine.
 func Generate(profile *AppProfile, opts GenerateOptions) (*GenerateResult, error) {
+	if profile == nil {
+		return nil, fmt.Errorf("profile is required")
+	}
 	if err := profile.Validate(); err != nil {
 		return nil, err
 	}
+	if err := profile.buildable(); err != nil {
+		return nil, err
+	}
 	if GenerateFunc == nil {
-		return nil, nil
+		return nil, errGeneratorNotInitialized
 	}
 	return GenerateFunc(profile, opts)
 }
+
+// GenerateWorkspace renders a single flake.nix exposing a named package and
+// app for each profile, typically the result of DetectWorkspace. Each app is
+// built from its profile Path.
+func GenerateWorkspace(profiles []*AppProfile, opts GenerateOptions) (*GenerateResult, error) {
+	if len(profiles) == 0 {
+		return nil, fmt.Errorf("at least one profile is required")
+	}
+	for _, p := range profiles {
+		if p == nil {
+			return nil, fmt.Errorf("profile is required")
+		}
+		if err := p.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Path, err)
+		}
+		if err := p.buildable(); err != nil {
+			return nil, fmt.Errorf("%s: %w", p.Path, err)
+		}
+	}
+	if GenerateWorkspaceFunc == nil {
+		return nil, errGeneratorNotInitialized
+	}
+	return GenerateWorkspaceFunc(profiles, opts)
+}
+
+// buildable reports the problems that keep the profile's app from building
+// from its source tree.
+func (p *AppProfile) buildable() error {
+	if len(p.Problems) > 0 {
+		return fmt.Errorf("cannot build from the source tree: %s", strings.Join(p.Problems, "; "))
+	}
+	return nil
+}
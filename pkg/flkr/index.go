@@ -0,0 +1,44 @@
+package flkr
+
+import (
+	"errors"
+	"io/fs"
+)
+
+// ErrFileTooLarge is returned by Index.ReadFileLimit for files above the
+// requested size.
+var ErrFileTooLarge = errors.New("file too large")
+
+// Index is a read-only view of a repository built in a single walk. It
+// honors .gitignore files and leaves the contents of dependency and build
+// directories such as node_modules, vendor and target unindexed; those
+// directories still exist in the index but appear empty.
+//
+// The root handed to a Detector is an Index during normal detection, so
+// detectors that scan deeply can type-assert for it:
+//
+//	if idx, ok := root.(flkr.Index); ok {
+//		for _, name := range idx.Match("**/*.go") { ... }
+//	}
+//
+// Reads through the index are cached, so several detectors reading the
+// same manifest only hit the disk once.
+type Index interface {
+	fs.ReadFileFS
+	fs.ReadDirFS
+	fs.StatFS
+	fs.GlobFS
+	fs.SubFS
+
+	// Files returns the paths of all indexed regular files, sorted.
+	Files() []string
+
+	// Match returns the indexed files matching pattern, sorted. Patterns
+	// use path.Match syntax, and a "**" segment matches any number of
+	// directories.
+	Match(pattern string) []string
+
+	// ReadFileLimit reads a file unless it is larger than limit bytes, in
+	// which case it returns an error wrapping ErrFileTooLarge.
+	ReadFileLimit(name string, limit int64) ([]byte, error)
+}
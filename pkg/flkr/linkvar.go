@@ -0,0 +1,16 @@
+package flkr
+
+// LinkVar is a string variable a Go build sets with -ldflags -X.
+type LinkVar struct {
+	// Name is the variable's package path and name, e.g. "main.version".
+	Name string `json:"name"`
+
+	// Value is the fixed value the variable is set to. It is empty when
+	// From is set.
+	Value string `json:"value,omitempty"`
+
+	// From names what the build fills the variable with: "version" for
+	// the app's version, "commit" for the source revision and "date" for
+	// its date.
+	From string `json:"from,omitempty"`
+}
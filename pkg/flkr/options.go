@@ -1,5 +1,10 @@
 package flkr
 
+import (
+	"io"
+	"time"
+)
+
 // DetectOptions configures detection behavior.
 type DetectOptions struct {
 	// Path is the root directory to scan. Defaults to ".".
@@ -7,16 +12,47 @@ type DetectOptions struct {
 
 	// Verbose enables detailed detection logging.
 	Verbose bool
+
+	// LogOutput receives verbose logging. Defaults to os.Stderr.
+	LogOutput io.Writer
+
+	// Parallelism limits how many detectors run at once. Zero uses the
+	// number of CPUs.
+	Parallelism int
+
+	// Languages selects the primary language of apps by their Path ("."
+	// for the repository root) when several stacks match, overriding both
+	// confidence and flkr.toml. See AppProfile.Alternatives.
+	Languages map[string]Language
+
+	// DetectorTimeout bounds how long each detector may run. A detector
+	// that exceeds it is skipped with a warning. Zero uses a default of
+	// ten seconds; a negative value disables the limit.
+	DetectorTimeout time.Duration
 }
 
 // GenerateOptions configures flake generation behavior.
 type GenerateOptions struct {
-	// OutputPath is where to write the flake.nix. Defaults to "<detect-path>/flake.nix".
+	// Path is the repository root the profile was detected from. It is used
+	// to default OutputPath and to compute the Go vendorHash. Defaults to ".".
+	Path string
+
+	// OutputPath is where to write the flake.nix. Defaults to "<Path>/flake.nix".
 	OutputPath string
 
 	// TemplateVersion pins the flkr-templates revision. Empty means "main".
 	TemplateVersion string
 
-	// DryRun prints the generated flake to stdout instead of writing a file.
+	// DryRun renders the flake without writing it. The content is returned
+	// in GenerateResult.FlakeContent.
 	DryRun bool
+
+	// Names maps an app Path to its flake attribute name when generating a
+	// multi-app flake. Unnamed apps are named after their directory.
+	Names map[string]string
+
+	// Default is the app name exposed as packages.default and apps.default
+	// in a multi-app flake. Empty prefers the root app, then the first app
+	// that serves on a port.
+	Default string
 }
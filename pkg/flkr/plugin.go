@@ -0,0 +1,118 @@
+package flkr
+
+import (
+	"context"
+	"io/fs"
+	"sync"
+)
+
+// Detector analyzes a repository filesystem and returns an AppProfile
+// if the ecosystem is detected.
+//
+// A detector that matches but leaves Language empty is treated as an
+// enrichment: its non-zero fields are merged over the best ecosystem match.
+// This lets a detector recognize an in-house framework without
+// re-implementing language detection.
+type Detector interface {
+	// Name returns a human-readable identifier for this detector.
+	Name() string
+
+	// Detect inspects the filesystem and returns a profile if detected.
+	// The boolean indicates whether the detector matched at all.
+	Detect(ctx context.Context, root fs.FS) (*AppProfile, bool, error)
+
+	// Priority controls execution order; lower values run first.
+	Priority() int
+}
+
+// pluginState holds detector registrations made through RegisterDetector,
+// DisableDetector and SetDetectorPriority.
+var pluginState = struct {
+	sync.RWMutex
+	registered []Detector
+	disabled   map[string]bool
+	priorities map[string]int
+}{
+	disabled:   map[string]bool{},
+	priorities: map[string]int{},
+}
+
+// RegisterDetector adds a detector to every registry created afterwards.
+// A detector with the same Name as a built-in or previously registered
+// detector replaces it.
+func RegisterDetector(d Detector) {
+	pluginState.Lock()
+	defer pluginState.Unlock()
+	for i, existing := range pluginState.registered {
+		if existing.Name() == d.Name() {
+			pluginState.registered[i] = d
+			return
+		}
+	}
+	pluginState.registered = append(pluginState.registered, d)
+}
+
+// DisableDetector prevents the named detector, built-in or registered,
+// from running.
+func DisableDetector(name string) {
+	pluginState.Lock()
+	defer pluginState.Unlock()
+	pluginState.disabled[name] = true
+}
+
+// SetDetectorPriority overrides the priority of the named detector.
+func SetDetectorPriority(name string, priority int) {
+	pluginState.Lock()
+	defer pluginState.Unlock()
+	pluginState.priorities[name] = priority
+}
+
+// ResetDetectors discards every registration, disable and priority override.
+// It is mainly useful in tests.
+func ResetDetectors() {
+	pluginState.Lock()
+	defer pluginState.Unlock()
+	pluginState.registered = nil
+	pluginState.disabled = map[string]bool{}
+	pluginState.priorities = map[string]int{}
+}
+
+// ResolveDetectors applies the current registrations to a set of built-in
+// detectors and returns the detectors that should run.
+func ResolveDetectors(builtin []Detector) []Detector {
+	pluginState.RLock()
+	defer pluginState.RUnlock()
+
+	replaced := make(map[string]bool, len(pluginState.registered))
+	for _, d := range pluginState.registered {
+		replaced[d.Name()] = true
+	}
+
+	var out []Detector
+	add := func(d Detector) {
+		if pluginState.disabled[d.Name()] {
+			return
+		}
+		if p, ok := pluginState.priorities[d.Name()]; ok {
+			d = prioritized{Detector: d, priority: p}
+		}
+		out = append(out, d)
+	}
+	for _, d := range builtin {
+		if !replaced[d.Name()] {
+			add(d)
+		}
+	}
+	for _, d := range pluginState.registered {
+		add(d)
+	}
+	return out
+}
+
+// prioritized overrides the priority of a wrapped detector.
+type prioritized struct {
+	Detector
+	priority int
+}
+
+func (p prioritized) Priority() int { return p.priority }
@@ -0,0 +1,71 @@
+package flkr
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubDetector struct {
+	name     string
+	priority int
+}
+
+func (d *stubDetector) Name() string  { return d.name }
+func (d *stubDetector) Priority() int { return d.priority }
+func (d *stubDetector) Detect(ctx context.Context, root fs.FS) (*AppProfile, bool, error) {
+	return nil, false, nil
+}
+
+func detectorNames(ds []Detector) []string {
+	var names []string
+	for _, d := range ds {
+		names = append(names, d.Name())
+	}
+	return names
+}
+
+func TestResolveDetectors(t *testing.T) {
+	t.Cleanup(ResetDetectors)
+	builtin := []Detector{
+		&stubDetector{name: "node", priority: 10},
+		&stubDetector{name: "go", priority: 30},
+		&stubDetector{name: "java", priority: 80},
+	}
+
+	custom := &stubDetector{name: "go", priority: 5}
+	RegisterDetector(custom)
+	RegisterDetector(&stubDetector{name: "acme", priority: 1})
+	DisableDetector("java")
+	SetDetectorPriority("node", 99)
+
+	resolved := ResolveDetectors(builtin)
+	assert.Equal(t, []string{"node", "go", "acme"}, detectorNames(resolved))
+	assert.Equal(t, 99, resolved[0].Priority())
+	assert.Same(t, custom, resolved[1])
+}
+
+func TestResetDetectors(t *testing.T) {
+	DisableDetector("node")
+	ResetDetectors()
+	resolved := ResolveDetectors([]Detector{&stubDetector{name: "node"}})
+	assert.Equal(t, []string{"node"}, detectorNames(resolved))
+}
+
+func TestRegisterLanguageAndFramework(t *testing.T) {
+	t.Cleanup(ResetCatalog)
+	RegisterLanguage("deno", "Deno")
+	RegisterLanguage(LangGo, "Golang")
+	RegisterFramework("fresh", "deno", "Fresh")
+
+	assert.Contains(t, Languages(), LanguageInfo{Language: "deno", Label: "Deno"})
+	assert.Contains(t, Languages(), LanguageInfo{Language: LangGo, Label: "Golang"})
+	assert.Contains(t, Frameworks(), FrameworkInfo{Framework: "fresh", Language: "deno", Label: "Fresh"})
+
+	ResetCatalog()
+	assert.NotContains(t, Languages(), LanguageInfo{Language: "deno", Label: "Deno"})
+	assert.Contains(t, Languages(), LanguageInfo{Language: LangGo, Label: "Go"})
+	assert.NotContains(t, Frameworks(), FrameworkInfo{Framework: "fresh", Language: "deno", Label: "Fresh"})
+}
@@ -0,0 +1,28 @@
+package flkr
+
+// Process is a process type the app runs besides its web process, such as
+// a background worker or a scheduler declared in a Procfile.
+type Process struct {
+	// Name is the process type, e.g. "worker".
+	Name string `json:"name"`
+
+	Command string `json:"command"`
+}
+
+// mergeProcesses overlays the processes in b onto a, matching by Name.
+func mergeProcesses(a, b []Process) []Process {
+	for _, p := range b {
+		replaced := false
+		for i := range a {
+			if a[i].Name == p.Name {
+				a[i] = p
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			a = append(a, p)
+		}
+	}
+	return a
+}
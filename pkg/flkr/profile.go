@@ -52,6 +52,12 @@ const (
 	FrameworkFlask   Framework = "flask"
 	FrameworkFastAPI Framework = "fastapi"
 	FrameworkGin     Framework = "gin"
+	FrameworkEcho    Framework = "echo"
+	FrameworkFiber   Framework = "fiber"
+	FrameworkChi     Framework = "chi"
+	FrameworkGorilla Framework = "gorilla"
+	FrameworkGRPC    Framework = "grpc"
+	FrameworkConnect Framework = "connect"
 	FrameworkActix   Framework = "actix"
 	FrameworkRails   Framework = "rails"
 	FrameworkPhoenix Framework = "phoenix"
@@ -60,17 +66,25 @@ const (
 )
 
 // AppProfile represents the full detected profile of an application.
+// Path is the app directory relative to the repository root; it is only set
+// by workspace-aware detection.
 type AppProfile struct {
+	Path           string         `json:"path,omitempty"`
 	Language       Language       `json:"language"`
 	Version        string         `json:"version,omitempty"`
+	Toolchain      string         `json:"toolchain,omitempty"`
 	PackageManager PackageManager `json:"packageManager"`
 	Framework      Framework      `json:"framework,omitempty"`
 	BuildCommand   string         `json:"buildCommand,omitempty"`
 	StartCommand   string         `json:"startCommand,omitempty"`
 	OutputDir      string         `json:"outputDir,omitempty"`
 	Port           int            `json:"port,omitempty"`
+	PortEnv        string         `json:"portEnv,omitempty"`
 	SystemDeps     []string       `json:"systemDeps,omitempty"`
+	BuildDeps      []string       `json:"buildDeps,omitempty"`
 	EnvVars        []string       `json:"envVars,omitempty"`
+	Env            []EnvVar       `json:"env,omitempty"`
+	Stages         []BuildStage   `json:"stages,omitempty"`
 	AppVersion     string         `json:"appVersion,omitempty"`
 	HasLockfile    bool           `json:"hasLockfile"`
 	LockfileType   string         `json:"lockfileType,omitempty"`
@@ -78,6 +92,87 @@ type AppProfile struct {
 	VendorHash     string         `json:"vendorHash,omitempty"`
 	Confidence     float64        `json:"confidence"`
 	DetectedBy     string         `json:"detectedBy,omitempty"`
+	Evidence       []Evidence     `json:"evidence,omitempty"`
+
+	// VersionConstraint is the version requirement Version was read from,
+	// such as ">=20.0.0" in engines.node, and is empty when the version
+	// was pinned. The toolchain is resolved from it.
+	VersionConstraint string `json:"versionConstraint,omitempty"`
+
+	// PortProtocol is the protocol spoken on Port when it isn't plain
+	// HTTP, e.g. "grpc" for gRPC over HTTP/2, so that a deployment can
+	// route to the app and probe its health accordingly.
+	PortProtocol string `json:"portProtocol,omitempty"`
+
+	// BaseImage is the base image of the repository's Dockerfile, when it
+	// provides the app's language.
+	BaseImage string `json:"baseImage,omitempty"`
+
+	// ErlangVersion is the Erlang/OTP release an Elixir app runs on, and
+	// ErlangToolchain the nixpkgs attribute providing it.
+	ErlangVersion   string `json:"erlangVersion,omitempty"`
+	ErlangToolchain string `json:"erlangToolchain,omitempty"`
+
+	// Processes are the app's process types other than the web process
+	// run by StartCommand, and ReleaseCommand runs before the app starts,
+	// e.g. to migrate its database.
+	Processes      []Process `json:"processes,omitempty"`
+	ReleaseCommand string    `json:"releaseCommand,omitempty"`
+
+	// HealthCheck is the HTTP path a deployment platform polls to tell
+	// whether the app is up, e.g. /healthz.
+	HealthCheck string `json:"healthCheck,omitempty"`
+
+	// Binaries are the executables the build produces, the first being
+	// the one StartCommand runs.
+	Binaries []Binary `json:"binaries,omitempty"`
+
+	// CGOEnabled is the CGO_ENABLED value a Go app is built with: "1"
+	// when it uses cgo, "0" for a static, pure-Go binary. CGOOptional is
+	// set when every use of cgo has a pure-Go fallback, so that the app
+	// can be built statically too.
+	CGOEnabled  string `json:"cgoEnabled,omitempty"`
+	CGOOptional bool   `json:"cgoOptional,omitempty"`
+
+	// Ldflags are the linker flags a Go app is built with, other than the
+	// string variables set with -X, which are in LinkVars. Tags are its
+	// build tags.
+	Ldflags  []string  `json:"ldflags,omitempty"`
+	LinkVars []LinkVar `json:"linkVars,omitempty"`
+	Tags     []string  `json:"tags,omitempty"`
+
+	// LocalModules are the directories, relative to Path, holding the
+	// local Go modules the app builds with: those its go.mod replaces
+	// modules with, and the go.work modules it requires.
+	LocalModules []string `json:"localModules,omitempty"`
+
+	// Source is the directory, relative to the repository root, the app
+	// is built from when Path alone leaves out local modules it needs.
+	// Empty means Path.
+	Source string `json:"source,omitempty"`
+
+	// TestCommand runs the app's test suite.
+	TestCommand string `json:"testCommand,omitempty"`
+
+	// Services are the backing services the app depends on, and
+	// ServiceEnv the env vars pointing the app at them when both run
+	// locally, e.g. DATABASE_URL.
+	Services   []Service `json:"services,omitempty"`
+	ServiceEnv []Setting `json:"serviceEnv,omitempty"`
+
+	// Alternatives lists the other stacks whose confidence is within
+	// AmbiguityMargin of this one. It is empty unless detection was too
+	// close to call.
+	Alternatives []Candidate `json:"alternatives,omitempty"`
+
+	// Warnings lists problems encountered during detection, such as a
+	// detector that failed or timed out. The profile may be incomplete.
+	Warnings []string `json:"warnings,omitempty"`
+
+	// Problems lists what keeps the app from building from its source
+	// tree, such as a local module outside it. Unlike Warnings, they stop
+	// generation.
+	Problems []string `json:"problems,omitempty"`
 }
 
 // Validate checks that the profile has the minimum required fields.
@@ -99,53 +194,137 @@ func (p *AppProfile) Validate() error {
 }
 
 // Merge overlays another profile onto this one. Non-zero fields in other
-// take precedence. Slices are appended and deduplicated. Confidence takes
-// the higher value.
+// take precedence. Slices are appended and deduplicated. Evidence for
+// replaced fields is swapped for other's, and Confidence is rescored from
+// the merged evidence; without evidence it takes the higher value.
 func (p *AppProfile) Merge(other *AppProfile) {
 	if other == nil {
 		return
 	}
+	replaced := map[string]bool{}
+	if other.Path != "" {
+		p.Path = other.Path
+	}
 	if other.Language != "" {
 		p.Language = other.Language
+		replaced["language"] = true
 	}
 	if other.Version != "" {
 		p.Version = other.Version
+		p.VersionConstraint = other.VersionConstraint
+		replaced["version"] = true
+	}
+	if other.Toolchain != "" {
+		p.Toolchain = other.Toolchain
+		replaced["toolchain"] = true
+	}
+	if other.BaseImage != "" {
+		p.BaseImage = other.BaseImage
+		replaced["baseImage"] = true
+	}
+	if other.ErlangVersion != "" {
+		p.ErlangVersion = other.ErlangVersion
+		replaced["erlangVersion"] = true
+	}
+	if other.ErlangToolchain != "" {
+		p.ErlangToolchain = other.ErlangToolchain
 	}
 	if other.PackageManager != "" {
 		p.PackageManager = other.PackageManager
+		replaced["packageManager"] = true
 	}
 	if other.Framework != "" {
 		p.Framework = other.Framework
+		replaced["framework"] = true
 	}
 	if other.BuildCommand != "" {
 		p.BuildCommand = other.BuildCommand
+		replaced["buildCommand"] = true
 	}
 	if other.StartCommand != "" {
 		p.StartCommand = other.StartCommand
+		replaced["startCommand"] = true
+	}
+	if other.ReleaseCommand != "" {
+		p.ReleaseCommand = other.ReleaseCommand
+		replaced["releaseCommand"] = true
+	}
+	if other.TestCommand != "" {
+		p.TestCommand = other.TestCommand
+		replaced["testCommand"] = true
+	}
+	if other.HealthCheck != "" {
+		p.HealthCheck = other.HealthCheck
+		replaced["healthCheck"] = true
 	}
 	if other.OutputDir != "" {
 		p.OutputDir = other.OutputDir
+		replaced["outputDir"] = true
 	}
 	if other.Port != 0 {
 		p.Port = other.Port
+		p.PortEnv = other.PortEnv
+		replaced["port"] = true
+		replaced["portEnv"] = true
+	} else if other.PortEnv != "" {
+		p.PortEnv = other.PortEnv
+		replaced["portEnv"] = true
+	}
+	if other.PortProtocol != "" {
+		p.PortProtocol = other.PortProtocol
+		replaced["portProtocol"] = true
+	}
+	if other.CGOEnabled != "" {
+		p.CGOEnabled = other.CGOEnabled
+		p.CGOOptional = other.CGOOptional
+		replaced["cgoEnabled"] = true
+	}
+	if len(other.Ldflags) > 0 || len(other.LinkVars) > 0 {
+		p.Ldflags = other.Ldflags
+		p.LinkVars = other.LinkVars
+		replaced["ldflags"] = true
+		replaced["linkVars"] = true
+	}
+	if len(other.Tags) > 0 {
+		p.Tags = other.Tags
+		replaced["tags"] = true
+	}
+	if other.Source != "" {
+		p.Source = other.Source
 	}
 	if other.AppVersion != "" {
 		p.AppVersion = other.AppVersion
+		replaced["appVersion"] = true
 	}
 	if other.HasLockfile {
 		p.HasLockfile = true
 	}
 	if other.LockfileType != "" {
 		p.LockfileType = other.LockfileType
+		replaced["lockfileType"] = true
 	}
-	if other.Confidence > p.Confidence {
-		p.Confidence = other.Confidence
-	}
+
 	if other.DetectedBy != "" {
 		p.DetectedBy = other.DetectedBy
 	}
 	p.SystemDeps = mergeUnique(p.SystemDeps, other.SystemDeps)
+	p.BuildDeps = mergeUnique(p.BuildDeps, other.BuildDeps)
 	p.EnvVars = mergeUnique(p.EnvVars, other.EnvVars)
+	p.Env = mergeEnv(p.Env, other.Env)
+	p.Stages = mergeStages(p.Stages, other.Stages)
+	p.Processes = mergeProcesses(p.Processes, other.Processes)
+	p.Binaries = mergeBinaries(p.Binaries, other.Binaries)
+	p.Services = mergeServices(p.Services, other.Services)
+	p.ServiceEnv = mergeSettings(p.ServiceEnv, other.ServiceEnv)
+	p.LocalModules = mergeUnique(p.LocalModules, other.LocalModules)
+	p.Warnings = mergeUnique(p.Warnings, other.Warnings)
+	p.Problems = mergeUnique(p.Problems, other.Problems)
+	p.mergeEvidence(other, replaced)
+	if len(p.Evidence) > 0 {
+		p.Confidence = p.Score()
+	} else if other.Confidence > p.Confidence {
+		p.Confidence = other.Confidence
+	}
 }
 
 func mergeUnique(a, b []string) []string {
@@ -0,0 +1,76 @@
+package flkr
+
+// Service is a backing service the app talks to, such as a database or a
+// message broker declared next to it in docker-compose.yml. The generated
+// flake runs it locally for development.
+type Service struct {
+	// Name is the service's name in the compose file.
+	Name string `json:"name"`
+
+	// Kind identifies the service, e.g. "postgres" or "redis".
+	Kind string `json:"kind"`
+
+	// Image is the container image the compose file runs.
+	Image string `json:"image"`
+
+	// Version is the service version from the image tag, if any.
+	Version string `json:"version,omitempty"`
+
+	// Package is the nixpkgs attribute providing the service, e.g.
+	// "postgresql_16".
+	Package string `json:"package"`
+
+	// Port is the port the service listens on locally.
+	Port int `json:"port"`
+
+	// Settings configure the service, such as POSTGRES_DB or
+	// MINIO_ROOT_USER, as given in its compose environment.
+	Settings []Setting `json:"settings,omitempty"`
+
+	// User, Password and Database are what the service is set up with
+	// locally: its settings, or the image's defaults without them.
+	User     string `json:"user,omitempty"`
+	Password string `json:"password,omitempty"`
+	Database string `json:"database,omitempty"`
+}
+
+// Setting is a named string value.
+type Setting struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// mergeServices appends the services in b whose Name is not already in a.
+func mergeServices(a, b []Service) []Service {
+	for _, s := range b {
+		found := false
+		for _, existing := range a {
+			if existing.Name == s.Name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, s)
+		}
+	}
+	return a
+}
+
+// mergeSettings overlays the settings in b onto a, matching by Name.
+func mergeSettings(a, b []Setting) []Setting {
+	for _, s := range b {
+		replaced := false
+		for i := range a {
+			if a[i].Name == s.Name {
+				a[i] = s
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			a = append(a, s)
+		}
+	}
+	return a
+}
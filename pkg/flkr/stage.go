@@ -0,0 +1,46 @@
+package flkr
+
+// BuildStage is an auxiliary build that runs before the primary build of an
+// app, such as a Vite frontend compiled into web/dist and embedded by a Go
+// server. Its commands run from Dir, and OutputDir is left in place for the
+// primary build to pick up.
+type BuildStage struct {
+	// Name identifies the stage, usually after its directory.
+	Name string `json:"name"`
+
+	Language       Language       `json:"language"`
+	Version        string         `json:"version,omitempty"`
+	Toolchain      string         `json:"toolchain,omitempty"`
+	PackageManager PackageManager `json:"packageManager"`
+	HasLockfile    bool           `json:"hasLockfile"`
+
+	// VersionConstraint is the version requirement Version was read from,
+	// as on AppProfile.
+	VersionConstraint string `json:"versionConstraint,omitempty"`
+
+	// Dir is the stage's working directory relative to the app root.
+	Dir string `json:"dir"`
+
+	BuildCommand string `json:"buildCommand"`
+
+	// OutputDir is where the stage writes its output, relative to the
+	// app root.
+	OutputDir string `json:"outputDir,omitempty"`
+}
+
+// mergeStages appends the stages in b whose Dir is not already in a.
+func mergeStages(a, b []BuildStage) []BuildStage {
+	for _, s := range b {
+		found := false
+		for _, existing := range a {
+			if existing.Dir == s.Dir {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, s)
+		}
+	}
+	return a
+}